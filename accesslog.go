@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// recentConnectionsKept is how many connections of a forward are kept for the detail view
+const recentConnectionsKept = 20
+
+// ConnectionRecord describes a connection accepted by a forward's local listener
+type ConnectionRecord struct {
+	Client   string    `json:"client"`
+	Pod      string    `json:"pod,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end,omitzero"` // zero while the connection is open
+	BytesIn  int64     `json:"bytes_in"`     // sent by the client
+	BytesOut int64     `json:"bytes_out"`    // sent to the client
+	Error    string    `json:"error,omitempty"`
+}
+
+// connectionOpened records a connection accepted from client and returns it for
+// connectionClosed
+func (pf *PortForward) connectionOpened(client string) *ConnectionRecord {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	record := &ConnectionRecord{Client: client, Pod: pf.pod, Start: time.Now()}
+	pf.connections = append(pf.connections, record)
+	if len(pf.connections) > recentConnectionsKept {
+		pf.connections = pf.connections[len(pf.connections)-recentConnectionsKept:]
+	}
+	return record
+}
+
+// connectionClosed completes the record of a connection and writes it to the access log.
+// Connections of the health check are dropped.
+func (pf *PortForward) connectionClosed(record *ConnectionRecord, bytesIn, bytesOut int64, err error) {
+	pf.mu.Lock()
+	if pf.healthChecks[record.Client] {
+		delete(pf.healthChecks, record.Client)
+		for i, c := range pf.connections {
+			if c == record {
+				pf.connections = append(pf.connections[:i], pf.connections[i+1:]...)
+				break
+			}
+		}
+		pf.mu.Unlock()
+		return
+	}
+	record.End = time.Now()
+	record.BytesIn = bytesIn
+	record.BytesOut = bytesOut
+	if err != nil {
+		record.Error = err.Error()
+	}
+	entry := *record
+	pf.mu.Unlock()
+
+	logger, logErr := pf.accessLogger()
+	if logErr != nil {
+		slog.Warn("Failed to open access log", "cluster", pf.ClusterName, "service", pf.Config.Service, "error", logErr)
+		return
+	}
+	if logger == nil {
+		return
+	}
+
+	attrs := []any{"client", entry.Client}
+	if entry.Pod != "" {
+		attrs = append(attrs, "pod", entry.Pod)
+	}
+	attrs = append(attrs,
+		"start", entry.Start.Format(time.RFC3339Nano),
+		"duration", entry.End.Sub(entry.Start),
+		"bytes_in", entry.BytesIn,
+		"bytes_out", entry.BytesOut,
+	)
+	if entry.Error != "" {
+		attrs = append(attrs, "error", entry.Error)
+	}
+	logger.Info("connection", attrs...)
+}
+
+// accessLogger returns the logger writing the forward's access log, opening the file on
+// first use. nil without access_log.
+func (pf *PortForward) accessLogger() (*slog.Logger, error) {
+	if pf.Config.AccessLog == "" {
+		return nil, nil
+	}
+
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	if pf.accessLog != nil {
+		return pf.accessLog, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(pf.Config.AccessLog), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create access log directory: %w", err)
+	}
+	file, err := os.OpenFile(pf.Config.AccessLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	pf.accessLog = slog.New(slog.NewTextHandler(file, nil)).With(
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+		"local_port", pf.localPort,
+	)
+	return pf.accessLog, nil
+}
+
+// recentConnections returns copies of the forward's latest connections, newest first
+// (caller holds pf.mu)
+func (pf *PortForward) recentConnections() []ConnectionRecord {
+	records := make([]ConnectionRecord, 0, len(pf.connections))
+	for i := len(pf.connections) - 1; i >= 0; i-- {
+		records = append(records, *pf.connections[i])
+	}
+	return records
+}
+
+// healthCheckConnected marks a health check connection, so it isn't reported as a client.
+// Only connections to nanoporter's own listener are seen, and marked.
+func (pf *PortForward) healthCheckConnected(conn net.Conn) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	if pf.Config.IsKubernetes() && !pf.viaProxy {
+		return
+	}
+	if pf.healthChecks == nil {
+		pf.healthChecks = make(map[string]bool)
+	}
+	pf.healthChecks[conn.LocalAddr().String()] = true
+}
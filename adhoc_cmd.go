@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runForwardCommand runs a single forward given on the command line, kubectl style, without
+// a config file: nanoporter forward --context prod -n billing svc/postgres 15432:5432
+func runForwardCommand() {
+	forwardFlags := flag.NewFlagSet("forward", flag.ExitOnError)
+	kubeconfig := forwardFlags.String("kubeconfig", "", "Path to the kubeconfig (default: $KUBECONFIG or ~/.kube/config)")
+	kubeContext := forwardFlags.String("context", "", "Kubeconfig context (default: the current context)")
+	namespace := forwardFlags.String("namespace", "default", "Namespace of the service or pod")
+	forwardFlags.StringVar(namespace, "n", "default", "Shorthand for --namespace")
+	address := forwardFlags.String("address", "", "Local address to listen on (default: "+defaultBindAddress+")")
+	plain := forwardFlags.Bool("plain", false, "Print state changes instead of showing the TUI (default when stdout isn't a terminal)")
+	verbose := forwardFlags.Bool("verbose", false, "Enable verbose logging")
+	forwardFlags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: nanoporter forward [flags] [svc/|pod/]NAME [LOCAL:]REMOTE")
+		forwardFlags.PrintDefaults()
+	}
+	forwardFlags.Parse(os.Args[2:])
+	*plain = usePlainOutput(*plain)
+
+	if forwardFlags.NArg() != 2 {
+		forwardFlags.Usage()
+		os.Exit(2)
+	}
+	forward, err := parseAdHocForward(*namespace, forwardFlags.Arg(0), forwardFlags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// The cluster is shown under its context's name
+	clusterName := *kubeContext
+	if clusterName == "" {
+		clusterName = "default"
+		if rawConfig, err := kubeconfigLoadingRules(*kubeconfig).Load(); err == nil && rawConfig.CurrentContext != "" {
+			clusterName = rawConfig.CurrentContext
+		}
+	}
+	config, err := prepareConfig(&Config{
+		Clusters: []ClusterConfig{{
+			Name:        clusterName,
+			Kubeconfig:  *kubeconfig,
+			Context:     *kubeContext,
+			BindAddress: *address,
+			Forwards:    []ForwardConfig{forward},
+		}},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	logLevel := slog.LevelInfo
+	if *verbose {
+		logLevel = slog.LevelDebug
+	}
+	logOutput := os.Stderr
+	if !*plain {
+		// The TUI owns the terminal, so it logs to a file like the main command does
+		f, err := os.OpenFile("nanoporter.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		logOutput = f
+	} else if !*verbose {
+		// State changes and their errors are printed already
+		logLevel = slog.LevelError
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: logLevel})))
+
+	if err := CheckBindAddresses(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	manager := NewPortForwardManager(config)
+	if err := manager.Initialize(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ResolvePortConflicts(config, manager, ConflictPolicy{}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *plain {
+		runPlainForward(manager)
+		return
+	}
+
+	manager.Start()
+	if _, err := tea.NewProgram(NewTUIModel(manager, nil, ""), tea.WithAltScreen()).Run(); err != nil {
+		manager.Stop()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runPlainForward starts the forwards and prints their state changes until interrupted
+func runPlainForward(manager *PortForwardManager) {
+	events, unsubscribe := manager.Subscribe(100)
+	defer unsubscribe()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		<-signals
+		close(done)
+	}()
+
+	printForwards(manager)
+	fmt.Println("Press Ctrl+C to stop")
+	manager.Start()
+	printEvents(events, done)
+
+	fmt.Println("Stopping...")
+	manager.Stop()
+	time.Sleep(500 * time.Millisecond)
+}
+
+// parseAdHocForward builds a forward from a kubectl-style target (svc/NAME, service/NAME,
+// pod/NAME or a bare service name) and ports (LOCAL:REMOTE, or one port for both; REMOTE may
+// name a container port)
+func parseAdHocForward(namespace, target, ports string) (ForwardConfig, error) {
+	forward := ForwardConfig{Namespace: namespace, Type: "service", Service: target}
+	if kind, name, ok := strings.Cut(target, "/"); ok {
+		switch kind {
+		case "svc", "service", "services":
+		case "po", "pod", "pods":
+			forward.Type = "pod"
+		default:
+			return ForwardConfig{}, fmt.Errorf("unsupported target '%s' (expected svc/NAME or pod/NAME)", target)
+		}
+		forward.Service = name
+	}
+	if forward.Service == "" {
+		return ForwardConfig{}, fmt.Errorf("missing name in target '%s'", target)
+	}
+
+	local, remote, ok := strings.Cut(ports, ":")
+	if !ok {
+		remote = local
+	}
+	var err error
+	if forward.LocalPort, err = strconv.Atoi(local); err != nil {
+		return ForwardConfig{}, fmt.Errorf("invalid local port '%s'", local)
+	}
+	if forward.RemotePort, err = strconv.Atoi(remote); err != nil {
+		// A named container port, as kubectl takes it
+		forward.RemotePortName = remote
+	}
+	return forward, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// parseAllowedCIDR parses an entry of allowed_cidrs: a CIDR or a single address
+func parseAllowedCIDR(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	return netip.ParsePrefix(s)
+}
+
+// clientAllowed reports whether a connection may use a forward. Without allowed_cidrs all
+// clients may; with them, clients on this machine (loopback) and in the listed networks.
+// Rejected connections are logged and counted.
+func (pf *PortForward) clientAllowed(conn net.Conn) bool {
+	if len(pf.Config.AllowedCIDRs) == 0 {
+		return true
+	}
+
+	addrPort, err := netip.ParseAddrPort(conn.RemoteAddr().String())
+	if err == nil {
+		addr := addrPort.Addr().Unmap()
+		if addr.IsLoopback() {
+			return true
+		}
+		for _, cidr := range pf.Config.AllowedCIDRs {
+			// Checked with the config
+			if prefix, err := parseAllowedCIDR(cidr); err == nil && prefix.Contains(addr) {
+				return true
+			}
+		}
+	}
+
+	pf.mu.Lock()
+	pf.rejected++
+	rejected := pf.rejected
+	pf.mu.Unlock()
+
+	slog.Warn("Rejected connection from client outside allowed_cidrs",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+		"client", conn.RemoteAddr().String(),
+		"rejected", rejected,
+	)
+	return false
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultAPIListen is the address the HTTP API listens on unless api.listen is set
+const defaultAPIListen = "127.0.0.1:7070"
+
+// apiReadTimeout bounds reading a request of the HTTP API
+const apiReadTimeout = 10 * time.Second
+
+// APIServer serves the HTTP API, which lets automation that can't reach the control socket
+// (a chat bot, a CI job) trigger database backups and poll for their completion:
+//
+//	POST /api/backups              back up all databases
+//	POST /api/backups/{db}         back up one, db being cluster/namespace/service or cluster/name
+//	GET  /api/backups[/{db}]       backup states of the forwards
+//
+// Every request needs the configured token as "Authorization: Bearer <token>".
+type APIServer struct {
+	config   *Config
+	manager  *PortForwardManager
+	backups  *BackupManager
+	token    string
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewAPIServer creates the HTTP API server, resolving its token from the environment or
+// the OS keyring
+func NewAPIServer(config *Config, manager *PortForwardManager, backups *BackupManager) (*APIServer, error) {
+	token, err := expandEnvReferences(config.API.Token, "")
+	if err == nil {
+		token, err = resolveKeyringReference(token)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve api.token: %w", err)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("api.token is empty")
+	}
+
+	s := &APIServer{config: config, manager: manager, backups: backups, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/backups", s.handleTriggerBackup)
+	mux.HandleFunc("POST /api/backups/{db...}", s.handleTriggerBackup)
+	mux.HandleFunc("GET /api/backups", s.handleBackupStatus)
+	mux.HandleFunc("GET /api/backups/{db...}", s.handleBackupStatus)
+	s.server = &http.Server{Handler: s.authenticate(mux), ReadTimeout: apiReadTimeout}
+	return s, nil
+}
+
+// Start begins serving the API in the background
+func (s *APIServer) Start() error {
+	listener, err := net.Listen("tcp", s.config.API.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen for the API on %s: %w", s.config.API.Listen, err)
+	}
+	s.listener = listener
+
+	slog.Info("API listening", "address", listener.Addr())
+
+	go s.server.Serve(listener)
+	return nil
+}
+
+// Stop closes the API listener and its connections
+func (s *APIServer) Stop() {
+	if s.listener != nil {
+		s.server.Close()
+	}
+}
+
+// authenticate rejects requests without the API token
+func (s *APIServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			audit(r.Method+" "+r.URL.Path, auditSourceAPI, errControlNotAllowed, "remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, errControlNotAllowed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleTriggerBackup starts backing up one database or all of them, answering with the IDs
+// of the forwards whose backups started
+func (s *APIServer) handleTriggerBackup(w http.ResponseWriter, r *http.Request) {
+	database := r.PathValue("db")
+	result, err := triggerBackups(s.config, s.manager, s.backups, database)
+	audit("backup", auditSourceAPI, err, "remote", r.RemoteAddr, "database", database)
+	if err != nil {
+		writeAPIError(w, backupErrorStatus(s.config, database, err), err)
+		return
+	}
+	writeAPIResponse(w, http.StatusAccepted, result)
+}
+
+// handleBackupStatus answers with the status of the forwards with a database backup, or
+// only the one named in the path
+func (s *APIServer) handleBackupStatus(w http.ResponseWriter, r *http.Request) {
+	selected := s.config
+	if database := r.PathValue("db"); database != "" {
+		var err error
+		if selected, err = onlyBackup(s.config, database); err != nil {
+			writeAPIError(w, http.StatusNotFound, err)
+			return
+		}
+	}
+
+	statuses := []ForwardStatus{}
+	for _, cluster := range selected.Clusters {
+		for _, forward := range cluster.Forwards {
+			if forward.DBBackup == nil {
+				continue
+			}
+			if pf := s.manager.GetForward(forwardID(cluster.Name, forward)); pf != nil {
+				statuses = append(statuses, pf.Status())
+			}
+		}
+	}
+	writeAPIResponse(w, http.StatusOK, statuses)
+}
+
+// backupErrorStatus returns the HTTP status of a failed backup request
+func backupErrorStatus(config *Config, database string, err error) int {
+	switch {
+	case errors.Is(err, errBackupInProgress):
+		return http.StatusConflict
+	case database != "":
+		if _, selectErr := onlyBackup(config, database); selectErr != nil {
+			return http.StatusNotFound
+		}
+	}
+	return http.StatusServiceUnavailable
+}
+
+// writeAPIResponse writes a JSON response
+func writeAPIResponse(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeAPIError writes a JSON error response
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIResponse(w, status, map[string]string{"error": err.Error()})
+}
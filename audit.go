@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+)
+
+// Sources of audited actions; actions requested over the control socket name their peer
+const (
+	auditSourceTUI     = "tui"     // a key pressed in the TUI
+	auditSourceSignal  = "signal"  // SIGINT or SIGTERM
+	auditSourceStartup = "startup" // --takeover or -force-free-ports
+	auditSourceControl = "control" // a client of the control socket
+	auditSourceWatcher = "watcher" // a changed kubeconfig file
+	auditSourceAPI     = "api"     // a client of the HTTP API
+)
+
+// auditLogger writes the audit log, nil without audit_log
+var auditLogger atomic.Pointer[slog.Logger]
+
+// OpenAuditLog starts recording control actions to path. The returned file is closed on
+// exit.
+func OpenAuditLog(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	auditLogger.Store(slog.New(slog.NewTextHandler(file, nil)))
+	return file, nil
+}
+
+// audit records an action, who requested it and its outcome in the audit log. attrs name
+// what it acted on. Actions of the TUI and on startup are attributed to the user running
+// nanoporter.
+func audit(action, source string, err error, attrs ...any) {
+	logger := auditLogger.Load()
+	if logger == nil {
+		return
+	}
+
+	all := []any{"source", source}
+	if source == auditSourceTUI || source == auditSourceStartup {
+		all = append(all, "user", currentUserName())
+	}
+	all = append(all, attrs...)
+	if err != nil {
+		all = append(all, "outcome", "failed", "error", err.Error())
+		logger.Warn(action, all...)
+		return
+	}
+	all = append(all, "outcome", "ok")
+	logger.Info(action, all...)
+}
+
+// currentUserName returns the name of the user running nanoporter
+func currentUserName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(os.Getuid())
+}
+
+// userName returns the login name of a user ID, or the ID if it has none
+func userName(uid int) string {
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(uid)
+}
+
+// describePeer describes the process on the other end of a control connection
+func describePeer(uid, pid int) []any {
+	attrs := []any{"user", userName(uid), "pid", pid}
+	if name, err := newProcessInspector().ProcessName(pid); err == nil && name != "" {
+		attrs = append(attrs, "process", name)
+	}
+	return attrs
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -14,7 +15,11 @@ func runBackupCommand() {
 	configPath := backupFlags.String("config", "config.yaml", "Path to configuration file")
 	backupDir := backupFlags.String("dir", "backups", "Directory to store backups")
 	verbose := backupFlags.Bool("verbose", false, "Enable verbose logging")
-	waitTimeout := backupFlags.Int("timeout", 120, "Timeout in seconds to wait for port forwards")
+	waitTimeout := backupFlags.Int("timeout", 120, "Timeout in seconds to wait for port forwards to become active")
+	only := backupFlags.String("only", "", "Back up only this database (cluster/namespace/service or cluster/name)")
+	instance := backupFlags.Bool("instance", false, "Ask the running instance to back up, instead of starting own port forwards")
+	socketPath := backupFlags.String("socket", "", "Control socket of the running instance, with -instance (default: from config)")
+	noWait := backupFlags.Bool("no-wait", false, "With -instance, return once the backups started instead of waiting for them")
 
 	if len(os.Args) < 2 || os.Args[1] != "backup" {
 		return
@@ -22,6 +27,15 @@ func runBackupCommand() {
 
 	backupFlags.Parse(os.Args[2:])
 
+	if *instance {
+		socket := *socketPath
+		if socket == "" {
+			socket = controlSocketFromConfig(*configPath)
+		}
+		runInstanceBackup(socket, *only, !*noWait)
+		return
+	}
+
 	// Setup logging
 	logLevel := slog.LevelInfo
 	if *verbose {
@@ -45,6 +59,14 @@ func runBackupCommand() {
 		os.Exit(1)
 	}
 
+	// Forwards of other databases aren't even started
+	if *only != "" {
+		if config, err = onlyBackup(config, *only); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Count databases to backup
 	dbCount := 0
 	for _, cluster := range config.Clusters {
@@ -61,12 +83,14 @@ func runBackupCommand() {
 	}
 
 	fmt.Printf("Found %d database(s) configured for backup\n\n", dbCount)
+	pingBackupStart(config.BackupPing)
 
 	// Create backup manager
 	slog.Info("Initializing backup manager", "backup_dir", *backupDir)
 	backupManager, err := NewBackupManager(config, *backupDir)
 	if err != nil {
 		slog.Error("Failed to initialize backup manager", "error", err)
+		pingBackupResult(config.BackupPing, nil, err)
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -76,6 +100,7 @@ func runBackupCommand() {
 	portManager := NewPortForwardManager(config)
 	if err := portManager.Initialize(); err != nil {
 		slog.Error("Failed to initialize port-forward manager", "error", err)
+		pingBackupResult(config.BackupPing, portManager, err)
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -84,13 +109,16 @@ func runBackupCommand() {
 	fmt.Println("Starting port forwards...")
 	portManager.Start()
 
-	// Wait a bit for port forwards to establish
-	fmt.Printf("Waiting %d seconds for port forwards to establish...\n", *waitTimeout)
-	time.Sleep(5 * time.Second)
+	// Each database is dumped as soon as its port forward is active
+	fmt.Printf("Backing up databases as their port forwards become active (waiting up to %d seconds)...\n", *waitTimeout)
+	backupManager.waitTimeout = time.Duration(*waitTimeout) * time.Second
+	err = backupManager.BackupAllDatabases(portManager)
+
+	// Prune old backups, also when backups failed
+	backupManager.CleanupAll()
+	pingBackupResult(config.BackupPing, portManager, err)
 
-	// Perform backups
-	fmt.Println("\nStarting database backups...")
-	if err := backupManager.BackupAllDatabases(portManager); err != nil {
+	if err != nil {
 		slog.Error("Backup process completed with errors", "error", err)
 		portManager.Stop()
 		fmt.Fprintf(os.Stderr, "\nBackup completed with errors. Check logs for details.\n")
@@ -105,3 +133,33 @@ func runBackupCommand() {
 	fmt.Printf("\n✓ All database backups completed successfully!\n")
 	fmt.Printf("Backups stored in: %s\n", *backupDir)
 }
+
+// onlyBackup returns a copy of the config reduced to the forward of one database, given as
+// cluster/namespace/service, cluster/service or cluster/name
+func onlyBackup(config *Config, only string) (*Config, error) {
+	clusterName, forwardName, ok := strings.Cut(only, "/")
+	if !ok || forwardName == "" {
+		return nil, fmt.Errorf("invalid --only '%s' (expected cluster/namespace/service or cluster/name)", only)
+	}
+
+	for _, cluster := range config.Clusters {
+		if cluster.Name != clusterName {
+			continue
+		}
+		for _, forward := range cluster.Forwards {
+			if forward.DBBackup == nil {
+				continue
+			}
+			if forwardName == forward.Namespace+"/"+forward.Service || forwardName == forward.Service ||
+				(forward.Name != "" && forwardName == forward.Name) {
+				reduced := *config
+				cluster.Forwards = []ForwardConfig{forward}
+				reduced.Clusters = []ClusterConfig{cluster}
+				return &reduced, nil
+			}
+		}
+		return nil, fmt.Errorf("no database backup '%s' configured in cluster '%s'", forwardName, clusterName)
+	}
+
+	return nil, fmt.Errorf("cluster '%s' not found", clusterName)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// backupPollInterval is how often `backup -instance` checks whether the backups finished
+const backupPollInterval = time.Second
+
+// backupParams are the parameters of the "backup" control command
+type backupParams struct {
+	Database string `json:"database,omitempty"` // cluster/namespace/service or cluster/name; all if empty
+}
+
+// backupResult is the result of the "backup" control command
+type backupResult struct {
+	Started []string `json:"started"` // IDs of the forwards whose databases are backed up
+}
+
+// errBackupInProgress is returned when a backup is requested while one of the same
+// database is pending or running
+var errBackupInProgress = errors.New("backup already in progress")
+
+// handleBackup returns the control handler starting backups in the running instance, so
+// automation can request a fresh dump without a second process competing for the ports.
+// The backups run in the background; their progress shows in the status.
+func handleBackup(config *Config, manager *PortForwardManager, backups *BackupManager) ControlHandler {
+	return func(params json.RawMessage) (any, error) {
+		var req backupParams
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, fmt.Errorf("invalid params: %w", err)
+			}
+		}
+		return triggerBackups(config, manager, backups, req.Database)
+	}
+}
+
+// triggerBackups starts backing up the databases of the running instance in the
+// background, or only one (cluster/namespace/service or cluster/name) when database is set
+func triggerBackups(config *Config, manager *PortForwardManager, backups *BackupManager, database string) (backupResult, error) {
+	result := backupResult{Started: []string{}}
+	if backups == nil {
+		return result, fmt.Errorf("no database backups configured")
+	}
+	if backups.draining() {
+		return result, fmt.Errorf("nanoporter is quitting")
+	}
+
+	selected := config
+	if database != "" {
+		var err error
+		if selected, err = onlyBackup(config, database); err != nil {
+			return result, err
+		}
+	}
+
+	type trigger struct {
+		cluster  string
+		forward  ForwardConfig
+		pf       *PortForward
+		previous BackupState // restored when another backup is already running
+	}
+	var triggers []trigger
+	for _, cluster := range selected.Clusters {
+		for _, forward := range cluster.Forwards {
+			if forward.DBBackup == nil {
+				continue
+			}
+			pf := manager.GetForward(forwardID(cluster.Name, forward))
+			if pf == nil {
+				return result, fmt.Errorf("port forward not found for %s/%s/%s", cluster.Name, forward.Namespace, forward.Service)
+			}
+			triggers = append(triggers, trigger{cluster: cluster.Name, forward: forward, pf: pf})
+		}
+	}
+
+	// Pending right away, so a client polling the status doesn't see the previous backup.
+	// Claimed under the forward's lock, so concurrent requests can't both start one.
+	for i, t := range triggers {
+		previous, ok := t.pf.claimBackup()
+		if !ok {
+			for _, claimed := range triggers[:i] {
+				claimed.pf.setBackupState(claimed.previous)
+			}
+			return result, fmt.Errorf("%w: %s is %s", errBackupInProgress, t.pf.ID, previous)
+		}
+		triggers[i].previous = previous
+	}
+
+	for _, t := range triggers {
+		// Counted before the goroutine starts, so WaitTriggered can't miss it
+		backups.triggered.Add(1)
+		go backups.BackupNow(manager, t.cluster, t.forward, t.pf)
+		result.Started = append(result.Started, t.pf.ID)
+	}
+	return result, nil
+}
+
+// runInstanceBackup asks the running instance to back up its databases (or only one) and
+// waits until they're done unless wait is false. Exits 1 when a backup failed.
+func runInstanceBackup(socket, only string, wait bool) {
+	client, err := DialControl(socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: no running instance on %s: %v\n", socket, err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	var result backupResult
+	if err := client.Call("backup", backupParams{Database: only}, &result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, id := range result.Started {
+		fmt.Printf("Backing up %s\n", id)
+	}
+	if !wait {
+		return
+	}
+
+	pending := make(map[string]bool)
+	for _, id := range result.Started {
+		pending[id] = true
+	}
+	failed := 0
+	for len(pending) > 0 {
+		time.Sleep(backupPollInterval)
+
+		var statuses []ForwardStatus
+		if err := client.Call("status", nil, &statuses); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		seen := make(map[string]bool)
+		for _, fs := range statuses {
+			if !pending[fs.ID] {
+				continue
+			}
+			seen[fs.ID] = true
+			switch fs.BackupState {
+			case BackupCompleted:
+				fmt.Printf("✓ %s: %.1f MB\n", fs.ID, fs.BackupSizeMB)
+			case BackupFailed:
+				fmt.Printf("✗ %s: %s\n", fs.ID, fs.BackupError)
+				failed++
+			case BackupPending, BackupRunning:
+				continue
+			default:
+				fmt.Printf("✗ %s: backup not run\n", fs.ID)
+				failed++
+			}
+			delete(pending, fs.ID)
+		}
+		for id := range pending {
+			if !seen[id] {
+				fmt.Printf("✗ %s: port-forward removed\n", id)
+				failed++
+				delete(pending, id)
+			}
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// historyRow is a backup listed on the backup history screen
+type historyRow struct {
+	label   string // cluster/namespace/service of the forward
+	service string // database name restores use; empty when the forward is gone
+	entry   backupEntry
+}
+
+// historyVerifiedMsg carries the checksum verification of the history row at index
+type historyVerifiedMsg struct {
+	index  int
+	file   string
+	result string
+}
+
+// historyNoticeMsg is sent when a delete or restore started from the history screen finishes
+type historyNoticeMsg string
+
+// backupHistory lists the recent backups of every database from the backup state file,
+// verifies their checksums in the background and deletes or restores a selected backup
+type backupHistory struct {
+	manager   *PortForwardManager
+	backupDir string
+
+	rows          []historyRow
+	verified      map[string]string // file -> ✓, ✗ or ? (no checksum)
+	cursor        int
+	confirmDelete bool
+	busy          bool
+	err           string
+
+	// notice is shown on the screen, and in the forward list after it closes
+	notice string
+}
+
+// newBackupHistory opens the history screen and starts verifying the listed backups
+func newBackupHistory(manager *PortForwardManager, backupDir string) (*backupHistory, tea.Cmd) {
+	h := &backupHistory{
+		manager:   manager,
+		backupDir: backupDir,
+		verified:  make(map[string]string),
+	}
+	h.load()
+
+	return h, h.verify(0)
+}
+
+// load reads the history rows from the state file
+func (h *backupHistory) load() {
+	backupStateMu.Lock()
+	state, err := readBackupState(h.backupDir)
+	backupStateMu.Unlock()
+	if err != nil {
+		h.err = err.Error()
+		return
+	}
+
+	// Forwards in the order of the main list, then records of forwards that are gone
+	h.rows = nil
+	seen := make(map[string]bool)
+	for _, fs := range h.manager.Snapshot() {
+		if record := state.Forwards[fs.ID]; record != nil {
+			label := fs.Cluster + "/" + fs.Service
+			if fs.Namespace != "" {
+				label = fs.Cluster + "/" + fs.Namespace + "/" + fs.Service
+			}
+			h.addRows(label, fs.Service, record)
+			seen[fs.ID] = true
+		}
+	}
+	var gone []string
+	for id := range state.Forwards {
+		if !seen[id] {
+			gone = append(gone, id)
+		}
+	}
+	sort.Strings(gone)
+	for _, id := range gone {
+		h.addRows(id, "", state.Forwards[id])
+	}
+
+	if h.cursor >= len(h.rows) {
+		h.cursor = max(len(h.rows)-1, 0)
+	}
+}
+
+// addRows adds the history of one forward
+func (h *backupHistory) addRows(label, service string, record *backupRecord) {
+	for _, entry := range record.History {
+		h.rows = append(h.rows, historyRow{label: label, service: service, entry: entry})
+	}
+}
+
+// verify checks the checksum of the row at index, then continues with the next row
+func (h *backupHistory) verify(index int) tea.Cmd {
+	if index >= len(h.rows) {
+		return nil
+	}
+	file := h.rows[index].entry.File
+
+	return func() tea.Msg {
+		result := "✓"
+		if err := verifyChecksum(file); errors.Is(err, errNoChecksum) {
+			result = "?"
+		} else if err != nil {
+			result = "✗"
+		}
+		return historyVerifiedMsg{index: index, file: file, result: result}
+	}
+}
+
+// Update handles a message while the screen is open. Returns true once the screen is closed.
+func (h *backupHistory) Update(msg tea.Msg) (bool, tea.Cmd) {
+	switch msg := msg.(type) {
+	case historyVerifiedMsg:
+		h.verified[msg.file] = msg.result
+		// Rows listing the same file (unchanged dumps) are verified once
+		next := msg.index + 1
+		for next < len(h.rows) && h.verified[h.rows[next].entry.File] != "" {
+			next++
+		}
+		return false, h.verify(next)
+
+	case historyNoticeMsg:
+		h.busy = false
+		h.notice = string(msg)
+		h.load()
+
+	case tea.KeyMsg:
+		if h.confirmDelete {
+			h.confirmDelete = false
+			if msg.String() == "y" && h.cursor < len(h.rows) {
+				return false, h.delete(h.rows[h.cursor])
+			}
+			return false, nil
+		}
+
+		switch msg.String() {
+		case "esc", "q", "B", "ctrl+c":
+			return true, nil
+		case "up", "k":
+			if h.cursor > 0 {
+				h.cursor--
+			}
+		case "down", "j":
+			if h.cursor < len(h.rows)-1 {
+				h.cursor++
+			}
+		case "x", "delete":
+			if !h.busy && h.cursor < len(h.rows) {
+				h.confirmDelete = true
+			}
+		case "enter":
+			if !h.busy && h.cursor < len(h.rows) {
+				return false, h.restore(h.rows[h.cursor])
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// delete removes a backup in the background
+func (h *backupHistory) delete(row historyRow) tea.Cmd {
+	h.busy = true
+	h.notice = fmt.Sprintf("Deleting %s...", row.entry.File)
+	backupDir := h.backupDir
+
+	return func() tea.Msg {
+		if err := deleteBackup(backupDir, row.entry.File); err != nil {
+			return historyNoticeMsg(fmt.Sprintf("Can't delete %s: %v", row.entry.File, err))
+		}
+		return historyNoticeMsg(fmt.Sprintf("Deleted %s", row.entry.File))
+	}
+}
+
+// restore loads a backup into a local Docker container in the background
+func (h *backupHistory) restore(row historyRow) tea.Cmd {
+	if !strings.HasSuffix(row.entry.File, ".sql.gz") {
+		h.notice = fmt.Sprintf("Can't restore %s: only PostgreSQL dumps can be restored", row.entry.File)
+		return nil
+	}
+	if row.service == "" {
+		h.notice = fmt.Sprintf("Can't restore %s: its forward is no longer configured", row.entry.File)
+		return nil
+	}
+	h.busy = true
+	h.notice = fmt.Sprintf("Restoring %s into a local container...", filepath.Base(row.entry.File))
+
+	return func() tea.Msg {
+		// Progress output would garble the screen; it's only shown when the restore fails
+		var out bytes.Buffer
+		port, err := restoreIntoDocker(&out, row.entry.File, row.service, defaultRestorePort)
+		if err != nil {
+			return historyNoticeMsg(fmt.Sprintf("Can't restore %s: %v\n%s", row.entry.File, err, strings.TrimSpace(out.String())))
+		}
+		return historyNoticeMsg(fmt.Sprintf("Restored %s: %s", row.service, restoreURL(port, row.service)))
+	}
+}
+
+// View renders the history screen
+func (h *backupHistory) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("Backup history"))
+	b.WriteString("\n\n")
+
+	header := fmt.Sprintf("%-40s %-16s %-9s %-9s %-8s %s",
+		"Database", "Time", "Size", "Duration", "Verified", "File")
+	b.WriteString(headerStyle.Render(header))
+	b.WriteString("\n")
+	b.WriteString(strings.Repeat("─", 150))
+	b.WriteString("\n")
+
+	if len(h.rows) == 0 {
+		b.WriteString("No backups recorded yet.\n")
+	}
+
+	// Keep the cursor visible in long lists
+	start := 0
+	if h.cursor >= 20 {
+		start = h.cursor - 19
+	}
+	for i := start; i < len(h.rows) && i < start+20; i++ {
+		row := h.rows[i]
+		verified := h.verified[row.entry.File]
+		if verified == "" {
+			verified = "…"
+		}
+		duration := "<1s"
+		if row.entry.DurationSeconds >= 1 {
+			duration = formatDuration(time.Duration(row.entry.DurationSeconds * float64(time.Second)))
+		}
+		if row.entry.Unchanged {
+			duration += "*"
+		}
+
+		line := fmt.Sprintf("%-40s %-16s %-9s %-9s %-8s %s",
+			truncate(row.label, 40), row.entry.Time.Local().Format("2006-01-02 15:04"),
+			formatSize(row.entry.SizeMB), duration, verified, row.entry.File)
+		if i == h.cursor {
+			b.WriteString(activeStyle.Reverse(true).Render(line))
+		} else {
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
+	}
+
+	if h.err != "" {
+		b.WriteString("\n")
+		b.WriteString(failedStyle.Render(h.err))
+		b.WriteString("\n")
+	}
+
+	if h.confirmDelete && h.cursor < len(h.rows) {
+		b.WriteString("\n")
+		b.WriteString(failedStyle.Render(fmt.Sprintf("Delete %s? (y/n)", h.rows[h.cursor].entry.File)))
+		b.WriteString("\n")
+	} else if h.notice != "" {
+		b.WriteString("\n")
+		b.WriteString(h.notice)
+		b.WriteString("\n")
+	}
+
+	b.WriteString(helpStyle.Render("↑/↓ select • enter restore into local Docker • x delete • * unchanged dump kept • esc back"))
+
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// backupPingTimeout bounds a single ping request
+	backupPingTimeout = 10 * time.Second
+	// backupPingAttempts is how often a ping is tried before giving up
+	backupPingAttempts = 3
+	// backupPingBodyLimit caps the report sent with a ping (healthchecks.io keeps 100 KB)
+	backupPingBodyLimit = 10000
+)
+
+// backupPingClient is the HTTP client used for backup pings
+var backupPingClient = &http.Client{Timeout: backupPingTimeout}
+
+// pingBackupStart tells the monitoring service that backups are starting, with start:
+// true, so it can track their duration and alert on runs that never finish
+func pingBackupStart(config *BackupPingConfig) {
+	if config == nil || !config.Start {
+		return
+	}
+	sendBackupPing(strings.TrimSuffix(config.URL, "/")+"/start", "")
+}
+
+// pingBackupResult reports the outcome of a backup run to the monitoring service, to the
+// fail URL when err is set. The report lists the result of every database. Missing pings
+// then mean the backups didn't run at all.
+func pingBackupResult(config *BackupPingConfig, manager *PortForwardManager, err error) {
+	if config == nil {
+		return
+	}
+
+	var lines []string
+	if manager != nil {
+		for _, fs := range manager.Snapshot() {
+			if !fs.HasBackup {
+				continue
+			}
+			line := fmt.Sprintf("%s/%s/%s: %s", fs.Cluster, fs.Namespace, fs.Service, fs.BackupState)
+			if fs.BackupState == BackupCompleted {
+				line += fmt.Sprintf(" (%.1f MB)", fs.BackupSizeMB)
+			} else if fs.BackupError != "" {
+				line += ": " + fs.BackupError
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	url := config.URL
+	if err != nil {
+		url = config.FailURL
+		if url == "" {
+			url = strings.TrimSuffix(config.URL, "/") + "/fail"
+		}
+		lines = append(lines, err.Error())
+	}
+	sendBackupPing(url, strings.Join(lines, "\n"))
+}
+
+// sendBackupPing POSTs a report to a ping URL, retrying failed attempts. Failures are only
+// logged: the monitoring service alerts on the missing ping anyway.
+func sendBackupPing(url, report string) {
+	url, err := expandEnvReferences(url, "")
+	if err != nil {
+		slog.Warn("Failed to send backup ping", "error", err)
+		return
+	}
+	if len(report) > backupPingBodyLimit {
+		report = report[:backupPingBodyLimit]
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = postBackupPing(url, report)
+		if err == nil {
+			slog.Debug("Sent backup ping", "url", redactPingURL(url))
+			return
+		}
+		if attempt == backupPingAttempts {
+			break
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+	slog.Warn("Failed to send backup ping", "url", redactPingURL(url), "error", err)
+}
+
+// postBackupPing sends a single ping
+func postBackupPing(url, report string) error {
+	resp, err := backupPingClient.Post(url, "text/plain; charset=utf-8", strings.NewReader(report))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// redactPingURL hides the path of a ping URL in logs, since it usually is the secret
+func redactPingURL(url string) string {
+	scheme, rest, ok := strings.Cut(url, "://")
+	if !ok {
+		return "***"
+	}
+	host, _, _ := strings.Cut(rest, "/")
+	return scheme + "://" + host + "/***"
+}
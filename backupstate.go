@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupStateFile records the outcome of the latest backup of each forward in the backup
+// directory, so it's shown again after a restart
+const backupStateFile = ".state.json"
+
+// maxBackupHistory is how many successful backups are remembered per forward
+const maxBackupHistory = 20
+
+// backupStateMu guards reading and rewriting the state file within the process
+var backupStateMu sync.Mutex
+
+// backupStateContents is the JSON document written to backupStateFile
+type backupStateContents struct {
+	Forwards map[string]*backupRecord `json:"forwards"` // forward ID -> latest backup
+}
+
+// backupRecord is the persisted backup state of a forward
+type backupRecord struct {
+	State   BackupState   `json:"state"`
+	Error   string        `json:"error,omitempty"`
+	Time    time.Time     `json:"time,omitzero"` // of the latest successful backup
+	SizeMB  float64       `json:"size_mb,omitempty"`
+	File    string        `json:"file,omitempty"`
+	History []backupEntry `json:"history,omitempty"` // successful backups, newest first
+}
+
+// backupEntry is a successful backup in a forward's history
+type backupEntry struct {
+	Time            time.Time `json:"time"`
+	File            string    `json:"file"`
+	SizeMB          float64   `json:"size_mb"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Unchanged       bool      `json:"unchanged,omitempty"`
+}
+
+// RestoreState sets the backup state of the forwards to what the state file recorded,
+// skipping successful backups whose dump has been removed since
+func (m *BackupManager) RestoreState(manager *PortForwardManager) {
+	backupStateMu.Lock()
+	state, err := readBackupState(m.backupDir)
+	backupStateMu.Unlock()
+	if err != nil {
+		slog.Warn("Failed to read backup state", "error", err)
+		return
+	}
+
+	for _, pf := range manager.GetForwards() {
+		record := state.Forwards[pf.ID]
+		if record == nil || pf.Config.DBBackup == nil {
+			continue
+		}
+		if record.State == BackupCompleted && record.File != "" {
+			if _, err := os.Stat(record.File); err != nil {
+				continue
+			}
+		}
+
+		pf.restoreBackupState(record)
+		manager.emitBackupProgress(pf)
+	}
+}
+
+// recordBackup saves the backup state of a forward after a backup finished (with its
+// result) or failed (result is nil)
+func (m *BackupManager) recordBackup(pf *PortForward, result *BackupResult) {
+	backupStateMu.Lock()
+	defer backupStateMu.Unlock()
+
+	state, err := readBackupState(m.backupDir)
+	if err != nil {
+		// Start over rather than never recording again
+		slog.Warn("Failed to read backup state", "error", err)
+		state = &backupStateContents{Forwards: make(map[string]*backupRecord)}
+	}
+
+	status := pf.Status()
+	record := &backupRecord{
+		State:  status.BackupState,
+		Error:  status.BackupError,
+		Time:   status.BackupTime,
+		SizeMB: status.BackupSizeMB,
+	}
+	if previous := state.Forwards[pf.ID]; previous != nil {
+		// A failed backup keeps pointing at the latest successful dump
+		record.File = previous.File
+		record.History = previous.History
+	}
+	if result != nil {
+		record.File = result.File
+		record.History = append([]backupEntry{{
+			Time:            status.BackupTime,
+			File:            result.File,
+			SizeMB:          result.SizeMB,
+			DurationSeconds: result.Duration.Seconds(),
+			Unchanged:       result.Unchanged,
+		}}, record.History...)
+	}
+	record.History = existingBackups(record.History)
+	state.Forwards[pf.ID] = record
+
+	if err := writeBackupState(m.backupDir, state); err != nil {
+		slog.Warn("Failed to write backup state", "error", err)
+	}
+}
+
+// existingBackups drops history entries whose dump has been pruned and caps the history
+func existingBackups(history []backupEntry) []backupEntry {
+	kept := history[:0]
+	for _, entry := range history {
+		if _, err := os.Stat(entry.File); err == nil && len(kept) < maxBackupHistory {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// deleteBackup removes a dump (with its plain and globals dumps and checksums) and forgets it
+func deleteBackup(backupDir, file string) error {
+	backupStateMu.Lock()
+	defer backupStateMu.Unlock()
+
+	paths := []string{file, strings.TrimSuffix(file, ".gz")}
+	if strings.HasSuffix(file, ".sql.gz") {
+		paths = append(paths, strings.TrimSuffix(file, ".sql.gz")+globalsSuffix)
+	}
+	for _, path := range paths {
+		if err := removeBackup(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	state, err := readBackupState(backupDir)
+	if err != nil {
+		return err
+	}
+	for _, record := range state.Forwards {
+		record.History = existingBackups(record.History)
+	}
+	return writeBackupState(backupDir, state)
+}
+
+// readBackupState reads the state file; a missing file is an empty state
+func readBackupState(backupDir string) (*backupStateContents, error) {
+	state := &backupStateContents{Forwards: make(map[string]*backupRecord)}
+
+	data, err := os.ReadFile(filepath.Join(backupDir, backupStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", backupStateFile, err)
+	}
+	if state.Forwards == nil {
+		state.Forwards = make(map[string]*backupRecord)
+	}
+
+	return state, nil
+}
+
+// writeBackupState replaces the state file
+func writeBackupState(backupDir string, state *backupStateContents) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(backupDir, backupStateFile), append(data, '\n'), 0644)
+}
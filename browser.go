@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// endpointURL returns the URL a browser opens for a forward. Without a configured scheme,
+// forwards with local TLS or to the usual TLS ports use https and all others http.
+func endpointURL(fs ForwardStatus) string {
+	scheme := fs.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if fs.LocalTLS || fs.RemotePort == 443 || fs.RemotePort == 8443 {
+			scheme = "https"
+		}
+	}
+
+	// localhost for the default address, so cookies and CORS settings for it apply
+	host := fs.LocalAddress
+	if host == defaultBindAddress {
+		host = "localhost"
+	}
+
+	return fmt.Sprintf("%s://%s:%d", scheme, host, fs.LocalPort)
+}
+
+// openBrowser opens a URL in the default browser
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Don't leave a zombie behind once the opener exits
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of a traffic capture
+const (
+	defaultCaptureMaxSizeMB = 10
+	defaultCaptureDuration  = 5 * time.Minute
+)
+
+// captureSegmentSize is the largest payload of a synthesized TCP segment in a pcap file
+const captureSegmentSize = 32 * 1024
+
+// trafficCapture records the connections relayed by a forward's local proxy, either as a
+// pcap file of synthesized TCP/IPv4 packets or as a hexdump log. It stops by itself once the
+// file reaches its size limit or its duration is over.
+type trafficCapture struct {
+	path     string
+	pcap     bool
+	limit    int64
+	deadline time.Time
+
+	mu      sync.Mutex
+	file    *os.File
+	written int64
+	timer   *time.Timer
+	stopped bool
+	reason  string // why the capture stopped
+	nextID  int
+	conns   map[int]*capturedConn
+}
+
+// capturedConn is a connection being captured, with the TCP sequence numbers of its
+// synthesized packets
+type capturedConn struct {
+	client, server       netip.AddrPort
+	clientSeq, serverSeq uint32
+}
+
+// startCapture creates the capture file and starts recording
+func startCapture(path string, maxSizeMB int, duration time.Duration) (*trafficCapture, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture file: %w", err)
+	}
+
+	c := &trafficCapture{
+		path:     path,
+		pcap:     strings.EqualFold(filepath.Ext(path), ".pcap"),
+		limit:    int64(maxSizeMB) * 1024 * 1024,
+		deadline: time.Now().Add(duration),
+		file:     file,
+		conns:    make(map[int]*capturedConn),
+	}
+	if c.pcap {
+		// Global header: version 2.4, snaplen 65535, LINKTYPE_RAW (IPv4 without link layer)
+		header := make([]byte, 24)
+		binary.LittleEndian.PutUint32(header[0:], 0xa1b2c3d4)
+		binary.LittleEndian.PutUint16(header[4:], 2)
+		binary.LittleEndian.PutUint16(header[6:], 4)
+		binary.LittleEndian.PutUint32(header[16:], 65535)
+		binary.LittleEndian.PutUint32(header[20:], 101)
+		c.write(header)
+	}
+	c.timer = time.AfterFunc(duration, func() { c.stop("duration reached") })
+	return c, nil
+}
+
+// active reports whether the capture is still recording
+func (c *trafficCapture) active() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.stopped
+}
+
+// stop closes the capture file
+func (c *trafficCapture) stop(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopLocked(reason)
+}
+
+// stopLocked closes the capture file (caller holds c.mu)
+func (c *trafficCapture) stopLocked(reason string) {
+	if c.stopped {
+		return
+	}
+	c.stopped = true
+	c.reason = reason
+	c.timer.Stop()
+	c.file.Close()
+
+	slog.Info("Traffic capture stopped", "path", c.path, "reason", reason, "bytes", c.written)
+}
+
+// write appends to the capture file, stopping the capture at its size limit (caller holds c.mu)
+func (c *trafficCapture) write(data []byte) {
+	if c.stopped {
+		return
+	}
+	if c.written+int64(len(data)) > c.limit {
+		c.stopLocked("size limit reached")
+		return
+	}
+	n, err := c.file.Write(data)
+	c.written += int64(n)
+	if err != nil {
+		c.stopLocked(fmt.Sprintf("write failed: %v", err))
+	}
+}
+
+// open records a new connection from client to the forward's listener at server and
+// returns its ID
+func (c *trafficCapture) open(client, server net.Addr) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	id := c.nextID
+	conn := &capturedConn{client: captureAddr(client), server: captureAddr(server), clientSeq: 1000, serverSeq: 5000}
+	c.conns[id] = conn
+
+	if c.pcap {
+		// Handshake, so Wireshark follows the stream from its start
+		c.packet(conn, true, tcpSYN, nil)
+		c.packet(conn, false, tcpSYN|tcpACK, nil)
+		c.packet(conn, true, tcpACK, nil)
+	} else {
+		c.logf(id, "%s > %s opened", client, server)
+	}
+	return id
+}
+
+// record adds data sent over a captured connection
+func (c *trafficCapture) record(id int, fromClient bool, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn := c.conns[id]
+	if conn == nil || c.stopped {
+		return
+	}
+
+	if !c.pcap {
+		direction := "client > server"
+		if !fromClient {
+			direction = "server > client"
+		}
+		c.logf(id, "%s %d bytes\n%s", direction, len(data), strings.TrimSuffix(hex.Dump(data), "\n"))
+		return
+	}
+	for len(data) > 0 {
+		n := min(len(data), captureSegmentSize)
+		c.packet(conn, fromClient, tcpPSH|tcpACK, data[:n])
+		data = data[n:]
+	}
+}
+
+// closeConn records the end of a captured connection
+func (c *trafficCapture) closeConn(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn := c.conns[id]
+	if conn == nil {
+		return
+	}
+	delete(c.conns, id)
+
+	if c.pcap {
+		c.packet(conn, true, tcpFIN|tcpACK, nil)
+		c.packet(conn, false, tcpFIN|tcpACK, nil)
+		c.packet(conn, true, tcpACK, nil)
+	} else {
+		c.logf(id, "closed")
+	}
+}
+
+// logf writes a line of the hexdump log (caller holds c.mu)
+func (c *trafficCapture) logf(id int, format string, args ...any) {
+	line := fmt.Sprintf("%s #%d %s\n", time.Now().Format("2006-01-02T15:04:05.000000Z07:00"), id, fmt.Sprintf(format, args...))
+	c.write([]byte(line))
+}
+
+// TCP flags of synthesized packets
+const (
+	tcpFIN = 0x01
+	tcpSYN = 0x02
+	tcpPSH = 0x08
+	tcpACK = 0x10
+)
+
+// packet writes a pcap record of a TCP/IPv4 packet of a captured connection and advances
+// its sequence numbers (caller holds c.mu)
+func (c *trafficCapture) packet(conn *capturedConn, fromClient bool, flags byte, payload []byte) {
+	src, dst := conn.client, conn.server
+	seq, ack := &conn.clientSeq, &conn.serverSeq
+	if !fromClient {
+		src, dst = dst, src
+		seq, ack = ack, seq
+	}
+
+	packet := make([]byte, 40+len(payload))
+
+	// IPv4 header
+	ip := packet[:20]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(packet)))
+	binary.BigEndian.PutUint16(ip[6:], 0x4000) // don't fragment
+	ip[8] = 64
+	ip[9] = 6 // TCP
+	srcIP, dstIP := src.Addr().As4(), dst.Addr().As4()
+	copy(ip[12:], srcIP[:])
+	copy(ip[16:], dstIP[:])
+	binary.BigEndian.PutUint16(ip[10:], ipChecksum(ip))
+
+	// TCP header; its checksum is left out, Wireshark doesn't verify it by default
+	tcp := packet[20:40]
+	binary.BigEndian.PutUint16(tcp[0:], src.Port())
+	binary.BigEndian.PutUint16(tcp[2:], dst.Port())
+	binary.BigEndian.PutUint32(tcp[4:], *seq)
+	if flags&tcpACK != 0 {
+		binary.BigEndian.PutUint32(tcp[8:], *ack)
+	}
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], 65535)
+	copy(packet[40:], payload)
+
+	*seq += uint32(len(payload))
+	if flags&(tcpSYN|tcpFIN) != 0 {
+		*seq++
+	}
+
+	now := time.Now()
+	record := make([]byte, 16, 16+len(packet))
+	binary.LittleEndian.PutUint32(record[0:], uint32(now.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(now.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(packet)))
+	c.write(append(record, packet...))
+}
+
+// ipChecksum computes the checksum of an IPv4 header
+func ipChecksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// captureAddr converts a connection address for the IPv4 packets of a pcap file; IPv6
+// addresses are recorded as 127.0.0.1
+func captureAddr(addr net.Addr) netip.AddrPort {
+	addrPort, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return netip.AddrPortFrom(netip.AddrFrom4([4]byte{127, 0, 0, 1}), 0)
+	}
+	ip := addrPort.Addr().Unmap()
+	if !ip.Is4() {
+		ip = netip.AddrFrom4([4]byte{127, 0, 0, 1})
+	}
+	return netip.AddrPortFrom(ip, addrPort.Port())
+}
+
+// captureTap passes the data read from one side of a relayed connection to a capture
+type captureTap struct {
+	capture    *trafficCapture
+	id         int
+	fromClient bool
+}
+
+// Write records the data; capturing never fails the relayed connection
+func (t captureTap) Write(p []byte) (int, error) {
+	t.capture.record(t.id, t.fromClient, p)
+	return len(p), nil
+}
+
+// activeCapture returns the forward's running capture, nil when it isn't capturing
+func (pf *PortForward) activeCapture() *trafficCapture {
+	pf.mu.RLock()
+	capture := pf.capture
+	pf.mu.RUnlock()
+	if capture == nil || !capture.active() {
+		return nil
+	}
+	return capture
+}
+
+// StartCapture starts capturing the connections relayed by a forward. Kubernetes forwards
+// that aren't listening through nanoporter's proxy reconnect through it.
+func (m *PortForwardManager) StartCapture(pf *PortForward) (string, error) {
+	if pf.activeCapture() != nil {
+		return "", fmt.Errorf("already capturing")
+	}
+
+	settings := CaptureConfig{}
+	if pf.Config.Capture != nil {
+		settings = *pf.Config.Capture
+	}
+	if settings.Path == "" {
+		settings.Path = filepath.Join("captures", fmt.Sprintf("%s-%s.pcap",
+			forwardFileName(pf), time.Now().Format("20060102-150405")))
+	}
+	if settings.MaxSizeMB == 0 {
+		settings.MaxSizeMB = defaultCaptureMaxSizeMB
+	}
+	if settings.Duration == 0 {
+		settings.Duration = defaultCaptureDuration
+	}
+
+	capture, err := startCapture(settings.Path, settings.MaxSizeMB, settings.Duration)
+	if err != nil {
+		return "", err
+	}
+
+	pf.mu.Lock()
+	pf.capture = capture
+	reconnect := pf.Config.IsKubernetes() && !pf.viaProxy && pf.State == StateActive
+	pf.mu.Unlock()
+
+	slog.Info("Traffic capture started",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+		"path", settings.Path,
+		"max_size_mb", settings.MaxSizeMB,
+		"duration", settings.Duration,
+	)
+
+	if reconnect {
+		pf.reconnect()
+	}
+	return settings.Path, nil
+}
+
+// StopCapture stops a forward's capture and returns the path of its file
+func (m *PortForwardManager) StopCapture(pf *PortForward) (string, error) {
+	capture := pf.activeCapture()
+	if capture == nil {
+		return "", fmt.Errorf("not capturing")
+	}
+	capture.stop("stopped")
+	return capture.path, nil
+}
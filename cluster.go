@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// minRefreshInterval limits how often a cluster's credentials are rebuilt
+	minRefreshInterval = 10 * time.Second
+	// minLoginInterval limits how often a cluster's login command is run
+	minLoginInterval = time.Minute
+	// loginTimeout bounds a single run of a login command (SSO flows can take a while)
+	loginTimeout = 5 * time.Minute
+	// apiProbeTimeout bounds a single reachability check of an API server
+	apiProbeTimeout = 5 * time.Second
+	// maxProbeAge is how old a probe may be for a failing forward to rely on it
+	maxProbeAge = 5 * time.Second
+)
+
+// errRefreshThrottled is returned when credentials were rebuilt too recently
+var errRefreshThrottled = errors.New("credentials refreshed recently")
+
+// errClusterUnavailable is returned for clusters whose kubeconfig couldn't be loaded yet
+var errClusterUnavailable = errors.New("cluster unavailable")
+
+// ClusterClient holds the Kubernetes client of a cluster shared by its forwards and
+// rebuilds it from the kubeconfig when credentials expire
+type ClusterClient struct {
+	Name string
+
+	config      ClusterConfig
+	mu          sync.RWMutex
+	restConfig  *rest.Config
+	clientset   *kubernetes.Clientset
+	lastRefresh time.Time
+	lastLogin   time.Time
+	authExpired bool
+	loadErr     error // why the kubeconfig couldn't be loaded, nil once it was
+	refreshed   chan struct{}
+	kubeContext string                     // context used from the kubeconfig
+	probeMu     sync.Mutex                 // held while probing, so concurrent probes share one request
+	probed      time.Time                  // when the API server was last probed
+	probeErr    error                      // why the API server didn't answer the last probe
+	probeRTT    time.Duration              // round-trip time of the last successful probe
+	reachable   chan struct{}              // closed when the API server answers again
+	caches      map[string]*namespaceCache // pods and services by namespace, started on first use
+}
+
+// NewClusterClient loads the kubeconfig of a cluster. Clusters using exec credential
+// plugins are contacted once right away, while the terminal is still available for
+// interactive prompts (SSO logins, MFA); afterwards plugins run without stdin.
+func NewClusterClient(cluster ClusterConfig) (*ClusterClient, error) {
+	c := &ClusterClient{
+		Name:        cluster.Name,
+		config:      cluster,
+		refreshed:   make(chan struct{}),
+		reachable:   make(chan struct{}),
+		kubeContext: kubeContextName(cluster),
+	}
+
+	if err := validateKubeContext(cluster); err != nil {
+		return nil, err
+	}
+
+	restConfig, clientset, err := loadKubeconfig(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if restConfig.ExecProvider != nil {
+		if err := warmUpCredentials(clientset); err != nil {
+			slog.Warn("Failed to obtain credentials from exec plugin",
+				"cluster", cluster.Name,
+				"command", restConfig.ExecProvider.Command,
+				"error", err,
+			)
+		}
+	}
+
+	c.set(restConfig, clientset)
+	return c, nil
+}
+
+// newUnavailableClusterClient returns a client for a cluster whose kubeconfig failed to load.
+// Get returns nil until a Refresh succeeds.
+func newUnavailableClusterClient(cluster ClusterConfig, err error) *ClusterClient {
+	return &ClusterClient{
+		Name:        cluster.Name,
+		config:      cluster,
+		loadErr:     err,
+		refreshed:   make(chan struct{}),
+		reachable:   make(chan struct{}),
+		kubeContext: kubeContextName(cluster),
+	}
+}
+
+// Err returns errClusterUnavailable wrapping why the kubeconfig couldn't be loaded, or nil
+func (c *ClusterClient) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.loadErr != nil {
+		return fmt.Errorf("%w: %v", errClusterUnavailable, c.loadErr)
+	}
+	return nil
+}
+
+// Get returns the current REST config and clientset
+func (c *ClusterClient) Get() (*rest.Config, *kubernetes.Clientset) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.restConfig, c.clientset
+}
+
+// Refresh reloads the kubeconfig and rebuilds the client, so exec plugins are re-run
+// and rewritten kubeconfig files are picked up. Calls within minRefreshInterval of the
+// previous refresh return errRefreshThrottled.
+func (c *ClusterClient) Refresh() error {
+	c.mu.Lock()
+	if time.Since(c.lastRefresh) < minRefreshInterval {
+		c.mu.Unlock()
+		return errRefreshThrottled
+	}
+	c.lastRefresh = time.Now()
+	c.mu.Unlock()
+
+	slog.Info("Refreshing cluster credentials", "cluster", c.Name)
+
+	if c.config.LoginCommand != "" {
+		if err := c.login(); err != nil {
+			slog.Warn("Login command failed", "cluster", c.Name, "error", err)
+		}
+	}
+
+	return c.Reload()
+}
+
+// Reload rebuilds the client from the kubeconfig right away, without running the login
+// command, and wakes up forwards waiting for new credentials
+func (c *ClusterClient) Reload() error {
+	err := validateKubeContext(c.config)
+	var restConfig *rest.Config
+	var clientset *kubernetes.Clientset
+	if err == nil {
+		restConfig, clientset, err = loadKubeconfig(c.config)
+	}
+	if err != nil {
+		c.mu.Lock()
+		if c.loadErr != nil {
+			c.loadErr = err
+		}
+		c.mu.Unlock()
+		return fmt.Errorf("failed to reload kubeconfig for cluster %s: %w", c.Name, err)
+	}
+
+	if c.Err() != nil {
+		slog.Info("Cluster kubeconfig loaded", "cluster", c.Name)
+	}
+	c.set(restConfig, clientset)
+
+	// The current context may have been switched
+	kubeContext := kubeContextName(c.config)
+	c.mu.Lock()
+	c.kubeContext = kubeContext
+	c.mu.Unlock()
+
+	// Wake up forwards waiting for new credentials
+	c.mu.Lock()
+	close(c.refreshed)
+	c.refreshed = make(chan struct{})
+	c.mu.Unlock()
+
+	return nil
+}
+
+// login runs the cluster's login command (e.g. `tsh kube login`), at most once per minLoginInterval
+func (c *ClusterClient) login() error {
+	c.mu.Lock()
+	if time.Since(c.lastLogin) < minLoginInterval {
+		c.mu.Unlock()
+		return nil
+	}
+	c.lastLogin = time.Now()
+	c.mu.Unlock()
+
+	slog.Info("Running login command", "cluster", c.Name, "command", c.config.LoginCommand)
+
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, c.config.LoginCommand)
+	cmd.Env = append(os.Environ(), "NANOPORTER_CLUSTER="+c.Name)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	slog.Info("Login command completed", "cluster", c.Name)
+	return nil
+}
+
+// Refreshed returns a channel that is closed the next time credentials are refreshed
+func (c *ClusterClient) Refreshed() <-chan struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.refreshed
+}
+
+// SetAuthExpired records whether the cluster is currently rejecting our credentials
+func (c *ClusterClient) SetAuthExpired(expired bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.authExpired != expired {
+		slog.Info("Cluster authentication state changed", "cluster", c.Name, "auth_expired", expired)
+	}
+	c.authExpired = expired
+}
+
+// AuthExpired reports whether the cluster is currently rejecting our credentials
+func (c *ClusterClient) AuthExpired() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.authExpired
+}
+
+// Probe checks whether the API server answers and records the result
+func (c *ClusterClient) Probe() {
+	c.probeMu.Lock()
+	defer c.probeMu.Unlock()
+	c.probe()
+}
+
+// Unreachable reports whether the API server doesn't answer, probing it unless that was
+// done within maxProbeAge. The forwards of a cluster failing at once share one probe.
+// An API server answering with an error, e.g. rejecting the credentials, is reachable.
+func (c *ClusterClient) Unreachable() bool {
+	c.probeMu.Lock()
+	defer c.probeMu.Unlock()
+
+	if probed, _, _ := c.Reachability(); time.Since(probed) > maxProbeAge {
+		c.probe()
+	}
+	_, _, err := c.Reachability()
+	var status apierrors.APIStatus
+	return err != nil && !errors.As(err, &status)
+}
+
+// Reachable returns a channel that is closed the next time a probe finds the API server
+// answering after it didn't
+func (c *ClusterClient) Reachable() <-chan struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.reachable
+}
+
+// probe checks the API server like Probe (caller holds c.probeMu)
+func (c *ClusterClient) probe() {
+	_, clientset := c.Get()
+	var err error
+	var rtt time.Duration
+	if clientset == nil {
+		err = c.Err()
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), apiProbeTimeout)
+		defer cancel()
+		start := time.Now()
+		err = clientset.CoreV1().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+		rtt = time.Since(start)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.probed.IsZero() && (err == nil) != (c.probeErr == nil) {
+		if err != nil {
+			slog.Warn("API server unreachable", "cluster", c.Name, "error", err)
+		} else {
+			slog.Info("API server reachable again", "cluster", c.Name)
+			close(c.reachable)
+			c.reachable = make(chan struct{})
+		}
+	}
+	c.probed = time.Now()
+	c.probeErr = err
+	if err == nil {
+		c.probeRTT = rtt
+	}
+}
+
+// Reachability returns when the API server was last probed (zero before the first probe),
+// how long it took to answer and why it didn't answer
+func (c *ClusterClient) Reachability() (time.Time, time.Duration, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.probed, c.probeRTT, c.probeErr
+}
+
+// Context returns the name of the kubeconfig context the cluster uses
+func (c *ClusterClient) Context() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.kubeContext
+}
+
+// kubeContextName returns the configured context of a cluster, or the kubeconfig's current
+// context when none is configured (empty when the kubeconfig can't be read)
+func kubeContextName(cluster ClusterConfig) string {
+	if cluster.Context != "" {
+		return cluster.Context
+	}
+	rawConfig, err := kubeconfigLoadingRules(cluster.Kubeconfig).Load()
+	if err != nil {
+		return ""
+	}
+	return rawConfig.CurrentContext
+}
+
+// set stores a new client, disabling interactive exec plugin prompts since the TUI owns the terminal
+func (c *ClusterClient) set(restConfig *rest.Config, clientset *kubernetes.Clientset) {
+	if restConfig.ExecProvider != nil {
+		restConfig.ExecProvider.StdinUnavailable = true
+		restConfig.ExecProvider.StdinUnavailableMessage = "nanoporter owns the terminal; log in from another terminal and nanoporter will pick up the new credentials"
+
+		var err error
+		clientset, err = kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			slog.Warn("Failed to rebuild clientset", "cluster", c.Name, "error", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.restConfig = restConfig
+	c.clientset = clientset
+	c.loadErr = nil
+	c.stopCaches()
+}
+
+// warmUpCredentials makes a cheap API call so exec plugins obtain credentials up front
+func warmUpCredentials(clientset *kubernetes.Clientset) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	return clientset.CoreV1().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+}
+
+// isUnauthorized reports whether an error means the cluster rejected our credentials
+func isUnauthorized(err error) bool {
+	if err == nil {
+		return false
+	}
+	if apierrors.IsUnauthorized(err) {
+		return true
+	}
+	// SPDY upgrade failures only carry the status text
+	return strings.Contains(err.Error(), "Unauthorized")
+}
+
+// ClusterStatus is a snapshot of a cluster's connectivity and the state of its forwards
+type ClusterStatus struct {
+	Name        string    `json:"name"`
+	Kubernetes  bool      `json:"kubernetes"` // false for clusters of only ssh, docker and tcp forwards
+	Context     string    `json:"context,omitempty"`
+	Probed      time.Time `json:"probed,omitzero"`
+	Reachable   bool      `json:"reachable"`
+	Error       string    `json:"error,omitempty"`
+	AuthExpired bool      `json:"auth_expired,omitempty"`
+	Disabled    bool      `json:"disabled,omitempty"`
+	Forwards    int       `json:"forwards"`
+	Active      int       `json:"active"`
+	Down        int       `json:"down"`                 // reconnecting, waiting for credentials or failed
+	LatencyMS   float64   `json:"latency_ms,omitempty"` // round trip of the last API server probe
+}
+
+// ClusterStatuses returns the status of each cluster, in the order of their forwards
+func (m *PortForwardManager) ClusterStatuses() []ClusterStatus {
+	var statuses []ClusterStatus
+	index := make(map[string]int)
+
+	for _, fs := range m.Snapshot() {
+		i, ok := index[fs.Cluster]
+		if !ok {
+			i = len(statuses)
+			index[fs.Cluster] = i
+			statuses = append(statuses, m.clusterStatus(fs.Cluster))
+		}
+
+		status := &statuses[i]
+		status.Forwards++
+		switch fs.State {
+		case StateActive:
+			status.Active++
+		case StateReconnecting, StateAuthExpired, StateFailed:
+			status.Down++
+		}
+	}
+
+	return statuses
+}
+
+// clusterStatus returns the connectivity of a cluster, without forward counts
+func (m *PortForwardManager) clusterStatus(name string) ClusterStatus {
+	status := ClusterStatus{Name: name, Disabled: m.ClusterDisabled(name)}
+
+	client := m.ClusterClient(name)
+	if client == nil {
+		return status
+	}
+	status.Kubernetes = true
+	status.Context = client.Context()
+	status.AuthExpired = client.AuthExpired()
+
+	probed, rtt, err := client.Reachability()
+	status.Probed = probed
+	status.Reachable = !probed.IsZero() && err == nil
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.LatencyMS = milliseconds(rtt)
+	}
+	return status
+}
+
+// probeClusters checks the API servers of all enabled clusters in the background
+func (m *PortForwardManager) probeClusters() {
+	m.mu.RLock()
+	if m.paused {
+		m.mu.RUnlock()
+		return
+	}
+	var clients []*ClusterClient
+	for name, client := range m.clusters {
+		if !m.disabled[name] {
+			clients = append(clients, client)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, client := range clients {
+		go client.Probe()
+	}
+}
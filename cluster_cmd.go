@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// clusterParams are the parameters of the "enable" and "disable" control commands
+type clusterParams struct {
+	Cluster string `json:"cluster"`
+}
+
+// runClusterCommand enables or disables a cluster of the running instance; command is
+// "enable" or "disable"
+func runClusterCommand(command string) {
+	clusterFlags := flag.NewFlagSet(command, flag.ExitOnError)
+	configPath := clusterFlags.String("config", defaultConfigPath, "Path to configuration file")
+	socketPath := clusterFlags.String("socket", "", "Control socket of the running instance (default: from config)")
+	clusterName := clusterFlags.String("cluster", "", "Cluster to "+command)
+	clusterFlags.Parse(os.Args[2:])
+
+	if *clusterName == "" {
+		fmt.Fprintf(os.Stderr, "Usage: nanoporter %s --cluster NAME\n", command)
+		clusterFlags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	socket := *socketPath
+	if socket == "" {
+		socket = controlSocketFromConfig(*configPath)
+	}
+
+	client, err := DialControl(socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: no running instance on %s: %v\n", socket, err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	if err := client.Call(command, clusterParams{Cluster: *clusterName}, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Cluster %s %sd\n", *clusterName, command)
+}
+
+// handleCluster returns the control handler enabling or disabling a cluster
+func handleCluster(apply func(name string) error) ControlHandler {
+	return func(params json.RawMessage) (any, error) {
+		var req clusterParams
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+		return nil, apply(req.Cluster)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// cacheSyncTimeout bounds the initial listing of a namespace's pods and services
+	cacheSyncTimeout = 15 * time.Second
+	// cacheRetryInterval is how long a namespace that failed to sync is queried directly
+	// before its informers are tried again
+	cacheRetryInterval = 5 * time.Minute
+)
+
+// namespaceCache keeps the pods and services of a namespace up to date through informers
+// shared by all forwards into the namespace
+type namespaceCache struct {
+	ready    chan struct{} // closed once synced or given up
+	synced   bool          // the listers can be used, set before ready is closed
+	failedAt time.Time     // when syncing was given up
+	stop     chan struct{}
+	stopOnce sync.Once
+	pods     corelisters.PodNamespaceLister
+	services corelisters.ServiceNamespaceLister
+}
+
+// namespaceCache returns the synced cache of a namespace's pods and services, starting its
+// informers on first use. Returns nil while they can't sync, e.g. without permission to
+// list and watch, so callers query the API server directly instead.
+func (c *ClusterClient) namespaceCache(namespace string) *namespaceCache {
+	c.mu.Lock()
+	nc := c.caches[namespace]
+	if nc != nil && nc.failed() && time.Since(nc.failedAt) >= cacheRetryInterval {
+		nc = nil
+	}
+	if nc == nil {
+		if c.restConfig == nil {
+			c.mu.Unlock()
+			return nil
+		}
+		if c.caches == nil {
+			c.caches = make(map[string]*namespaceCache)
+		}
+		nc = &namespaceCache{ready: make(chan struct{}), stop: make(chan struct{})}
+		c.caches[namespace] = nc
+		go nc.start(c.Name, c.restConfig, namespace)
+	}
+	c.mu.Unlock()
+
+	<-nc.ready
+	if !nc.synced {
+		return nil
+	}
+	return nc
+}
+
+// stopCaches stops the informers of all namespaces, e.g. because the credentials changed;
+// they're started again on next use (caller holds c.mu)
+func (c *ClusterClient) stopCaches() {
+	for namespace, nc := range c.caches {
+		nc.close()
+		delete(c.caches, namespace)
+	}
+}
+
+// start runs the namespace's informers and waits for their initial listing
+func (nc *namespaceCache) start(clusterName string, restConfig *rest.Config, namespace string) {
+	defer close(nc.ready)
+
+	// Watches run for a long time, so the cluster's request timeout doesn't apply
+	config := rest.CopyConfig(restConfig)
+	config.Timeout = 0
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		nc.fail(clusterName, namespace, fmt.Errorf("failed to create clientset: %w", err))
+		return
+	}
+
+	// Without permission to list and watch there's no point in waiting for the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), cacheSyncTimeout)
+	defer cancel()
+	var mu sync.Mutex
+	var watchErr error
+	onWatchError := func(_ *cache.Reflector, err error) {
+		if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) {
+			mu.Lock()
+			watchErr = err
+			mu.Unlock()
+			cancel()
+		}
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	pods := factory.Core().V1().Pods()
+	services := factory.Core().V1().Services()
+	pods.Informer().SetWatchErrorHandler(onWatchError)
+	services.Informer().SetWatchErrorHandler(onWatchError)
+	nc.pods = pods.Lister().Pods(namespace)
+	nc.services = services.Lister().Services(namespace)
+	factory.Start(nc.stop)
+
+	go func() {
+		select {
+		case <-nc.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if !cache.WaitForCacheSync(ctx.Done(), pods.Informer().HasSynced, services.Informer().HasSynced) {
+		mu.Lock()
+		err := watchErr
+		mu.Unlock()
+		if err == nil {
+			err = fmt.Errorf("pods and services not listed within %s", cacheSyncTimeout)
+		}
+		nc.fail(clusterName, namespace, err)
+		return
+	}
+
+	nc.synced = true
+	slog.Debug("Caching pods and services", "cluster", clusterName, "namespace", namespace)
+}
+
+// fail stops the informers of a namespace that didn't sync
+func (nc *namespaceCache) fail(clusterName, namespace string, err error) {
+	nc.failedAt = time.Now()
+	nc.close()
+	slog.Warn("Failed to cache pods and services, querying them directly",
+		"cluster", clusterName,
+		"namespace", namespace,
+		"retry_in", cacheRetryInterval,
+		"error", err,
+	)
+}
+
+// failed reports whether the cache gave up syncing
+func (nc *namespaceCache) failed() bool {
+	select {
+	case <-nc.ready:
+		return !nc.synced
+	default:
+		return false
+	}
+}
+
+// close stops the informers
+func (nc *namespaceCache) close() {
+	nc.stopOnce.Do(func() { close(nc.stop) })
+}
+
+// pod returns a cached pod by name
+func (nc *namespaceCache) pod(name string) (*corev1.Pod, error) {
+	return nc.pods.Get(name)
+}
+
+// service returns a cached service by name
+func (nc *namespaceCache) service(name string) (*corev1.Service, error) {
+	return nc.services.Get(name)
+}
+
+// selectPods returns the cached pods matching a selector, sorted by name like the API
+// server lists them
+func (nc *namespaceCache) selectPods(selector map[string]string) ([]*corev1.Pod, error) {
+	pods, err := nc.pods.List(labels.SelectorFromSet(selector))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods, nil
+}
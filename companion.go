@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Restart policies of companion commands
+const (
+	companionRestartNo        = "no"
+	companionRestartOnFailure = "on-failure"
+	companionRestartAlways    = "always"
+)
+
+const (
+	companionRestartDelay = 5 * time.Second // between a companion exiting and its restart
+	companionStopTimeout  = 5 * time.Second // between SIGTERM and SIGKILL when stopping one
+)
+
+// CompanionSupervisor runs the companion commands of forwards: started when a forward
+// becomes active, restarted per restart policy and stopped when the forward is stopped,
+// fails for good, is disabled, paused or removed. Reconnects leave them running, the
+// local port comes back by itself; a companion that exited for good is started again when
+// its forward becomes active again.
+type CompanionSupervisor struct {
+	manager   *PortForwardManager
+	mu        sync.Mutex
+	processes map[string]*companionProcess // by forward ID
+	stopped   bool
+}
+
+// companionProcess is the supervision of one forward's companion
+type companionProcess struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopping bool // cancelled, a new one may be started once done is closed
+}
+
+// NewCompanionSupervisor creates a supervisor and registers it for manager updates
+func NewCompanionSupervisor(manager *PortForwardManager) *CompanionSupervisor {
+	s := &CompanionSupervisor{
+		manager:   manager,
+		processes: make(map[string]*companionProcess),
+	}
+	manager.OnEvent(s.handle)
+	return s
+}
+
+// handle starts or stops a companion when its forward changes state
+func (s *CompanionSupervisor) handle(e Event) {
+	switch e.Type {
+	case EventForwardRemoved:
+		s.stop(e.Forward.ID)
+		return
+	case EventStateChanged:
+	default:
+		return
+	}
+
+	switch e.Forward.State {
+	case StateActive:
+		if pf := s.manager.GetForward(e.Forward.ID); pf != nil && pf.Config.Companion != nil {
+			s.start(pf)
+		}
+	case StateStopped, StateFailed, StateDisabled, StatePaused:
+		s.stop(e.Forward.ID)
+	}
+}
+
+// start starts supervising a forward's companion unless it's supervised already. A
+// companion still stopping is waited for first, so the two never run at once.
+func (s *CompanionSupervisor) start(pf *PortForward) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.processes[pf.ID]
+	if s.stopped || (previous != nil && !previous.stopping) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	process := &companionProcess{cancel: cancel, done: make(chan struct{})}
+	s.processes[pf.ID] = process
+
+	go func() {
+		defer close(process.done)
+		if previous != nil {
+			<-previous.done
+		}
+		s.supervise(ctx, pf)
+
+		// Stopped, or exited by itself for good: the next activation starts it again
+		s.mu.Lock()
+		if s.processes[pf.ID] == process {
+			delete(s.processes, pf.ID)
+		}
+		s.mu.Unlock()
+	}()
+}
+
+// stop stops a forward's companion without waiting for it to exit
+func (s *CompanionSupervisor) stop(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if process := s.processes[id]; process != nil && !process.stopping {
+		process.stopping = true
+		process.cancel()
+	}
+}
+
+// StopAll stops all companions and waits for them to exit. None are started afterwards.
+func (s *CompanionSupervisor) StopAll() {
+	s.mu.Lock()
+	s.stopped = true
+	processes := make([]*companionProcess, 0, len(s.processes))
+	for _, process := range s.processes {
+		process.stopping = true
+		process.cancel()
+		processes = append(processes, process)
+	}
+	s.mu.Unlock()
+
+	for _, process := range processes {
+		<-process.done
+	}
+}
+
+// supervise runs a forward's companion until ctx is cancelled, restarting it after it
+// exited as its restart policy says
+func (s *CompanionSupervisor) supervise(ctx context.Context, pf *PortForward) {
+	companion := pf.Config.Companion
+	for {
+		err := runCompanion(ctx, pf, companion)
+		if ctx.Err() != nil {
+			return
+		}
+
+		restart := companion.Restart == companionRestartAlways ||
+			(companion.Restart == companionRestartOnFailure && err != nil)
+		attrs := []any{
+			"cluster", pf.ClusterName,
+			"namespace", pf.Config.Namespace,
+			"service", pf.Config.Service,
+			"restart", restart,
+		}
+		if err != nil {
+			slog.Warn("Companion failed", append(attrs, "error", err)...)
+		} else {
+			slog.Info("Companion exited", attrs...)
+		}
+		if !restart {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(companionRestartDelay):
+		}
+	}
+}
+
+// runCompanion runs a companion command until it exits or ctx is cancelled, which stops
+// it with SIGTERM and, after companionStopTimeout, SIGKILL
+func runCompanion(ctx context.Context, pf *PortForward, companion *CompanionConfig) error {
+	cmd := shellCommand(context.Background(), companion.Command)
+	cmd.Env = append(os.Environ(), forwardEnv(pf)...)
+	startProcessGroup(cmd)
+
+	if companion.Log != "" {
+		if err := os.MkdirAll(filepath.Dir(companion.Log), 0755); err != nil {
+			return fmt.Errorf("failed to create companion log directory: %w", err)
+		}
+		file, err := os.OpenFile(companion.Log, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open companion log: %w", err)
+		}
+		defer file.Close()
+		cmd.Stdout = file
+		cmd.Stderr = file
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start companion: %w", err)
+	}
+	slog.Info("Companion started",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+		"pid", cmd.Process.Pid,
+	)
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		return err
+	case <-ctx.Done():
+	}
+
+	if err := signalProcessGroup(cmd, false); err != nil {
+		slog.Debug("Failed to signal companion", "service", pf.Config.Service, "error", err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(companionStopTimeout):
+		signalProcessGroup(cmd, true)
+		<-exited
+	}
+
+	slog.Info("Companion stopped",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+	)
+	return nil
+}
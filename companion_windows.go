@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// startProcessGroup does nothing on Windows, signalProcessGroup ends the process tree instead
+func startProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup ends a started command and the processes it started. Windows has no
+// SIGTERM to ask them first, so they're always ended forcefully.
+func signalProcessGroup(cmd *exec.Cmd, kill bool) error {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
@@ -2,7 +2,16 @@ package main
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -10,39 +19,393 @@ import (
 
 // Config represents the main configuration structure
 type Config struct {
-	CheckInterval  time.Duration   `yaml:"check_interval"`
-	ReconnectDelay time.Duration   `yaml:"reconnect_delay"`
-	Clusters       []ClusterConfig `yaml:"clusters"`
+	CheckInterval      time.Duration     `yaml:"check_interval"`
+	ReconnectDelay     time.Duration     `yaml:"reconnect_delay"`
+	Backoff            *BackoffConfig    `yaml:"backoff,omitempty"`
+	MaxRetries         int               `yaml:"max_retries,omitempty"`         // give up after this many failed reconnects (0: never)
+	RetryWindow        time.Duration     `yaml:"retry_window,omitempty"`        // give up after failing this long (0: never)
+	StartupConcurrency int               `yaml:"startup_concurrency,omitempty"` // port-forwards established at once
+	StartDelay         time.Duration     `yaml:"start_delay,omitempty"`         // pause between start_order phases
+	ReconnectRate      float64           `yaml:"reconnect_rate,omitempty"`      // reconnect attempts per second and cluster
+	ReconnectBurst     int               `yaml:"reconnect_burst,omitempty"`     // reconnect attempts of a cluster at once before reconnect_rate applies
+	ControlSocket      string            `yaml:"control_socket,omitempty"`
+	ControlUsers       []string          `yaml:"control_users,omitempty"`  // users allowed to use the control socket besides the owner
+	ControlGroups      []string          `yaml:"control_groups,omitempty"` // groups allowed to use the control socket
+	KillTimeout        time.Duration     `yaml:"kill_timeout"`
+	KillEscalate       bool              `yaml:"kill_escalate"`
+	EnvFile            *EnvFileConfig    `yaml:"env_file,omitempty"`
+	StatusFile         string            `yaml:"status_file,omitempty"` // JSON file rewritten on every state change
+	StateFile          string            `yaml:"state_file,omitempty"`  // statistics kept across restarts (default: nanoporter-state.json)
+	BackupPing         *BackupPingConfig `yaml:"backup_ping,omitempty"`
+	AuditLog           string            `yaml:"audit_log,omitempty"`    // file recording control actions, their source and outcome
+	UpdateCheck        bool              `yaml:"update_check,omitempty"` // look for a newer release on startup
+	HostsFile          *HostsFileConfig  `yaml:"hosts_file,omitempty"`
+	DNS                *DNSConfig        `yaml:"dns,omitempty"`
+	API                *APIConfig        `yaml:"api,omitempty"` // HTTP API for automation that can't use the control socket
+	Discovery          *DiscoveryConfig  `yaml:"discovery,omitempty"`
+	TUI                *TUIConfig        `yaml:"tui,omitempty"`
+	SocketOptions      *SocketOptions    `yaml:"socket_options,omitempty"` // local listener options of all forwards
+	Clusters           []ClusterConfig   `yaml:"clusters"`
 }
 
+// TUIConfig configures the terminal UI
+type TUIConfig struct {
+	Columns      []string            `yaml:"columns,omitempty"`       // columns in display order (default: all but pod, uptime and latency)
+	ColumnWidths map[string]int      `yaml:"column_widths,omitempty"` // column name -> width in characters
+	Keymap       map[string][]string `yaml:"keymap,omitempty"`        // action -> keys, replacing its default keys
+	Mode         string              `yaml:"mode,omitempty"`          // "auto" (default), "wide" or "compact"
+}
+
+// EnvFileConfig configures the generated file listing endpoints of active forwards
+type EnvFileConfig struct {
+	Path     string `yaml:"path"`
+	Template string `yaml:"template,omitempty"` // text/template rendered once per active forward
+}
+
+// HostsFileConfig configures managed /etc/hosts entries for forward hostnames
+type HostsFileConfig struct {
+	Path string `yaml:"path,omitempty"` // default: /etc/hosts (or the Windows equivalent)
+	Sudo bool   `yaml:"sudo,omitempty"` // write through `sudo tee` when the file isn't writable
+}
+
+// BackupPingConfig configures dead-man pings to healthchecks.io or a similar service after
+// the backups ran, so missed or failing backups raise an alert
+type BackupPingConfig struct {
+	URL     string `yaml:"url"`                // pinged when all backups succeeded
+	FailURL string `yaml:"fail_url,omitempty"` // pinged when a backup failed (default: url + /fail)
+	Start   bool   `yaml:"start,omitempty"`    // also ping url + /start before the backups
+}
+
+// APIConfig configures the HTTP API for triggering backups
+type APIConfig struct {
+	Listen string `yaml:"listen,omitempty"` // TCP address (default: 127.0.0.1:7070)
+	Token  string `yaml:"token"`            // bearer token of every request; may be ${VAR} or keyring:service/account
+}
+
+// DNSConfig configures the embedded DNS resolver for forwarded service names
+type DNSConfig struct {
+	Listen string `yaml:"listen,omitempty"` // UDP address (default: 127.0.0.1:5353)
+	Domain string `yaml:"domain,omitempty"` // cluster domain (default: cluster.local)
+}
+
+// defaultStartupConcurrency is how many port-forwards are established at once by default
+const defaultStartupConcurrency = 10
+
+// Reconnect attempts allowed per cluster by default: a burst, then this many per second
+const (
+	defaultReconnectRate  = 5
+	defaultReconnectBurst = 10
+)
+
+// BackoffConfig controls the delay between reconnection attempts
+type BackoffConfig struct {
+	Base       time.Duration `yaml:"base,omitempty"`       // delay after the first failure (default: reconnect_delay)
+	Multiplier float64       `yaml:"multiplier,omitempty"` // growth per failed attempt (default: 2)
+	Max        time.Duration `yaml:"max,omitempty"`        // upper bound of the delay (default: 60s)
+	Jitter     *float64      `yaml:"jitter,omitempty"`     // +/- fraction of the delay randomized (default: 0.2)
+}
+
+// SocketOptions tunes the local listening socket of a forward. Kubernetes forwards with
+// socket options listen through nanoporter's own proxy instead of client-go's listener.
+type SocketOptions struct {
+	ReuseAddr *bool         `yaml:"reuse_addr,omitempty"` // SO_REUSEADDR (Go sets it on unix by default)
+	ReusePort bool          `yaml:"reuse_port,omitempty"` // SO_REUSEPORT (not on Windows)
+	KeepAlive time.Duration `yaml:"keepalive,omitempty"`  // TCP keepalive period of accepted connections (negative: off)
+}
+
+// defaultBackoffJitter spreads out retries of forwards that failed together
+const defaultBackoffJitter = 0.2
+
+// mergeBackoff fills fields unset in override from base
+func mergeBackoff(base BackoffConfig, override *BackoffConfig) BackoffConfig {
+	if override == nil {
+		return base
+	}
+	if override.Base != 0 {
+		base.Base = override.Base
+	}
+	if override.Multiplier != 0 {
+		base.Multiplier = override.Multiplier
+	}
+	if override.Max != 0 {
+		base.Max = override.Max
+	}
+	if override.Jitter != nil {
+		base.Jitter = override.Jitter
+	}
+	return base
+}
+
+// DiscoveryConfig configures forwards created from annotated Services
+type DiscoveryConfig struct {
+	Annotation string        `yaml:"annotation,omitempty"` // local port annotation (default: nanoporter.io/local-port)
+	Namespaces []string      `yaml:"namespaces,omitempty"` // namespaces to search (default: all)
+	Interval   time.Duration `yaml:"interval,omitempty"`   // how often Services are re-listed (default: 1m)
+}
+
+// defaultBindAddress is the loopback address forwards listen on unless a cluster sets bind_address
+const defaultBindAddress = "127.0.0.1"
+
 // ClusterConfig represents a Kubernetes cluster configuration
 type ClusterConfig struct {
-	Name       string          `yaml:"name"`
-	Kubeconfig string          `yaml:"kubeconfig"`
-	Context    string          `yaml:"context"`
-	Forwards   []ForwardConfig `yaml:"forwards"`
+	Name         string          `yaml:"name"`
+	Kubeconfig   string          `yaml:"kubeconfig"`
+	Context      string          `yaml:"context"`
+	Contexts     []string        `yaml:"contexts,omitempty"`        // several contexts (or globs), one logical cluster each
+	PortStep     int             `yaml:"local_port_step,omitempty"` // added to local ports for each further context
+	BindAddress  string          `yaml:"bind_address,omitempty"`    // loopback alias for this cluster's forwards
+	Backoff      *BackoffConfig  `yaml:"backoff,omitempty"`         // overrides fields of the global backoff
+	LoginCommand string          `yaml:"login_command,omitempty"`   // run when the cluster rejects our credentials
+	Teleport     *TeleportConfig `yaml:"teleport,omitempty"`
+	Engine       string          `yaml:"engine,omitempty"`  // "native" (default) or "kubectl" to shell out to kubectl port-forward
+	QPS          float32         `yaml:"qps,omitempty"`     // client-side request rate limit of the API client (default: 5, negative: none)
+	Burst        int             `yaml:"burst,omitempty"`   // requests allowed at once before qps applies (default: 10)
+	Timeout      time.Duration   `yaml:"timeout,omitempty"` // per API request, not port-forward streams (default: none)
+	Forwards     []ForwardConfig `yaml:"forwards"`
+}
+
+// TeleportConfig describes a Kubernetes cluster reached through Teleport
+type TeleportConfig struct {
+	Proxy       string `yaml:"proxy"`             // Teleport proxy address, e.g. teleport.example.com:443
+	Cluster     string `yaml:"cluster,omitempty"` // Teleport cluster name (default: proxy host)
+	KubeCluster string `yaml:"kube_cluster"`      // Kubernetes cluster name as listed by `tsh kube ls`
+}
+
+// LocalAddress returns the address this cluster's forwards listen on
+func (c ClusterConfig) LocalAddress() string {
+	if c.BindAddress != "" {
+		return c.BindAddress
+	}
+	return defaultBindAddress
 }
 
 // ForwardConfig represents a port-forward configuration
 type ForwardConfig struct {
-	Namespace  string          `yaml:"namespace"`
-	Service    string          `yaml:"service"`
-	Type       string          `yaml:"type"` // "service" or "pod"
-	LocalPort  int             `yaml:"local_port"`
-	RemotePort int             `yaml:"remote_port"`
-	DBBackup   *DBBackupConfig `yaml:"db_backup,omitempty"`
+	Name           string           `yaml:"name,omitempty"` // alias used in generated files (default: service)
+	Namespace      string           `yaml:"namespace"`
+	Service        string           `yaml:"service"`
+	Type           string           `yaml:"type"` // "service", "pod", "ssh", "docker" or "tcp"
+	LocalPort      int              `yaml:"local_port"`
+	RemotePort     int              `yaml:"remote_port"`
+	RemotePortName string           `yaml:"-"`                          // named container port given as remote_port, resolved on the pod
+	LocalPortRange string           `yaml:"local_port_range,omitempty"` // "from-to" local ports for `service: "*"`
+	DBBackup       *DBBackupConfig  `yaml:"db_backup,omitempty"`
+	Hooks          *HooksConfig     `yaml:"hooks,omitempty"`
+	Hostnames      []string         `yaml:"hostnames,omitempty"`       // added to the hosts file when hosts_file is set
+	Scheme         string           `yaml:"scheme,omitempty"`          // "http" or "https", used when opening the endpoint in a browser
+	SSH            *SSHConfig       `yaml:"ssh,omitempty"`             // SSH server for type "ssh"
+	Docker         *DockerConfig    `yaml:"docker,omitempty"`          // container selection for type "docker"
+	SocketOptions  *SocketOptions   `yaml:"socket_options,omitempty"`  // replaces the global socket_options
+	AllowedCIDRs   []string         `yaml:"allowed_cidrs,omitempty"`   // clients allowed besides loopback (default: all)
+	LocalTLS       *LocalTLSConfig  `yaml:"local_tls,omitempty"`       // serve the local port over TLS
+	Capture        *CaptureConfig   `yaml:"capture,omitempty"`         // traffic capture, toggled in the TUI
+	AccessLog      string           `yaml:"access_log,omitempty"`      // file each accepted connection is logged to
+	MaxConnections int              `yaml:"max_connections,omitempty"` // connections open at once, further ones are refused (0: no limit)
+	OnConflict     string           `yaml:"on_conflict,omitempty"`     // "fail" (default) or "reassign" when local_port is taken
+	FallbackPorts  string           `yaml:"fallback_ports,omitempty"`  // "from-to" local ports tried by on_conflict: reassign
+	StartOrder     int              `yaml:"start_order,omitempty"`     // startup phase, lower ones are established first (default: 0)
+	Companion      *CompanionConfig `yaml:"companion,omitempty"`       // process run while the forward is active
+	FanOut         bool             `yaml:"fan_out,omitempty"`         // a forward per pod of the service, on local_port + ordinal
+	Primary        string           `yaml:"primary,omitempty"`         // label selector (or preset) of the pod to prefer, followed on switchover
+	CNPG           *CNPGConfig      `yaml:"cnpg,omitempty"`            // CloudNativePG Cluster the service, port and backup credentials follow from
+	Bitnami        *BitnamiConfig   `yaml:"bitnami,omitempty"`         // Bitnami chart release the service, port and backup credentials follow from
+}
+
+// LocalTLSConfig makes the local listener of a forward terminate TLS, while the tunnel
+// carries plaintext to the target
+type LocalTLSConfig struct {
+	Cert string `yaml:"cert,omitempty"` // PEM certificate (chain)
+	Key  string `yaml:"key,omitempty"`  // PEM private key of the certificate
+	Auto bool   `yaml:"auto,omitempty"` // generate a self-signed certificate instead
+}
+
+// CaptureConfig sets where and for how long the traffic of a forward is captured
+type CaptureConfig struct {
+	Path      string        `yaml:"path,omitempty"`        // .pcap, or a hexdump log for other extensions (default: captures/<cluster>-<name>-<time>.pcap)
+	MaxSizeMB int           `yaml:"max_size_mb,omitempty"` // stop at this file size (default: 10)
+	Duration  time.Duration `yaml:"duration,omitempty"`    // stop after this long (default: 5m)
+	Start     bool          `yaml:"start,omitempty"`       // capture from startup instead of when toggled
+}
+
+// SSHConfig describes the SSH server an "ssh" forward tunnels through. The forward's
+// service is the host connected to from the SSH server (e.g. localhost or db.internal).
+type SSHConfig struct {
+	Host                  string `yaml:"host"`                       // host[:port] of the SSH server (default port: 22)
+	User                  string `yaml:"user,omitempty"`             // default: the current user
+	KeyFile               string `yaml:"key_file,omitempty"`         // private key; without one the SSH agent is used
+	KnownHostsFile        string `yaml:"known_hosts_file,omitempty"` // default: ~/.ssh/known_hosts
+	InsecureIgnoreHostKey bool   `yaml:"insecure_ignore_host_key,omitempty"`
+}
+
+// DockerConfig selects the container a "docker" forward connects to. Without labels the
+// container named like the forward's service is used.
+type DockerConfig struct {
+	Labels map[string]string `yaml:"labels,omitempty"` // e.g. com.docker.compose.service: db
+}
+
+// UnmarshalYAML decodes a forward, taking a remote_port that isn't a number as the name of
+// a container port
+func (f *ForwardConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain ForwardConfig
+	name := ""
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value := node.Content[i+1]
+			if node.Content[i].Value != "remote_port" || value.Kind != yaml.ScalarNode || value.Tag == "!!null" {
+				continue
+			}
+			port := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value.Value, Line: value.Line, Column: value.Column}
+			if _, err := strconv.Atoi(value.Value); err != nil {
+				name = value.Value
+				port.Value = "0"
+			}
+			// Decode the rest of the forward with the port as a number ("5432" is taken too)
+			content := slices.Clone(node.Content)
+			content[i+1] = port
+			copied := *node
+			copied.Content = content
+			node = &copied
+			break
+		}
+	}
+	if err := node.Decode((*plain)(f)); err != nil {
+		return err
+	}
+	f.RemotePortName = name
+	return nil
+}
+
+// MarshalYAML encodes a forward, writing a named container port as its remote_port
+func (f ForwardConfig) MarshalYAML() (any, error) {
+	type plain ForwardConfig
+	var node yaml.Node
+	if err := node.Encode(plain(f)); err != nil {
+		return nil, err
+	}
+	if f.RemotePortName != "" {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "remote_port" {
+				node.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.RemotePortName}
+			}
+		}
+	}
+	return &node, nil
+}
+
+// RemotePortLabel returns the remote port as configured: its container port name or number
+func (f ForwardConfig) RemotePortLabel() string {
+	if f.RemotePortName != "" {
+		return f.RemotePortName
+	}
+	return strconv.Itoa(f.RemotePort)
+}
+
+// IsKubernetes reports whether the forward targets a Kubernetes service or pod
+func (f ForwardConfig) IsKubernetes() bool {
+	return f.Type == "service" || f.Type == "pod"
+}
+
+// usesKubernetes reports whether any forward of the cluster needs a Kubernetes client
+func (c ClusterConfig) usesKubernetes() bool {
+	for _, forward := range c.Forwards {
+		if forward.IsKubernetes() {
+			return true
+		}
+	}
+	return false
+}
+
+// HooksConfig contains shell commands run on port-forward lifecycle events
+type HooksConfig struct {
+	PostStart string        `yaml:"post_start,omitempty"` // after the forward becomes active
+	PreStop   string        `yaml:"pre_stop,omitempty"`   // before the forward is stopped
+	OnFailure string        `yaml:"on_failure,omitempty"` // after each failed connection attempt
+	Timeout   time.Duration `yaml:"timeout,omitempty"`    // per-command timeout (default: 30s)
+}
+
+// CompanionConfig describes a process that lives as long as a forward, e.g. a database UI
+// for a database tunnel
+type CompanionConfig struct {
+	Command string `yaml:"command"`           // run through the shell with the hook environment
+	Restart string `yaml:"restart,omitempty"` // "no" (default), "on-failure" or "always"
+	Log     string `yaml:"log,omitempty"`     // file the output is appended to (default: discarded)
+}
+
+// BackupHooksConfig contains shell commands run around a database backup
+type BackupHooksConfig struct {
+	Pre         string        `yaml:"pre,omitempty"`          // before the dump; failing fails the backup
+	PostSuccess string        `yaml:"post_success,omitempty"` // after a successful dump
+	PostFailure string        `yaml:"post_failure,omitempty"` // after a failed dump or pre hook
+	Timeout     time.Duration `yaml:"timeout,omitempty"`      // per-command timeout (default: 30s)
+}
+
+// MSSQLConfig contains settings of SQL Server backups
+type MSSQLConfig struct {
+	ServerBackupDir string `yaml:"server_backup_dir,omitempty"` // where BACKUP DATABASE writes in the container (default: /var/opt/mssql/backup)
+	Container       string `yaml:"container,omitempty"`         // pod container the backup is read from (default: the pod's only or default container)
 }
 
 // DBBackupConfig contains database backup configuration
 type DBBackupConfig struct {
+	// Database engine: "postgres" (default) or "mssql"
+	Type  string       `yaml:"type,omitempty"`
+	MSSQL *MSSQLConfig `yaml:"mssql,omitempty"`
+
 	// Kubernetes secret-based credentials (preferred for production)
 	SecretName   string            `yaml:"secret_name,omitempty"`
 	FieldMapping map[string]string `yaml:"field_mapping,omitempty"` // maps config field names to secret keys
 
-	// Direct credentials (useful for development or when secrets aren't available)
+	// Secret of a database operator, named after the operator's cluster and the username:
+	// "zalando" for the Zalando postgres-operator
+	SecretPreset string `yaml:"secret_preset,omitempty"`
+	Cluster      string `yaml:"cluster,omitempty"` // cluster (resource) name for secret_preset
+
+	// HashiCorp Vault credentials; field_mapping maps to the secret's keys (default: the field names)
+	Vault *VaultConfig `yaml:"vault,omitempty"`
+
+	// Direct credentials (useful for development or when secrets aren't available). They,
+	// and the Vault token, role_id and secret_id, may reference ${VAR} from the environment
+	// or the dotenv file, or be a keyring:service/account reference to the OS keyring.
 	Database string `yaml:"database,omitempty"`
 	Username string `yaml:"username,omitempty"`
 	Password string `yaml:"password,omitempty"`
+	Dotenv   string `yaml:"dotenv,omitempty"`
+
+	// libpq connection options for the dumps, e.g. sslmode: require
+	ConnOptions map[string]string `yaml:"conn_options,omitempty"`
+
+	// Dump through a temporary port-forward on a random local port instead of the forward itself
+	DedicatedForward bool `yaml:"dedicated_forward,omitempty"`
+
+	// What to dump (pg_dump -s, -t and -T); table names may be patterns like "public.ref_*"
+	SchemaOnly    bool     `yaml:"schema_only,omitempty"`
+	Tables        []string `yaml:"tables,omitempty"`
+	ExcludeTables []string `yaml:"exclude_tables,omitempty"`
+
+	// How dumps are stored. backup_dir may use {cluster}, {namespace}, {service} and {name}
+	// (default: <backup directory>/<service>).
+	BackupDir     string `yaml:"backup_dir,omitempty"`
+	MaxAge        string `yaml:"max_age,omitempty"`        // remove dumps older than this, e.g. "14d" or "36h"
+	SkipUnchanged bool   `yaml:"skip_unchanged,omitempty"` // keep only the previous dump when the new one is identical
+	Globals       bool   `yaml:"globals,omitempty"`        // also dump roles and tablespaces with pg_dumpall --globals-only
+	KeepPlain     bool   `yaml:"keep_plain,omitempty"`     // also keep the uncompressed .sql dump
+
+	Hooks *BackupHooksConfig `yaml:"hooks,omitempty"`
+}
+
+// VaultConfig selects a Vault secret holding database credentials
+type VaultConfig struct {
+	Address   string `yaml:"address,omitempty"`   // default: $VAULT_ADDR
+	Namespace string `yaml:"namespace,omitempty"` // Vault Enterprise namespace
+	Path      string `yaml:"path"`                // e.g. secret/data/prod/app-db or database/creds/app-readonly
+	Auth      string `yaml:"auth,omitempty"`      // "token" (default), "approle" or "kubernetes"
+	Mount     string `yaml:"mount,omitempty"`     // auth method mount path (default: the method name)
+
+	Token    string `yaml:"token,omitempty"`     // token auth (default: $VAULT_TOKEN, then ~/.vault-token)
+	RoleID   string `yaml:"role_id,omitempty"`   // approle auth
+	SecretID string `yaml:"secret_id,omitempty"` // approle auth (default: $VAULT_SECRET_ID)
+	Role     string `yaml:"role,omitempty"`      // kubernetes auth
+	JWTFile  string `yaml:"jwt_file,omitempty"`  // kubernetes auth (default: the pod's service account token)
 }
 
 // LoadConfig loads and validates the configuration from a YAML file
@@ -57,6 +420,11 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
+	return prepareConfig(&config)
+}
+
+// prepareConfig fills in the defaults of a parsed or assembled config and validates it
+func prepareConfig(config *Config) (*Config, error) {
 	// Set defaults
 	if config.CheckInterval == 0 {
 		config.CheckInterval = 10 * time.Second
@@ -65,12 +433,85 @@ func LoadConfig(path string) (*Config, error) {
 		config.ReconnectDelay = 5 * time.Second
 	}
 
+	// Resolve the backoff, first globally, then for every cluster
+	jitter := defaultBackoffJitter
+	backoff := mergeBackoff(BackoffConfig{
+		Base:       config.ReconnectDelay,
+		Multiplier: 2,
+		Max:        60 * time.Second,
+		Jitter:     &jitter,
+	}, config.Backoff)
+	config.Backoff = &backoff
+
+	if config.StartupConcurrency == 0 {
+		config.StartupConcurrency = defaultStartupConcurrency
+	}
+	if config.ReconnectRate == 0 {
+		config.ReconnectRate = defaultReconnectRate
+	}
+	if config.ReconnectBurst == 0 {
+		config.ReconnectBurst = defaultReconnectBurst
+	}
+	if config.KillTimeout == 0 {
+		config.KillTimeout = 5 * time.Second
+	}
+	if config.ControlSocket == "" {
+		config.ControlSocket = defaultControlSocketPath()
+	}
+	if config.StateFile == "" {
+		config.StateFile = defaultStateFile
+	}
+
+	clusters, err := expandClusterContexts(config.Clusters)
+	if err != nil {
+		return nil, err
+	}
+	config.Clusters = clusters
+
+	for i := range config.Clusters {
+		applyTeleportDefaults(&config.Clusters[i])
+
+		clusterBackoff := mergeBackoff(*config.Backoff, config.Clusters[i].Backoff)
+		config.Clusters[i].Backoff = &clusterBackoff
+
+		for j := range config.Clusters[i].Forwards {
+			applyCNPGDefaults(&config.Clusters[i].Forwards[j])
+			applyBitnamiDefaults(&config.Clusters[i].Forwards[j])
+			applySecretPreset(config.Clusters[i].Forwards[j].DBBackup)
+		}
+	}
+	if config.HostsFile != nil && config.HostsFile.Path == "" {
+		config.HostsFile.Path = defaultHostsFilePath()
+	}
+	if config.DNS != nil {
+		if config.DNS.Listen == "" {
+			config.DNS.Listen = "127.0.0.1:5353"
+		}
+		if config.DNS.Domain == "" {
+			config.DNS.Domain = "cluster.local"
+		}
+	}
+	if config.API != nil && config.API.Listen == "" {
+		config.API.Listen = defaultAPIListen
+	}
+	if config.Discovery != nil {
+		if config.Discovery.Annotation == "" {
+			config.Discovery.Annotation = defaultDiscoveryAnnotation
+		}
+		if config.Discovery.Interval == 0 {
+			config.Discovery.Interval = time.Minute
+		}
+	}
+	if config.EnvFile != nil && config.EnvFile.Template == "" {
+		config.EnvFile.Template = defaultEnvFileTemplate
+	}
+
 	// Validate configuration
-	if err := validateConfig(&config); err != nil {
+	if err := validateConfig(config); err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	return config, nil
 }
 
 // validateConfig performs comprehensive validation of the configuration
@@ -79,8 +520,65 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("no clusters configured")
 	}
 
+	if config.EnvFile != nil {
+		if config.EnvFile.Path == "" {
+			return fmt.Errorf("env_file has no path")
+		}
+		if _, err := template.New("env_file").Parse(config.EnvFile.Template); err != nil {
+			return fmt.Errorf("invalid env_file template: %w", err)
+		}
+	}
+
+	if (len(config.ControlUsers) > 0 || len(config.ControlGroups) > 0) && runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return fmt.Errorf("control_users and control_groups need peer credentials, which are only available on Linux and macOS")
+	}
+
+	if config.API != nil {
+		if config.API.Token == "" {
+			return fmt.Errorf("api needs a token")
+		}
+		if _, _, err := net.SplitHostPort(config.API.Listen); err != nil {
+			return fmt.Errorf("invalid api.listen: %w", err)
+		}
+	}
+
+	if config.BackupPing != nil {
+		if err := validateBackupPing(config.BackupPing); err != nil {
+			return fmt.Errorf("invalid backup_ping: %w", err)
+		}
+	}
+
+	if err := validateBackoff(config.Backoff); err != nil {
+		return fmt.Errorf("invalid backoff: %w", err)
+	}
+	if config.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	if config.RetryWindow < 0 {
+		return fmt.Errorf("retry_window must not be negative")
+	}
+	if config.StartupConcurrency < 0 {
+		return fmt.Errorf("startup_concurrency must not be negative")
+	}
+	if config.StartDelay < 0 {
+		return fmt.Errorf("start_delay must not be negative")
+	}
+	if config.ReconnectRate < 0 || config.ReconnectBurst < 0 {
+		return fmt.Errorf("reconnect_rate and reconnect_burst must not be negative")
+	}
+
+	if err := validateSocketOptions(config.SocketOptions); err != nil {
+		return fmt.Errorf("invalid socket_options: %w", err)
+	}
+
+	if config.TUI != nil {
+		if err := validateTUI(config.TUI); err != nil {
+			return fmt.Errorf("invalid tui: %w", err)
+		}
+	}
+
 	clusterNames := make(map[string]bool)
-	localPorts := make(map[int]string)
+	localPorts := make(map[string]string) // address:port -> forward
 
 	for i, cluster := range config.Clusters {
 		// Validate cluster name uniqueness
@@ -92,29 +590,55 @@ func validateConfig(config *Config) error {
 		}
 		clusterNames[cluster.Name] = true
 
-		// Validate kubeconfig file exists
-		if cluster.Kubeconfig == "" {
-			return fmt.Errorf("cluster '%s' has no kubeconfig path", cluster.Name)
+		// The kubeconfig is checked when the cluster is loaded, so one broken cluster
+		// doesn't keep the others from starting
+
+		if err := validateBackoff(cluster.Backoff); err != nil {
+			return fmt.Errorf("cluster '%s' has invalid backoff: %w", cluster.Name, err)
 		}
-		if _, err := os.Stat(cluster.Kubeconfig); os.IsNotExist(err) {
-			return fmt.Errorf("kubeconfig file not found for cluster '%s': %s", cluster.Name, cluster.Kubeconfig)
+
+		if cluster.Burst < 0 || cluster.Timeout < 0 {
+			return fmt.Errorf("cluster '%s' has a negative burst or timeout", cluster.Name)
 		}
 
-		// Validate forwards
-		if len(cluster.Forwards) == 0 {
+		switch cluster.Engine {
+		case "", engineNative, engineKubectl:
+		default:
+			return fmt.Errorf("cluster '%s' has invalid engine '%s' (must be 'native' or 'kubectl')", cluster.Name, cluster.Engine)
+		}
+
+		// Validate Teleport settings
+		if cluster.Teleport != nil {
+			if cluster.Teleport.Proxy == "" {
+				return fmt.Errorf("cluster '%s' has teleport settings without a proxy", cluster.Name)
+			}
+			if cluster.Teleport.KubeCluster == "" {
+				return fmt.Errorf("cluster '%s' has teleport settings without a kube_cluster", cluster.Name)
+			}
+		}
+
+		// Validate bind address
+		if cluster.BindAddress != "" {
+			ip := net.ParseIP(cluster.BindAddress)
+			if ip == nil || ip.To4() == nil {
+				return fmt.Errorf("cluster '%s' has invalid bind_address '%s' (must be an IPv4 address)", cluster.Name, cluster.BindAddress)
+			}
+		}
+
+		// Validate forwards (with discovery enabled they may all come from annotations)
+		if len(cluster.Forwards) == 0 && config.Discovery == nil {
 			return fmt.Errorf("cluster '%s' has no port-forwards configured", cluster.Name)
 		}
 
 		forwardKeys := make(map[string]bool)
 		for _, forward := range cluster.Forwards {
-			// Validate namespace
-			if forward.Namespace == "" {
-				return fmt.Errorf("forward in cluster '%s' has no namespace", cluster.Name)
+			if err := validateForward(cluster.Name, forward); err != nil {
+				return err
 			}
 
-			// Validate service name
-			if forward.Service == "" {
-				return fmt.Errorf("forward in cluster '%s' has no service/pod name", cluster.Name)
+			// Wildcard forwards are expanded once the cluster is reachable
+			if forward.Service == wildcardService {
+				continue
 			}
 
 			// Check for duplicate service/namespace combination within cluster
@@ -125,30 +649,550 @@ func validateConfig(config *Config) error {
 			}
 			forwardKeys[forwardKey] = true
 
-			// Validate type
-			if forward.Type != "service" && forward.Type != "pod" {
-				return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid type '%s' (must be 'service' or 'pod')",
-					forward.Namespace, forward.Service, cluster.Name, forward.Type)
+			// Check for duplicate local ports on the same address
+			endpoint := net.JoinHostPort(cluster.LocalAddress(), strconv.Itoa(forward.LocalPort))
+			if existingForward, exists := localPorts[endpoint]; exists {
+				return fmt.Errorf("local port %d is used by both '%s' and '%s/%s/%s'",
+					forward.LocalPort, existingForward, cluster.Name, forward.Namespace, forward.Service)
+			}
+			localPorts[endpoint] = fmt.Sprintf("%s/%s/%s", cluster.Name, forward.Namespace, forward.Service)
+		}
+	}
+
+	return nil
+}
+
+// validateBackupPing checks the ping URLs. URLs with environment references are checked
+// when they're expanded.
+func validateBackupPing(ping *BackupPingConfig) error {
+	if ping.URL == "" {
+		return fmt.Errorf("no url")
+	}
+	for _, u := range []string{ping.URL, ping.FailURL} {
+		if u == "" || strings.Contains(u, "${") {
+			continue
+		}
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("'%s' is not an http(s) URL", u)
+		}
+	}
+	return nil
+}
+
+// validateBackoff checks a resolved backoff configuration
+func validateBackoff(backoff *BackoffConfig) error {
+	if backoff == nil {
+		return nil
+	}
+	if backoff.Base <= 0 {
+		return fmt.Errorf("base must be positive")
+	}
+	if backoff.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1")
+	}
+	if backoff.Max < backoff.Base {
+		return fmt.Errorf("max must not be less than base")
+	}
+	if backoff.Jitter != nil && (*backoff.Jitter < 0 || *backoff.Jitter > 1) {
+		return fmt.Errorf("jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// validateSocketOptions checks the listener options against what the platform supports
+func validateSocketOptions(options *SocketOptions) error {
+	if options == nil {
+		return nil
+	}
+	if options.ReusePort && runtime.GOOS == "windows" {
+		return fmt.Errorf("reuse_port is not supported on Windows")
+	}
+	return nil
+}
+
+// validateForward checks a single forward of a cluster
+func validateForward(clusterName string, forward ForwardConfig) error {
+	// Validate namespace
+	if forward.Namespace == "" && forward.IsKubernetes() {
+		return fmt.Errorf("forward in cluster '%s' has no namespace", clusterName)
+	}
+
+	if forward.CNPG != nil {
+		if err := validateCNPG(forward.CNPG); err != nil {
+			return fmt.Errorf("forward in namespace '%s' in cluster '%s' has invalid cnpg: %w", forward.Namespace, clusterName, err)
+		}
+		if forward.Type != "service" {
+			return fmt.Errorf("forward for CloudNativePG cluster '%s' in cluster '%s' must have type 'service'", forward.CNPG.Cluster, clusterName)
+		}
+	}
+
+	if forward.Bitnami != nil {
+		if forward.CNPG != nil {
+			return fmt.Errorf("forward in namespace '%s' in cluster '%s' can't combine cnpg with bitnami", forward.Namespace, clusterName)
+		}
+		if err := validateBitnami(forward); err != nil {
+			return fmt.Errorf("forward in namespace '%s' in cluster '%s' has invalid bitnami: %w", forward.Namespace, clusterName, err)
+		}
+		if forward.Type != "service" {
+			return fmt.Errorf("forward for Bitnami release '%s' in cluster '%s' must have type 'service'", forward.Bitnami.Release, clusterName)
+		}
+	}
+
+	// Validate service name
+	if forward.Service == "" {
+		if forward.Type == "ssh" {
+			return fmt.Errorf("ssh forward in cluster '%s' has no service (the host to connect to from the SSH server)", clusterName)
+		}
+		if forward.Type == "docker" {
+			return fmt.Errorf("docker forward in cluster '%s' has no service (the container name)", clusterName)
+		}
+		if forward.Type == "tcp" {
+			return fmt.Errorf("tcp forward in cluster '%s' has no service (the host to connect to)", clusterName)
+		}
+		return fmt.Errorf("forward in cluster '%s' has no service/pod name", clusterName)
+	}
+
+	if forward.Service == wildcardService {
+		if forward.FanOut {
+			return fmt.Errorf("wildcard forward in namespace '%s' in cluster '%s' can't use fan_out",
+				forward.Namespace, clusterName)
+		}
+		if forward.Type != "service" {
+			return fmt.Errorf("wildcard forward in namespace '%s' in cluster '%s' must have type 'service'",
+				forward.Namespace, clusterName)
+		}
+		if _, _, err := parsePortRange(forward.LocalPortRange); err != nil {
+			return fmt.Errorf("wildcard forward in namespace '%s' in cluster '%s' has invalid local_port_range '%s': %w",
+				forward.Namespace, clusterName, forward.LocalPortRange, err)
+		}
+		return nil
+	}
+
+	// Validate type
+	switch forward.Type {
+	case "service", "pod":
+	case "ssh":
+		if forward.SSH == nil || forward.SSH.Host == "" {
+			return fmt.Errorf("ssh forward for '%s' in cluster '%s' has no ssh.host", forward.Service, clusterName)
+		}
+	case "docker", "tcp":
+	default:
+		return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid type '%s' (must be 'service', 'pod', 'ssh', 'docker' or 'tcp')",
+			forward.Namespace, forward.Service, clusterName, forward.Type)
+	}
+
+	// Validate port ranges
+	if forward.LocalPort < 1 || forward.LocalPort > 65535 {
+		return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid local_port: %d (must be 1-65535)",
+			forward.Namespace, forward.Service, clusterName, forward.LocalPort)
+	}
+	if forward.RemotePortName != "" {
+		if !forward.IsKubernetes() {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid remote_port: %s (container port names need type service or pod)",
+				forward.Namespace, forward.Service, clusterName, forward.RemotePortName)
+		}
+	} else if forward.RemotePort < 1 || forward.RemotePort > 65535 {
+		return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid remote_port: %d (must be 1-65535)",
+			forward.Namespace, forward.Service, clusterName, forward.RemotePort)
+	}
+
+	// Validate hostnames
+	for _, hostname := range forward.Hostnames {
+		if hostname == "" || strings.ContainsAny(hostname, " \t#") {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid hostname '%s'",
+				forward.Namespace, forward.Service, clusterName, hostname)
+		}
+	}
+
+	if forward.Scheme != "" && forward.Scheme != "http" && forward.Scheme != "https" {
+		return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid scheme '%s' (must be 'http' or 'https')",
+			forward.Namespace, forward.Service, clusterName, forward.Scheme)
+	}
+
+	if err := validateSocketOptions(forward.SocketOptions); err != nil {
+		return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid socket_options: %w",
+			forward.Namespace, forward.Service, clusterName, err)
+	}
+
+	for _, cidr := range forward.AllowedCIDRs {
+		if _, err := parseAllowedCIDR(cidr); err != nil {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid allowed_cidrs entry '%s': %w",
+				forward.Namespace, forward.Service, clusterName, cidr, err)
+		}
+	}
+
+	if tlsConfig := forward.LocalTLS; tlsConfig != nil {
+		if tlsConfig.Auto && (tlsConfig.Cert != "" || tlsConfig.Key != "") {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has local_tls with both auto and cert/key",
+				forward.Namespace, forward.Service, clusterName)
+		}
+		if !tlsConfig.Auto && (tlsConfig.Cert == "" || tlsConfig.Key == "") {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has local_tls without cert and key (or auto: true)",
+				forward.Namespace, forward.Service, clusterName)
+		}
+	}
+
+	if forward.Capture != nil && (forward.Capture.MaxSizeMB < 0 || forward.Capture.Duration < 0) {
+		return fmt.Errorf("forward for '%s/%s' in cluster '%s' has a negative capture limit",
+			forward.Namespace, forward.Service, clusterName)
+	}
+
+	if forward.MaxConnections < 0 {
+		return fmt.Errorf("forward for '%s/%s' in cluster '%s' has negative max_connections",
+			forward.Namespace, forward.Service, clusterName)
+	}
+
+	switch forward.OnConflict {
+	case "", onConflictFail, onConflictReassign:
+	default:
+		return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid on_conflict '%s' (must be 'fail' or 'reassign')",
+			forward.Namespace, forward.Service, clusterName, forward.OnConflict)
+	}
+	if forward.FallbackPorts != "" {
+		if forward.OnConflict != onConflictReassign {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has fallback_ports without on_conflict: reassign",
+				forward.Namespace, forward.Service, clusterName)
+		}
+		if _, _, err := parsePortRange(forward.FallbackPorts); err != nil {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid fallback_ports '%s': %w",
+				forward.Namespace, forward.Service, clusterName, forward.FallbackPorts, err)
+		}
+	}
+
+	if forward.FanOut {
+		switch {
+		case forward.Type != "service":
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has fan_out without type 'service'",
+				forward.Namespace, forward.Service, clusterName)
+		case forward.DBBackup != nil:
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' can't combine fan_out with db_backup",
+				forward.Namespace, forward.Service, clusterName)
+		case forward.OnConflict == onConflictReassign:
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' can't combine fan_out with on_conflict: reassign",
+				forward.Namespace, forward.Service, clusterName)
+		case forward.Primary != "":
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' can't combine fan_out with primary",
+				forward.Namespace, forward.Service, clusterName)
+		}
+	}
+
+	if forward.Primary != "" {
+		if forward.Type != "service" {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has primary without type 'service'",
+				forward.Namespace, forward.Service, clusterName)
+		}
+		if _, err := primarySelector(forward.Primary); err != nil {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid primary '%s': %w",
+				forward.Namespace, forward.Service, clusterName, forward.Primary, err)
+		}
+	}
+
+	if companion := forward.Companion; companion != nil {
+		if strings.TrimSpace(companion.Command) == "" {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has a companion without command",
+				forward.Namespace, forward.Service, clusterName)
+		}
+		switch companion.Restart {
+		case "", companionRestartNo, companionRestartOnFailure, companionRestartAlways:
+		default:
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid companion.restart '%s' (must be 'no', 'on-failure' or 'always')",
+				forward.Namespace, forward.Service, clusterName, companion.Restart)
+		}
+	}
+
+	// Validate the operator secret preset
+	if forward.DBBackup != nil && forward.DBBackup.SecretPreset != "" {
+		if err := validateSecretPreset(forward.DBBackup); err != nil {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid db_backup.secret_preset: %w",
+				forward.Namespace, forward.Service, clusterName, err)
+		}
+	}
+
+	// Validate Vault credentials
+	if forward.DBBackup != nil && forward.DBBackup.Vault != nil {
+		if err := validateVault(forward.DBBackup.Vault); err != nil {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid db_backup.vault: %w",
+				forward.Namespace, forward.Service, clusterName, err)
+		}
+	}
+
+	// Validate backup connection options
+	if forward.DBBackup != nil {
+		for key := range forward.DBBackup.ConnOptions {
+			if reservedConnOptions[key] {
+				return fmt.Errorf("forward for '%s/%s' in cluster '%s' sets '%s' in db_backup.conn_options, which nanoporter sets itself",
+					forward.Namespace, forward.Service, clusterName, key)
+			}
+			if key == "" || strings.ContainsAny(key, " ='") {
+				return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid db_backup.conn_options key '%s'",
+					forward.Namespace, forward.Service, clusterName, key)
 			}
+		}
+	}
+
+	// Validate the backup engine
+	if forward.DBBackup != nil {
+		if err := validateBackupType(forward); err != nil {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid db_backup: %w",
+				forward.Namespace, forward.Service, clusterName, err)
+		}
+	}
+
+	// Validate backup retention
+	if forward.DBBackup != nil && forward.DBBackup.MaxAge != "" {
+		if _, err := parseAge(forward.DBBackup.MaxAge); err != nil {
+			return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid db_backup.max_age '%s': %w",
+				forward.Namespace, forward.Service, clusterName, forward.DBBackup.MaxAge, err)
+		}
+	}
 
-			// Validate port ranges
-			if forward.LocalPort < 1 || forward.LocalPort > 65535 {
-				return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid local_port: %d (must be 1-65535)",
-					forward.Namespace, forward.Service, cluster.Name, forward.LocalPort)
+	return nil
+}
+
+// validateBackupType checks that the backup options fit the database engine
+func validateBackupType(forward ForwardConfig) error {
+	backup := forward.DBBackup
+	switch backup.Type {
+	case "", "postgres":
+		if backup.MSSQL != nil {
+			return fmt.Errorf("mssql is set but type isn't 'mssql'")
+		}
+	case "mssql":
+		// The backup file is written inside the container and read back with exec
+		if !forward.IsKubernetes() && forward.Type != "docker" {
+			return fmt.Errorf("mssql backups need a Kubernetes or docker forward")
+		}
+		switch {
+		case backup.Globals, backup.SchemaOnly, backup.SkipUnchanged:
+			return fmt.Errorf("globals, schema_only and skip_unchanged aren't supported for mssql")
+		case len(backup.Tables) > 0, len(backup.ExcludeTables) > 0, len(backup.ConnOptions) > 0:
+			return fmt.Errorf("tables, exclude_tables and conn_options aren't supported for mssql")
+		}
+	default:
+		return fmt.Errorf("unknown type '%s' (must be 'postgres' or 'mssql')", backup.Type)
+	}
+	return nil
+}
+
+// validateTUI checks the configured columns, keymap and display mode
+func validateTUI(tui *TUIConfig) error {
+	seen := make(map[string]bool)
+	for _, name := range tui.Columns {
+		if _, ok := tuiColumnWidths[name]; !ok {
+			return fmt.Errorf("unknown column '%s' (must be one of %s)", name, strings.Join(tuiColumnNames, ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("column '%s' is listed twice", name)
+		}
+		seen[name] = true
+	}
+	for name, width := range tui.ColumnWidths {
+		if _, ok := tuiColumnWidths[name]; !ok {
+			return fmt.Errorf("column_widths has unknown column '%s'", name)
+		}
+		if width < 4 {
+			return fmt.Errorf("column_widths.%s must be at least 4", name)
+		}
+	}
+	switch tui.Mode {
+	case "", modeAuto, modeWide, modeCompact:
+	default:
+		return fmt.Errorf("invalid mode '%s' (must be '%s', '%s' or '%s')", tui.Mode, modeAuto, modeWide, modeCompact)
+	}
+	if _, err := newKeymap(tui.Keymap); err != nil {
+		return fmt.Errorf("invalid keymap: %w", err)
+	}
+	return nil
+}
+
+// validateVault checks that the settings of the selected auth method are present
+func validateVault(vault *VaultConfig) error {
+	if vault.Path == "" {
+		return fmt.Errorf("no path")
+	}
+
+	switch vault.Auth {
+	case "", "token":
+	case "approle":
+		if vault.RoleID == "" {
+			return fmt.Errorf("approle auth needs role_id")
+		}
+	case "kubernetes":
+		if vault.Role == "" {
+			return fmt.Errorf("kubernetes auth needs role")
+		}
+	default:
+		return fmt.Errorf("unknown auth '%s' (must be 'token', 'approle' or 'kubernetes')", vault.Auth)
+	}
+	return nil
+}
+
+// reservedConnOptions are connection options that would bypass the forward or the
+// configured credentials
+var reservedConnOptions = map[string]bool{
+	"host": true, "hostaddr": true, "port": true, "dbname": true, "user": true, "password": true, "service": true,
+}
+
+// parseAge parses a positive age in days ("14d") or as a Go duration ("36h")
+func parseAge(s string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("expected a number of days like 14d")
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+
+	if age <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return age, nil
+}
+
+// expandClusterContexts turns every cluster listing several contexts into one logical
+// cluster per context, named "<name>-<context>" (or just the context when name is empty).
+// Glob patterns are matched against the kubeconfig's contexts in sorted order. Each further
+// context gets its local ports shifted by local_port_step so the forwards don't collide.
+// A pattern matching nothing, or an unreadable kubeconfig, leaves a cluster named after the
+// pattern, which starts unavailable like any cluster whose kubeconfig fails to load.
+func expandClusterContexts(clusters []ClusterConfig) ([]ClusterConfig, error) {
+	var expanded []ClusterConfig
+
+	for i, cluster := range clusters {
+		if len(cluster.Contexts) == 0 {
+			expanded = append(expanded, cluster)
+			continue
+		}
+		if cluster.Context != "" {
+			return nil, fmt.Errorf("cluster at index %d sets both context and contexts", i)
+		}
+
+		contexts, err := matchContexts(cluster)
+		if err != nil {
+			return nil, err
+		}
+
+		for n, kubeContext := range contexts {
+			logical := cluster
+			logical.Context = kubeContext
+			logical.Contexts = nil
+			logical.Name = kubeContext
+			if cluster.Name != "" {
+				logical.Name = cluster.Name + "-" + kubeContext
 			}
-			if forward.RemotePort < 1 || forward.RemotePort > 65535 {
-				return fmt.Errorf("forward for '%s/%s' in cluster '%s' has invalid remote_port: %d (must be 1-65535)",
-					forward.Namespace, forward.Service, cluster.Name, forward.RemotePort)
+
+			logical.Forwards = make([]ForwardConfig, len(cluster.Forwards))
+			for j, forward := range cluster.Forwards {
+				forward.LocalPort += n * cluster.PortStep
+				logical.Forwards[j] = forward
 			}
 
-			// Check for duplicate local ports
-			if existingForward, exists := localPorts[forward.LocalPort]; exists {
-				return fmt.Errorf("local port %d is used by both '%s' and '%s/%s/%s'",
-					forward.LocalPort, existingForward, cluster.Name, forward.Namespace, forward.Service)
+			expanded = append(expanded, logical)
+		}
+	}
+
+	return expanded, nil
+}
+
+// matchContexts resolves a cluster's contexts list against its kubeconfig, expanding globs.
+// Patterns that can't be resolved are kept as they are.
+func matchContexts(cluster ClusterConfig) ([]string, error) {
+	for _, pattern := range cluster.Contexts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid context pattern '%s' for cluster '%s': %w", pattern, cluster.Name, err)
+		}
+	}
+
+	rawConfig, err := kubeconfigLoadingRules(cluster.Kubeconfig).Load()
+	if err != nil {
+		return cluster.Contexts, nil
+	}
+
+	available := make([]string, 0, len(rawConfig.Contexts))
+	for name := range rawConfig.Contexts {
+		available = append(available, name)
+	}
+	sort.Strings(available)
+
+	seen := make(map[string]bool)
+	var contexts []string
+
+	for _, pattern := range cluster.Contexts {
+		matched := false
+		for _, name := range available {
+			if ok, _ := path.Match(pattern, name); ok {
+				matched = true
+				if !seen[name] {
+					seen[name] = true
+					contexts = append(contexts, name)
+				}
 			}
-			localPorts[forward.LocalPort] = fmt.Sprintf("%s/%s/%s", cluster.Name, forward.Namespace, forward.Service)
+		}
+		if !matched && !seen[pattern] {
+			seen[pattern] = true
+			contexts = append(contexts, pattern)
 		}
 	}
 
+	return contexts, nil
+}
+
+// validateKubeContext checks that the cluster's kubeconfig exists and contains its context.
+// Without an explicit kubeconfig the default discovery ($KUBECONFIG, ~/.kube/config) is used.
+func validateKubeContext(cluster ClusterConfig) error {
+	if cluster.Kubeconfig != "" {
+		if _, err := os.Stat(cluster.Kubeconfig); os.IsNotExist(err) {
+			return fmt.Errorf("kubeconfig file not found for cluster '%s': %s", cluster.Name, cluster.Kubeconfig)
+		}
+	}
+
+	rawConfig, err := kubeconfigLoadingRules(cluster.Kubeconfig).Load()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig for cluster '%s': %w", cluster.Name, err)
+	}
+
+	if cluster.Context == "" {
+		if rawConfig.CurrentContext == "" {
+			return fmt.Errorf("cluster '%s' has no context and the kubeconfig has no current-context", cluster.Name)
+		}
+		return nil
+	}
+
+	if _, ok := rawConfig.Contexts[cluster.Context]; !ok {
+		return fmt.Errorf("context '%s' for cluster '%s' not found in kubeconfig", cluster.Context, cluster.Name)
+	}
+
 	return nil
 }
+
+// applyTeleportDefaults fills in the context and login command of a Teleport cluster.
+// `tsh kube login` names contexts "<teleport cluster>-<kube cluster>" and writes them to
+// $KUBECONFIG or ~/.kube/config, which the default kubeconfig discovery already covers.
+func applyTeleportDefaults(cluster *ClusterConfig) {
+	tp := cluster.Teleport
+	if tp == nil {
+		return
+	}
+
+	teleportCluster := tp.Cluster
+	if teleportCluster == "" {
+		teleportCluster = tp.Proxy
+		if host, _, err := net.SplitHostPort(tp.Proxy); err == nil {
+			teleportCluster = host
+		}
+	}
+
+	if cluster.Context == "" {
+		cluster.Context = teleportCluster + "-" + tp.KubeCluster
+	}
+	if cluster.LoginCommand == "" {
+		cluster.LoginCommand = fmt.Sprintf("tsh kube login --proxy=%s %s", tp.Proxy, tp.KubeCluster)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// appendForwardToConfig adds a forward to a cluster in the config file. The file is edited
+// as a YAML node tree so comments and the order of existing entries are kept.
+func appendForwardToConfig(path, clusterName string, forward ForwardConfig) error {
+	return editConfigFile(path, func(root *yaml.Node) error {
+		cluster, err := findClusterNode(root, clusterName)
+		if err != nil {
+			return err
+		}
+
+		forwards := mappingValue(cluster, "forwards")
+		if forwards == nil || forwards.Kind != yaml.SequenceNode {
+			forwards = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			setMappingValue(cluster, "forwards", forwards)
+		}
+
+		var node yaml.Node
+		if err := node.Encode(forward); err != nil {
+			return fmt.Errorf("failed to encode forward: %w", err)
+		}
+		forwards.Content = append(forwards.Content, &node)
+
+		return nil
+	})
+}
+
+// removeForwardFromConfig deletes a forward from a cluster in the config file
+func removeForwardFromConfig(path, clusterName, namespace, service string) error {
+	return editConfigFile(path, func(root *yaml.Node) error {
+		cluster, err := findClusterNode(root, clusterName)
+		if err != nil {
+			return err
+		}
+
+		forwards := mappingValue(cluster, "forwards")
+		if forwards != nil && forwards.Kind == yaml.SequenceNode {
+			for i, forward := range forwards.Content {
+				ns := mappingValue(forward, "namespace")
+				svc := mappingValue(forward, "service")
+				if ns != nil && svc != nil && ns.Value == namespace && svc.Value == service {
+					forwards.Content = append(forwards.Content[:i], forwards.Content[i+1:]...)
+					return nil
+				}
+			}
+		}
+
+		return fmt.Errorf("no forward for %s/%s in cluster '%s' in config file", namespace, service, clusterName)
+	})
+}
+
+// editConfigFile parses the config file, applies edit to its root node and writes it back atomically
+func editConfigFile(path string, edit func(root *yaml.Node) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("config file is not a YAML mapping")
+	}
+
+	if err := edit(doc.Content[0]); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := writeFileAtomic(path, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// findClusterNode returns the mapping node of a cluster by name
+func findClusterNode(root *yaml.Node, name string) (*yaml.Node, error) {
+	clusters := mappingValue(root, "clusters")
+	if clusters == nil || clusters.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("config file has no clusters list")
+	}
+
+	for _, cluster := range clusters.Content {
+		if nameNode := mappingValue(cluster, "name"); nameNode != nil && nameNode.Value == name {
+			return cluster, nil
+		}
+	}
+
+	// Clusters expanded from a contexts list share the forwards of their entry, so editing
+	// them would change every context
+	for _, cluster := range clusters.Content {
+		if source, ok := expandedFrom(cluster, name); ok {
+			return nil, fmt.Errorf("cluster '%s' is expanded from the contexts of %s, whose forwards apply to all its contexts; edit them in the config file", name, source)
+		}
+	}
+
+	return nil, fmt.Errorf("cluster '%s' not found in config file", name)
+}
+
+// expandedFrom reports whether a cluster named name is expanded from the contexts list of a
+// cluster node, describing that entry for error messages
+func expandedFrom(cluster *yaml.Node, name string) (string, bool) {
+	contexts := mappingValue(cluster, "contexts")
+	if contexts == nil || contexts.Kind != yaml.SequenceNode {
+		return "", false
+	}
+
+	source := "an unnamed cluster"
+	kubeContext := name
+	if nameNode := mappingValue(cluster, "name"); nameNode != nil && nameNode.Value != "" {
+		source = fmt.Sprintf("cluster '%s'", nameNode.Value)
+		var ok bool
+		if kubeContext, ok = strings.CutPrefix(name, nameNode.Value+"-"); !ok {
+			return "", false
+		}
+	}
+
+	for _, pattern := range contexts.Content {
+		if ok, _ := path.Match(pattern.Value, kubeContext); ok || pattern.Value == kubeContext {
+			return source, true
+		}
+	}
+	return "", false
+}
+
+// mappingValue returns the value node of a key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets the value of a key in a mapping node, appending the key if missing
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+)
+
+// admit decides whether a connection accepted by a forward's listener is relayed: the client
+// has to be allowed, and the forward below its max_connections. Admitted connections are
+// counted until connectionDone.
+func (pf *PortForward) admit(conn net.Conn) bool {
+	if !pf.clientAllowed(conn) {
+		return false
+	}
+
+	pf.mu.Lock()
+	limit := pf.Config.MaxConnections
+	if limit > 0 && pf.openConns >= limit {
+		pf.refused++
+		refused := pf.refused
+		pf.mu.Unlock()
+
+		slog.Warn("Refused connection beyond max_connections",
+			"cluster", pf.ClusterName,
+			"namespace", pf.Config.Namespace,
+			"service", pf.Config.Service,
+			"client", conn.RemoteAddr().String(),
+			"max_connections", limit,
+			"refused", refused,
+		)
+		return false
+	}
+	pf.openConns++
+	pf.mu.Unlock()
+	return true
+}
+
+// connectionDone stops counting an admitted connection
+func (pf *PortForward) connectionDone() {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	pf.openConns--
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ControlRequest is a single command sent over the control socket
+type ControlRequest struct {
+	Command string          `json:"command"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// ControlResponse is the reply to a ControlRequest
+type ControlResponse struct {
+	OK    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// ControlHandler handles a single control command and returns data to send back
+type ControlHandler func(params json.RawMessage) (any, error)
+
+// unauditedCommands are the control commands that only read, so they're left out of the
+// audit log
+var unauditedCommands = map[string]bool{"status": true, "logs": true}
+
+// ControlServer exposes a unix socket that other nanoporter processes use to
+// talk to the running instance
+type ControlServer struct {
+	path     string
+	listener net.Listener
+	handlers map[string]ControlHandler
+	mu       sync.RWMutex
+
+	// Users and groups allowed besides the user running nanoporter, checked by peer
+	// credentials; without them, the socket is only accessible to its owner
+	allowedUIDs map[int]bool
+	allowedGIDs map[string]bool
+}
+
+// errControlNotAllowed is returned to clients that aren't allowed to use the control socket
+var errControlNotAllowed = errors.New("not allowed to control this instance")
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation
+const listenFDsStart = 3
+
+// defaultControlSocketPath returns the per-user control socket location
+func defaultControlSocketPath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, fmt.Sprintf("nanoporter-%d.sock", os.Getuid()))
+}
+
+// NewControlServer creates a new control server for the given socket path
+func NewControlServer(path string) *ControlServer {
+	return &ControlServer{
+		path:     path,
+		handlers: make(map[string]ControlHandler),
+	}
+}
+
+// Handle registers a handler for a command
+func (s *ControlServer) Handle(command string, handler ControlHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = handler
+}
+
+// AllowPeers lets users and members of groups, given by name, use the control socket
+// besides the user running nanoporter and root. Clients are checked by the socket's peer
+// credentials, and the socket itself becomes accessible to everyone.
+func (s *ControlServer) AllowPeers(users, groups []string) error {
+	if len(users) == 0 && len(groups) == 0 {
+		return nil
+	}
+	s.allowedUIDs = make(map[int]bool)
+	s.allowedGIDs = make(map[string]bool)
+	for _, name := range users {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return fmt.Errorf("unknown control user '%s': %w", name, err)
+		}
+		uid, err := strconv.Atoi(u.Uid)
+		if err != nil {
+			return fmt.Errorf("control user '%s' has no numeric uid", name)
+		}
+		s.allowedUIDs[uid] = true
+	}
+	for _, name := range groups {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return fmt.Errorf("unknown control group '%s': %w", name, err)
+		}
+		s.allowedGIDs[g.Gid] = true
+	}
+	return nil
+}
+
+// peerAllowed reports whether the client of a connection may send commands
+func (s *ControlServer) peerAllowed(uid int, known bool) bool {
+	if s.allowedUIDs == nil {
+		// Only the owner can connect at all
+		return true
+	}
+	if !known {
+		return false
+	}
+	if uid == os.Getuid() || uid == 0 || s.allowedUIDs[uid] {
+		return true
+	}
+	if len(s.allowedGIDs) == 0 {
+		return false
+	}
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return false
+	}
+	gids, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+	for _, gid := range gids {
+		if s.allowedGIDs[gid] {
+			return true
+		}
+	}
+	return false
+}
+
+// Start begins listening on the control socket, or takes over the socket passed by systemd
+// socket activation
+func (s *ControlServer) Start() error {
+	listener, err := activatedListener()
+	if err != nil {
+		return err
+	}
+	if listener != nil {
+		s.listener = listener
+		slog.Info("Control socket passed by systemd", "address", listener.Addr())
+		go s.acceptLoop()
+		return nil
+	}
+
+	// Remove a stale socket left behind by a crashed instance, but never
+	// steal the socket from an instance that is still answering
+	if _, err := os.Stat(s.path); err == nil {
+		if conn, err := net.DialTimeout("unix", s.path, time.Second); err == nil {
+			conn.Close()
+			return fmt.Errorf("control socket %s is in use by another instance", s.path)
+		}
+		if err := os.Remove(s.path); err != nil {
+			return fmt.Errorf("failed to remove stale control socket: %w", err)
+		}
+	}
+
+	listener, err = net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	mode := os.FileMode(0600)
+	if s.allowedUIDs != nil {
+		// Access is checked by peer credentials
+		mode = 0666
+	}
+	if err := os.Chmod(s.path, mode); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict control socket permissions: %w", err)
+	}
+	s.listener = listener
+
+	slog.Info("Control socket listening", "path", s.path)
+
+	go s.acceptLoop()
+	return nil
+}
+
+// activatedListener returns the control socket passed by systemd socket activation, nil
+// when nanoporter wasn't socket-activated
+func activatedListener() (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	if count, _ := strconv.Atoi(os.Getenv("LISTEN_FDS")); count < 1 {
+		return nil, nil
+	}
+	// Hooks and dumps started later must not take the socket for theirs
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	file := os.NewFile(listenFDsStart, "control-socket")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use the control socket passed by systemd: %w", err)
+	}
+	return listener, nil
+}
+
+// Stop closes the control socket
+func (s *ControlServer) Stop() {
+	if s.listener != nil {
+		s.listener.Close()
+	}
+}
+
+// acceptLoop accepts control connections until the listener is closed
+func (s *ControlServer) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Warn("Control socket accept failed", "error", err)
+			}
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn serves newline-delimited JSON requests on a single connection
+func (s *ControlServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	encoder := json.NewEncoder(conn)
+
+	uid, pid, known := peerCredentials(conn)
+	var peer []any
+	if known {
+		peer = describePeer(uid, pid)
+	}
+	allowed := s.peerAllowed(uid, known)
+
+	for scanner.Scan() {
+		var req ControlRequest
+		resp := ControlResponse{}
+
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else if !allowed {
+			slog.Warn("Refused control command", append([]any{"command", req.Command}, peer...)...)
+			audit(req.Command, auditSourceControl, errControlNotAllowed, peer...)
+			encoder.Encode(ControlResponse{Error: errControlNotAllowed.Error()})
+			return
+		} else {
+			resp = s.dispatch(req, peer)
+		}
+
+		if err := encoder.Encode(resp); err != nil {
+			slog.Debug("Failed to write control response", "error", err)
+			return
+		}
+	}
+}
+
+// dispatch runs the handler for a request and records it in the audit log, attributed to
+// the peer of its connection
+func (s *ControlServer) dispatch(req ControlRequest, peer []any) ControlResponse {
+	s.mu.RLock()
+	handler, ok := s.handlers[req.Command]
+	s.mu.RUnlock()
+
+	if !ok {
+		return ControlResponse{Error: fmt.Sprintf("unknown command: %s", req.Command)}
+	}
+
+	slog.Debug("Handling control command", "command", req.Command)
+
+	data, err := handler(req.Params)
+	if !unauditedCommands[req.Command] {
+		attrs := peer
+		if len(req.Params) > 0 {
+			attrs = append(attrs[:len(attrs):len(attrs)], "params", string(req.Params))
+		}
+		audit(req.Command, auditSourceControl, err, attrs...)
+	}
+	if err != nil {
+		return ControlResponse{Error: err.Error()}
+	}
+
+	resp := ControlResponse{OK: true}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return ControlResponse{Error: fmt.Sprintf("failed to encode response: %v", err)}
+		}
+		resp.Data = encoded
+	}
+	return resp
+}
+
+// ControlClient talks to a running instance over its control socket
+type ControlClient struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	encoder *json.Encoder
+}
+
+// DialControl connects to the control socket of a running instance
+func DialControl(path string) (*ControlClient, error) {
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to control socket %s: %w", path, err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	return &ControlClient{
+		conn:    conn,
+		scanner: scanner,
+		encoder: json.NewEncoder(conn),
+	}, nil
+}
+
+// Call sends a command and decodes the response data into result (if non-nil)
+func (c *ControlClient) Call(command string, params any, result any) error {
+	req := ControlRequest{Command: command}
+	if params != nil {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to encode params: %w", err)
+		}
+		req.Params = encoded
+	}
+
+	if err := c.encoder.Encode(req); err != nil {
+		return fmt.Errorf("failed to send control request: %w", err)
+	}
+
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read control response: %w", err)
+		}
+		return fmt.Errorf("control connection closed")
+	}
+
+	var resp ControlResponse
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		return fmt.Errorf("invalid control response: %w", err)
+	}
+	if !resp.OK {
+		return fmt.Errorf("%s", resp.Error)
+	}
+
+	if result != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, result); err != nil {
+			return fmt.Errorf("failed to decode response data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the control connection
+func (c *ControlClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// wildcardService is the service name that forwards every Service in a namespace
+const wildcardService = "*"
+
+// parsePortRange parses a "from-to" local port range
+func parsePortRange(s string) (int, int, error) {
+	fromStr, toStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected <from>-<to>")
+	}
+
+	from, err := strconv.Atoi(strings.TrimSpace(fromStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start port: %w", err)
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(toStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid end port: %w", err)
+	}
+
+	if from < 1 || to > 65535 || from > to {
+		return 0, 0, fmt.Errorf("ports must satisfy 1 <= from <= to <= 65535")
+	}
+
+	return from, to, nil
+}
+
+// portAllocator hands out local ports from a range, skipping ports already in use by the config
+type portAllocator struct {
+	address string
+	next    int
+	last    int
+	used    map[string]bool
+}
+
+// allocate returns the next free port of the range
+func (a *portAllocator) allocate() (int, error) {
+	for ; a.next <= a.last; a.next++ {
+		endpoint := net.JoinHostPort(a.address, strconv.Itoa(a.next))
+		if !a.used[endpoint] {
+			a.used[endpoint] = true
+			port := a.next
+			a.next++
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("local port range exhausted")
+}
+
+// usedLocalPorts collects the address:port endpoints of all explicitly configured forwards
+func usedLocalPorts(config *Config) map[string]bool {
+	used := make(map[string]bool)
+	for _, cluster := range config.Clusters {
+		for _, forward := range cluster.Forwards {
+			if forward.Service == wildcardService {
+				continue
+			}
+			used[net.JoinHostPort(cluster.LocalAddress(), strconv.Itoa(forward.LocalPort))] = true
+		}
+	}
+	return used
+}
+
+// expandWildcardForwards replaces `service: "*"` forwards with one forward per Service port
+// found in the namespace, assigning local ports from the forward's local_port_range
+func expandWildcardForwards(cluster *ClusterConfig, client kubernetes.Interface, used map[string]bool) error {
+	var forwards []ForwardConfig
+
+	for _, forward := range cluster.Forwards {
+		if forward.Service != wildcardService {
+			forwards = append(forwards, forward)
+			continue
+		}
+
+		discovered, err := discoverNamespaceServices(cluster, forward, client, used)
+		if err != nil {
+			return fmt.Errorf("failed to discover services in namespace %s: %w", forward.Namespace, err)
+		}
+
+		slog.Info("Discovered services for wildcard forward",
+			"cluster", cluster.Name,
+			"namespace", forward.Namespace,
+			"forwards", len(discovered),
+		)
+
+		forwards = append(forwards, discovered...)
+	}
+
+	cluster.Forwards = forwards
+	return nil
+}
+
+// dropWildcardForwards removes the wildcard forwards of a cluster and returns how many were removed
+func dropWildcardForwards(cluster *ClusterConfig) int {
+	var forwards []ForwardConfig
+	for _, forward := range cluster.Forwards {
+		if forward.Service != wildcardService {
+			forwards = append(forwards, forward)
+		}
+	}
+
+	dropped := len(cluster.Forwards) - len(forwards)
+	cluster.Forwards = forwards
+	return dropped
+}
+
+// discoverNamespaceServices lists the Services of a namespace and builds forwards for their ports
+func discoverNamespaceServices(cluster *ClusterConfig, wildcard ForwardConfig, client kubernetes.Interface, used map[string]bool) ([]ForwardConfig, error) {
+	from, to, err := parsePortRange(wildcard.LocalPortRange)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	services, err := client.CoreV1().Services(wildcard.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(services.Items, func(i, j int) bool {
+		return services.Items[i].Name < services.Items[j].Name
+	})
+
+	allocator := &portAllocator{address: cluster.LocalAddress(), next: from, last: to, used: used}
+	var forwards []ForwardConfig
+
+	for _, svc := range services.Items {
+		// Services without a selector often point outside the cluster; only explicit forwards use them
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+
+		for _, port := range svc.Spec.Ports {
+			if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+				continue
+			}
+
+			remotePort := servicePortTarget(port)
+			if remotePort == 0 {
+				slog.Debug("Skipping service port with named target port",
+					"cluster", cluster.Name,
+					"namespace", svc.Namespace,
+					"service", svc.Name,
+					"port", port.Name,
+				)
+				continue
+			}
+
+			localPort, err := allocator.allocate()
+			if err != nil {
+				return forwards, fmt.Errorf("no local port left for %s/%s in %s: %w",
+					svc.Namespace, svc.Name, wildcard.LocalPortRange, err)
+			}
+
+			name := svc.Name
+			if len(svc.Spec.Ports) > 1 {
+				name = fmt.Sprintf("%s-%d", svc.Name, port.Port)
+			}
+
+			forwards = append(forwards, ForwardConfig{
+				Name:       name,
+				Namespace:  wildcard.Namespace,
+				Service:    svc.Name,
+				Type:       "service",
+				LocalPort:  localPort,
+				RemotePort: remotePort,
+				Hooks:      wildcard.Hooks,
+			})
+		}
+	}
+
+	return forwards, nil
+}
+
+// servicePortTarget returns the pod port a Service port routes to, or 0 for named target ports
+func servicePortTarget(port corev1.ServicePort) int {
+	switch {
+	case port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal != 0:
+		return int(port.TargetPort.IntVal)
+	case port.TargetPort.Type == intstr.String && port.TargetPort.StrVal != "":
+		return 0
+	default:
+		// targetPort defaults to port
+		return int(port.Port)
+	}
+}
+
+const (
+	// defaultDiscoveryAnnotation marks Services to forward, its value is the local port
+	defaultDiscoveryAnnotation = "nanoporter.io/local-port"
+	// remotePortAnnotation optionally selects the remote port of an annotated Service
+	remotePortAnnotation = "nanoporter.io/remote-port"
+	// nameAnnotation optionally sets the forward's alias
+	nameAnnotation = "nanoporter.io/name"
+)
+
+// Discoverer creates and removes forwards for Services carrying the discovery annotation
+type Discoverer struct {
+	config     *Config
+	manager    *PortForwardManager
+	discovered map[string]*PortForward // cluster/namespace/service -> forward
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewDiscoverer creates a discoverer for the configured clusters
+func NewDiscoverer(config *Config, manager *PortForwardManager) *Discoverer {
+	return &Discoverer{
+		config:     config,
+		manager:    manager,
+		discovered: make(map[string]*PortForward),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start runs discovery right away and then on every interval
+func (d *Discoverer) Start() {
+	go func() {
+		defer close(d.done)
+
+		ticker := time.NewTicker(d.config.Discovery.Interval)
+		defer ticker.Stop()
+
+		for {
+			d.sync()
+
+			select {
+			case <-ticker.C:
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic discovery
+func (d *Discoverer) Stop() {
+	close(d.stop)
+	<-d.done
+}
+
+// sync lists annotated Services in every cluster and reconciles the discovered forwards
+func (d *Discoverer) sync() {
+	type discoveredForward struct {
+		cluster ClusterConfig
+		forward ForwardConfig
+	}
+	desired := make(map[string]discoveredForward)
+	listed := make(map[string]bool) // clusters whose Services could be listed
+
+	for _, cluster := range d.config.Clusters {
+		clusterClient := d.manager.ClusterClient(cluster.Name)
+		if clusterClient == nil || clusterClient.Err() != nil || d.manager.ClusterDisabled(cluster.Name) || d.manager.Paused() {
+			continue
+		}
+		_, client := clusterClient.Get()
+
+		forwards, err := d.discoverCluster(cluster, client)
+		if err != nil {
+			slog.Warn("Service discovery failed", "cluster", cluster.Name, "error", err)
+			continue
+		}
+		listed[cluster.Name] = true
+
+		for _, forward := range forwards {
+			desired[cluster.Name+"/"+forward.Namespace+"/"+forward.Service] = discoveredForward{cluster, forward}
+		}
+	}
+
+	// Remove forwards whose Service disappeared, lost its annotation or changed ports.
+	// Clusters that couldn't be listed keep their forwards.
+	for key, pf := range d.discovered {
+		want, ok := desired[key]
+		if !listed[pf.ClusterName] || (ok && want.forward.LocalPort == pf.Config.LocalPort && want.forward.RemotePort == pf.Config.RemotePort) {
+			continue
+		}
+		if err := d.manager.RemoveForward(pf.ID); err != nil {
+			slog.Warn("Failed to remove discovered forward", "forward", key, "error", err)
+		}
+		delete(d.discovered, key)
+	}
+
+	// Add new ones
+	for key, want := range desired {
+		if _, ok := d.discovered[key]; ok {
+			continue
+		}
+		if configuredForward(d.config, key) {
+			// Explicit config wins over annotations
+			continue
+		}
+
+		pf, err := d.manager.AddForward(want.cluster, want.forward)
+		if err != nil {
+			slog.Warn("Failed to add discovered forward", "forward", key, "error", err)
+			continue
+		}
+		d.discovered[key] = pf
+	}
+}
+
+// discoverCluster lists the annotated Services of a cluster
+func (d *Discoverer) discoverCluster(cluster ClusterConfig, client kubernetes.Interface) ([]ForwardConfig, error) {
+	namespaces := d.config.Discovery.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var forwards []ForwardConfig
+	for _, namespace := range namespaces {
+		services, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, svc := range services.Items {
+			forward, ok := annotatedForward(svc, d.config.Discovery.Annotation)
+			if !ok {
+				continue
+			}
+			forwards = append(forwards, forward)
+		}
+	}
+
+	return forwards, nil
+}
+
+// annotatedForward builds a forward from a Service's annotations
+func annotatedForward(svc corev1.Service, annotation string) (ForwardConfig, bool) {
+	value, ok := svc.Annotations[annotation]
+	if !ok {
+		return ForwardConfig{}, false
+	}
+
+	localPort, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || localPort < 1 || localPort > 65535 {
+		slog.Warn("Ignoring service with invalid local port annotation",
+			"namespace", svc.Namespace,
+			"service", svc.Name,
+			"value", value,
+		)
+		return ForwardConfig{}, false
+	}
+
+	var remotePort int
+	if value, ok := svc.Annotations[remotePortAnnotation]; ok {
+		remotePort, _ = strconv.Atoi(strings.TrimSpace(value))
+	} else if len(svc.Spec.Ports) > 0 {
+		remotePort = servicePortTarget(svc.Spec.Ports[0])
+	}
+	if remotePort < 1 || remotePort > 65535 {
+		slog.Warn("Ignoring annotated service without a usable remote port",
+			"namespace", svc.Namespace,
+			"service", svc.Name,
+		)
+		return ForwardConfig{}, false
+	}
+
+	return ForwardConfig{
+		Name:       svc.Annotations[nameAnnotation],
+		Namespace:  svc.Namespace,
+		Service:    svc.Name,
+		Type:       "service",
+		LocalPort:  localPort,
+		RemotePort: remotePort,
+	}, true
+}
+
+// configuredForward reports whether a cluster/namespace/service forward is in the config file
+func configuredForward(config *Config, key string) bool {
+	for _, cluster := range config.Clusters {
+		for _, forward := range cluster.Forwards {
+			if cluster.Name+"/"+forward.Namespace+"/"+forward.Service == key {
+				return true
+			}
+		}
+	}
+	return false
+}
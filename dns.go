@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsTTL is the TTL of answers; short so config changes take effect quickly
+const dnsTTL = 5
+
+// DNSServer answers queries for forwarded service names with the loopback
+// address the corresponding forward is bound to
+type DNSServer struct {
+	config  *DNSConfig
+	records map[string][4]byte // lower-case FQDN with trailing dot -> IPv4 address
+	conn    net.PacketConn
+}
+
+// NewDNSServer builds the record table from the configured forwards. Each forward
+// answers for <service>.<namespace>.svc.<domain>, <service>.<namespace>.svc,
+// <service>.<namespace> and its configured hostnames. The first forward wins
+// when several clusters share a service name.
+func NewDNSServer(config *Config) *DNSServer {
+	s := &DNSServer{
+		config:  config.DNS,
+		records: make(map[string][4]byte),
+	}
+
+	for _, cluster := range config.Clusters {
+		var loopback [4]byte
+		copy(loopback[:], net.ParseIP(cluster.LocalAddress()).To4())
+
+		for _, forward := range cluster.Forwards {
+			var names []string
+			if forward.IsKubernetes() {
+				base := forward.Service + "." + forward.Namespace
+				names = append(names, base+".svc."+config.DNS.Domain, base+".svc", base)
+			}
+			names = append(names, forward.Hostnames...)
+
+			for _, name := range names {
+				fqdn := strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+				if _, exists := s.records[fqdn]; exists {
+					slog.Debug("DNS name already registered, skipping", "name", fqdn, "cluster", cluster.Name)
+					continue
+				}
+				s.records[fqdn] = loopback
+			}
+		}
+	}
+
+	return s
+}
+
+// Start begins serving DNS over UDP
+func (s *DNSServer) Start() error {
+	conn, err := net.ListenPacket("udp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen for DNS on %s: %w", s.config.Listen, err)
+	}
+	s.conn = conn
+
+	slog.Info("DNS resolver listening", "address", s.config.Listen, "names", len(s.records))
+
+	go s.serve()
+	return nil
+}
+
+// Stop closes the DNS listener
+func (s *DNSServer) Stop() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// serve reads and answers queries until the listener is closed
+func (s *DNSServer) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Warn("DNS read failed", "error", err)
+			}
+			return
+		}
+
+		resp, err := s.answer(buf[:n])
+		if err != nil {
+			slog.Debug("Dropping invalid DNS query", "from", addr, "error", err)
+			continue
+		}
+
+		if _, err := s.conn.WriteTo(resp, addr); err != nil {
+			slog.Debug("DNS write failed", "to", addr, "error", err)
+		}
+	}
+}
+
+// answer builds the response to a single query
+func (s *DNSServer) answer(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, known := s.records[strings.ToLower(question.Name.String())]
+
+	rcode := dnsmessage.RCodeSuccess
+	if !known {
+		rcode = dnsmessage.RCodeNameError
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		Authoritative:      true,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: false,
+		RCode:              rcode,
+	})
+	builder.EnableCompression()
+
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+
+	// Known names only have A records; AAAA and other types get an empty NOERROR answer
+	if known && (question.Type == dnsmessage.TypeA || question.Type == dnsmessage.TypeALL) {
+		if err := builder.StartAnswers(); err != nil {
+			return nil, err
+		}
+		err := builder.AResource(dnsmessage.ResourceHeader{
+			Name:  question.Name,
+			Type:  dnsmessage.TypeA,
+			Class: dnsmessage.ClassINET,
+			TTL:   dnsTTL,
+		}, dnsmessage.AResource{A: addr})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Finish()
+}
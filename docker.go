@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// dockerCommandTimeout bounds a single docker CLI call
+	dockerCommandTimeout = 10 * time.Second
+	// dockerPollInterval is how often the container of an active docker forward is checked
+	dockerPollInterval = 5 * time.Second
+)
+
+// dockerContainer is a running container a docker forward connects to
+type dockerContainer struct {
+	ID      string
+	Name    string
+	Address string // host:port connections are relayed to
+}
+
+// establishDockerForward relays the forward's local port to a running container, through
+// the port published on the host when there is one and to the container's address otherwise
+func (m *PortForwardManager) establishDockerForward(pf *PortForward, release func()) error {
+	container, err := findContainer(pf.ctx, pf.Config)
+	if err != nil {
+		return err
+	}
+
+	if previous, switched := pf.setPod(container.Name, container.ID); switched {
+		slog.Info("Port-forward switched containers",
+			"cluster", pf.ClusterName,
+			"service", pf.Config.Service,
+			"previous_container", previous,
+			"container", container.Name,
+		)
+		m.emit(Event{Type: EventPodSwitched, Forward: pf.Status(), PreviousPod: previous})
+	}
+
+	// Reconnect when the container stops or is replaced (e.g. `docker compose up` recreated it)
+	stop := make(chan struct{})
+	defer close(stop)
+	broken := make(chan error, 1)
+	go watchContainer(container, stop, broken)
+
+	dial := func() (net.Conn, error) {
+		return net.DialTimeout("tcp", container.Address, dockerCommandTimeout)
+	}
+
+	return m.relayForward(pf, release, dial, broken)
+}
+
+// findContainer finds the running container a docker forward targets
+func findContainer(ctx context.Context, forward ForwardConfig) (*dockerContainer, error) {
+	args := []string{"ps", "--no-trunc", "--format", "{{.ID}}"}
+	if forward.Docker != nil && len(forward.Docker.Labels) > 0 {
+		keys := make([]string, 0, len(forward.Docker.Labels))
+		for key := range forward.Docker.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			args = append(args, "--filter", "label="+key+"="+forward.Docker.Labels[key])
+		}
+	} else {
+		// The name filter matches substrings, so anchor it (names are reported with a leading slash)
+		args = append(args, "--filter", "name=^/?"+forward.Service+"$")
+	}
+
+	output, err := dockerOutput(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	ids := strings.Fields(output)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no running container found for %s", forward.Service)
+	}
+
+	return inspectContainer(ctx, ids[0], forward.RemotePort)
+}
+
+// inspectContainer resolves the address a container's port is reachable at
+func inspectContainer(ctx context.Context, id string, port int) (*dockerContainer, error) {
+	output, err := dockerOutput(ctx, "inspect", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	var inspected []struct {
+		ID              string `json:"Id"`
+		Name            string `json:"Name"`
+		NetworkSettings struct {
+			Ports map[string][]struct {
+				HostIP   string `json:"HostIp"`
+				HostPort string `json:"HostPort"`
+			} `json:"Ports"`
+			Networks map[string]struct {
+				IPAddress string `json:"IPAddress"`
+			} `json:"Networks"`
+		} `json:"NetworkSettings"`
+	}
+	if err := json.Unmarshal([]byte(output), &inspected); err != nil || len(inspected) == 0 {
+		return nil, fmt.Errorf("failed to parse docker inspect output: %v", err)
+	}
+	info := inspected[0]
+
+	container := &dockerContainer{ID: info.ID, Name: strings.TrimPrefix(info.Name, "/")}
+
+	// Published ports work everywhere, including Docker Desktop where container
+	// addresses aren't reachable from the host
+	for _, binding := range info.NetworkSettings.Ports[fmt.Sprintf("%d/tcp", port)] {
+		if binding.HostPort == "" {
+			continue
+		}
+		host := binding.HostIP
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = "127.0.0.1"
+		}
+		container.Address = net.JoinHostPort(host, binding.HostPort)
+		return container, nil
+	}
+
+	networks := make([]string, 0, len(info.NetworkSettings.Networks))
+	for name := range info.NetworkSettings.Networks {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+	for _, name := range networks {
+		if ip := info.NetworkSettings.Networks[name].IPAddress; ip != "" {
+			container.Address = net.JoinHostPort(ip, strconv.Itoa(port))
+			return container, nil
+		}
+	}
+
+	// Host networking
+	container.Address = net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	return container, nil
+}
+
+// watchContainer reports on broken when the container stops running
+func watchContainer(container *dockerContainer, stop <-chan struct{}, broken chan<- error) {
+	ticker := time.NewTicker(dockerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			output, err := dockerOutput(context.Background(), "inspect", "--format", "{{.State.Running}}", container.ID)
+			if err != nil || strings.TrimSpace(output) != "true" {
+				broken <- fmt.Errorf("container %s is no longer running", container.Name)
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// dockerOutput runs the docker CLI and returns its output
+func dockerOutput(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, dockerCommandTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
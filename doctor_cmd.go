@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// doctorReport collects the results of the environment checks
+type doctorReport struct {
+	failures int
+	warnings int
+}
+
+// pass prints a passed check
+func (r *doctorReport) pass(format string, args ...any) {
+	fmt.Printf("  [PASS] %s\n", fmt.Sprintf(format, args...))
+}
+
+// warn prints a check that didn't pass but doesn't prevent nanoporter from working
+func (r *doctorReport) warn(format string, args ...any) {
+	r.warnings++
+	fmt.Printf("  [WARN] %s\n", fmt.Sprintf(format, args...))
+}
+
+// fail prints a failed check
+func (r *doctorReport) fail(format string, args ...any) {
+	r.failures++
+	fmt.Printf("  [FAIL] %s\n", fmt.Sprintf(format, args...))
+}
+
+// runDoctorCommand checks the environment for common problems and prints a report
+func runDoctorCommand() {
+	doctorFlags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := doctorFlags.String("config", defaultConfigPath, "Path to configuration file")
+	doctorFlags.Parse(os.Args[2:])
+
+	report := &doctorReport{}
+
+	fmt.Println("Configuration")
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		report.fail("%v", err)
+	} else {
+		report.pass("%s is valid (%d cluster(s))", *configPath, len(config.Clusters))
+	}
+
+	fmt.Println("\nTools")
+	checkTools(report, config)
+
+	if config != nil {
+		for _, cluster := range config.Clusters {
+			fmt.Printf("\nCluster %s\n", cluster.Name)
+			checkCluster(report, cluster)
+		}
+
+		fmt.Println("\nLocal ports")
+		checkLocalPorts(report, config)
+	}
+
+	fmt.Printf("\n%d failed, %d warnings\n", report.failures, report.warnings)
+	if report.failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkTools checks that external tools used by nanoporter are installed
+func checkTools(report *doctorReport, config *Config) {
+	hasBackups := false
+	hasGlobals := false
+	hasMSSQL := false
+	hasDocker := false
+	hasKubectl := false
+	if config != nil {
+		for _, cluster := range config.Clusters {
+			if cluster.Engine == engineKubectl {
+				hasKubectl = true
+			}
+			for _, forward := range cluster.Forwards {
+				if forward.DBBackup != nil && forward.DBBackup.Type == "mssql" {
+					hasMSSQL = true
+				} else if forward.DBBackup != nil {
+					hasBackups = true
+					hasGlobals = hasGlobals || forward.DBBackup.Globals
+				}
+				if forward.Type == "docker" {
+					hasDocker = true
+				}
+			}
+		}
+	}
+
+	// Docker forwards
+	if hasDocker {
+		if version, err := toolVersion("docker", "--version"); err != nil {
+			report.fail("docker not found (needed for docker forwards): %v", err)
+		} else {
+			report.pass("docker: %s", version)
+		}
+	}
+
+	// Clusters forwarding through kubectl
+	if hasKubectl {
+		if version, err := toolVersion("kubectl", "version", "--client"); err != nil {
+			report.fail("kubectl not found (needed for engine: kubectl): %v", err)
+		} else {
+			report.pass("kubectl: %s", version)
+		}
+	}
+
+	// Database backups
+	version, err := toolVersion("pg_dump", "--version")
+	switch {
+	case err == nil:
+		report.pass("pg_dump: %s", version)
+	case hasBackups:
+		report.fail("pg_dump not found (needed for db_backup): %v", err)
+	default:
+		report.warn("pg_dump not found (only needed for db_backup)")
+	}
+	if hasGlobals {
+		if version, err := toolVersion("pg_dumpall", "--version"); err != nil {
+			report.fail("pg_dumpall not found (needed for db_backup.globals): %v", err)
+		} else {
+			report.pass("pg_dumpall: %s", version)
+		}
+	}
+
+	if hasMSSQL {
+		if version, err := toolVersion("sqlcmd", "-?"); err != nil {
+			report.fail("sqlcmd not found (needed for db_backup type mssql): %v", err)
+		} else {
+			report.pass("sqlcmd: %s", version)
+		}
+	}
+
+	// Port owner lookup
+	if runtime.GOOS == "windows" {
+		if _, err := exec.LookPath("netstat"); err != nil {
+			report.warn("netstat not found (fallback for port conflict detection)")
+		} else {
+			report.pass("netstat available")
+		}
+		return
+	}
+
+	found := false
+	for _, tool := range []string{"lsof", "ss"} {
+		if _, err := exec.LookPath(tool); err == nil {
+			report.pass("%s available", tool)
+			found = true
+		}
+	}
+	if !found {
+		if runtime.GOOS == "linux" {
+			report.warn("neither lsof nor ss found (port conflicts are still detected through /proc)")
+		} else {
+			report.fail("neither lsof nor ss found (port conflicts with other processes can't be detected)")
+		}
+	}
+}
+
+// toolVersion returns the first line of a tool's version output
+func toolVersion(tool string, args ...string) (string, error) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := exec.Command(path, args...).CombinedOutput()
+	if err != nil {
+		return path, nil
+	}
+
+	line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return line, nil
+}
+
+// checkCluster checks that a cluster is reachable and the credentials have the permissions nanoporter needs
+func checkCluster(report *doctorReport, cluster ClusterConfig) {
+	if !cluster.usesKubernetes() {
+		report.pass("no Kubernetes forwards")
+		return
+	}
+
+	if err := validateKubeContext(cluster); err != nil {
+		report.fail("%v", err)
+		return
+	}
+
+	_, clientset, err := loadKubeconfig(cluster)
+	if err != nil {
+		report.fail("failed to load kubeconfig: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	version, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		report.fail("API server unreachable: %v", err)
+		return
+	}
+	report.pass("API server reachable (Kubernetes %s)", version.GitVersion)
+
+	// Permissions needed per namespace
+	namespaces := make(map[string]bool) // namespace -> needs secrets
+	needsExec := make(map[string]bool)  // namespace -> has SQL Server backups
+	for _, forward := range cluster.Forwards {
+		needsSecrets := forward.DBBackup != nil && forward.DBBackup.SecretName != ""
+		namespaces[forward.Namespace] = namespaces[forward.Namespace] || needsSecrets
+		if forward.DBBackup != nil && forward.DBBackup.Type == "mssql" && forward.IsKubernetes() {
+			needsExec[forward.Namespace] = true
+		}
+	}
+
+	names := make([]string, 0, len(namespaces))
+	for ns := range namespaces {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+
+	for _, ns := range names {
+		checks := []authorizationv1.ResourceAttributes{
+			{Namespace: ns, Verb: "get", Resource: "services"},
+			{Namespace: ns, Verb: "list", Resource: "pods"},
+			{Namespace: ns, Verb: "create", Resource: "pods", Subresource: "portforward"},
+		}
+		if namespaces[ns] {
+			checks = append(checks, authorizationv1.ResourceAttributes{Namespace: ns, Verb: "get", Resource: "secrets"})
+		}
+		if needsExec[ns] {
+			checks = append(checks, authorizationv1.ResourceAttributes{Namespace: ns, Verb: "create", Resource: "pods", Subresource: "exec"})
+		}
+
+		for _, attrs := range checks {
+			checkPermission(ctx, report, clientset, attrs, "")
+		}
+
+		// Without these pods and services aren't cached but looked up on every (re)connect
+		for _, attrs := range []authorizationv1.ResourceAttributes{
+			{Namespace: ns, Verb: "list", Resource: "services"},
+			{Namespace: ns, Verb: "watch", Resource: "services"},
+			{Namespace: ns, Verb: "watch", Resource: "pods"},
+		} {
+			checkPermission(ctx, report, clientset, attrs, "pods and services are looked up on every reconnect instead of cached")
+		}
+		checkPermission(ctx, report, clientset,
+			authorizationv1.ResourceAttributes{Namespace: ns, Verb: "list", Group: "discovery.k8s.io", Resource: "endpointslices"},
+			"services without a selector are resolved through their Endpoints instead")
+	}
+}
+
+// checkPermission asks the API server whether the current user may perform an action.
+// Without an optional reason a missing permission fails the check, with one it's a warning.
+func checkPermission(ctx context.Context, report *doctorReport, clientset *kubernetes.Clientset, attrs authorizationv1.ResourceAttributes, optional string) {
+	resource := attrs.Resource
+	if attrs.Subresource != "" {
+		resource += "/" + attrs.Subresource
+	}
+	action := fmt.Sprintf("%s %s in %s", attrs.Verb, resource, attrs.Namespace)
+
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attrs},
+	}
+	result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		report.warn("can't check permission to %s: %v", action, err)
+		return
+	}
+
+	switch {
+	case result.Status.Allowed:
+		report.pass("allowed to %s", action)
+	case optional != "":
+		report.warn("not allowed to %s (%s)", action, optional)
+	default:
+		report.fail("not allowed to %s", action)
+	}
+}
+
+// checkLocalPorts checks that configured local ports can be bound
+func checkLocalPorts(report *doctorReport, config *Config) {
+	for _, cluster := range config.Clusters {
+		for _, forward := range cluster.Forwards {
+			if forward.Service == wildcardService {
+				continue
+			}
+
+			address := net.JoinHostPort(cluster.LocalAddress(), strconv.Itoa(forward.LocalPort))
+			listener, err := net.Listen("tcp", address)
+			if err == nil {
+				listener.Close()
+				report.pass("%s is free (%s/%s/%s)", address, cluster.Name, forward.Namespace, forward.Service)
+				continue
+			}
+
+			holder := ""
+			if pid, name, err := findProcessUsingPort(cluster.LocalAddress(), forward.LocalPort); err == nil && pid != 0 {
+				holder = fmt.Sprintf(" by %s (PID %d)", name, pid)
+			}
+			if strings.Contains(holder, "nanoporter") {
+				report.warn("%s is in use%s, probably a running instance", address, holder)
+			} else {
+				report.fail("%s is in use%s (%s/%s/%s)", address, holder, cluster.Name, forward.Namespace, forward.Service)
+			}
+		}
+	}
+}
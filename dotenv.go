@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envReferencePattern matches ${VAR} references. Bare $VAR isn't expanded, since
+// passwords often contain '$'.
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnvReferences replaces ${VAR} references with values from the process environment,
+// falling back to the dotenv file when one is given
+func expandEnvReferences(s, dotenvPath string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var dotenv map[string]string
+	var expandErr error
+	expanded := envReferencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envReferencePattern.FindStringSubmatch(ref)[1]
+		if val, ok := os.LookupEnv(name); ok {
+			return val
+		}
+
+		if dotenvPath != "" && dotenv == nil {
+			var err error
+			if dotenv, err = readDotenv(dotenvPath); err != nil {
+				expandErr = err
+				return ref
+			}
+		}
+		if val, ok := dotenv[name]; ok {
+			return val
+		}
+
+		if expandErr == nil {
+			expandErr = fmt.Errorf("variable %s is not set", name)
+		}
+		return ref
+	})
+
+	return expanded, expandErr
+}
+
+// readDotenv reads KEY=VALUE lines of a dotenv file. Comments, blank lines and an
+// "export " prefix are allowed, and values may be quoted.
+func readDotenv(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dotenv file: %w", err)
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		} else if i := strings.Index(val, " #"); i >= 0 {
+			// Trailing comment of an unquoted value
+			val = strings.TrimSpace(val[:i])
+		}
+		values[key] = val
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dotenv file: %w", err)
+	}
+
+	return values, nil
+}
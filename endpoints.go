@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// endpointAddress is a ready address of a service, with the pod behind it when its
+// endpoint names one
+type endpointAddress struct {
+	ip  string
+	pod string
+}
+
+// endpointPods returns the pods behind the ready endpoints of a service without a selector,
+// whose endpoints are managed by hand or by an operator. Endpoints that don't name a pod
+// are matched to pods by IP; pods come from the namespace's cache when there is one.
+func endpointPods(pf *PortForward, nc *namespaceCache, svc *corev1.Service) ([]*corev1.Pod, error) {
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return nil, fmt.Errorf("service %s is an alias of %s without pods to forward to", svc.Name, svc.Spec.ExternalName)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, client := pf.cluster.Get()
+	addresses, err := readyEndpoints(ctx, client, svc.Namespace, svc.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("service %s has no selector and no ready endpoints", svc.Name)
+	}
+
+	var pods []*corev1.Pod
+	var unmatched []string
+	for _, address := range addresses {
+		var pod *corev1.Pod
+		if address.pod != "" {
+			pod, err = endpointPod(ctx, client, nc, svc.Namespace, address.pod)
+		} else {
+			pod, err = podByIP(ctx, client, nc, svc.Namespace, address.ip)
+		}
+		if err != nil {
+			slog.Debug("No pod for endpoint",
+				"cluster", pf.ClusterName,
+				"namespace", svc.Namespace,
+				"service", svc.Name,
+				"address", address.ip,
+				"error", err,
+			)
+		}
+		if pod == nil {
+			unmatched = append(unmatched, address.ip)
+			continue
+		}
+		if !slices.ContainsFunc(pods, func(p *corev1.Pod) bool { return p.Name == pod.Name }) {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("service %s has no selector and none of its ready endpoints (%s) is a pod in namespace %s",
+			svc.Name, strings.Join(unmatched, ", "), svc.Namespace)
+	}
+
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods, nil
+}
+
+// readyEndpoints returns the ready addresses of a service from its EndpointSlices, or from
+// its Endpoints when EndpointSlices can't be listed
+func readyEndpoints(ctx context.Context, client *kubernetes.Clientset, namespace, service string) ([]endpointAddress, error) {
+	endpointSlices, err := client.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + service,
+	})
+	if err == nil {
+		var addresses []endpointAddress
+		for _, slice := range endpointSlices.Items {
+			for _, endpoint := range slice.Endpoints {
+				// An unknown condition counts as ready
+				if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+					continue
+				}
+				for _, ip := range endpoint.Addresses {
+					addresses = append(addresses, endpointAddress{ip: ip, pod: endpointPodName(endpoint.TargetRef)})
+				}
+			}
+		}
+		return addresses, nil
+	}
+
+	endpoints, err := client.CoreV1().Endpoints(namespace).Get(ctx, service, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endpoints of service %s: %w", service, err)
+	}
+	var addresses []endpointAddress
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			addresses = append(addresses, endpointAddress{ip: address.IP, pod: endpointPodName(address.TargetRef)})
+		}
+	}
+	return addresses, nil
+}
+
+// endpointPodName returns the name of the pod an endpoint refers to, if it refers to one
+func endpointPodName(ref *corev1.ObjectReference) string {
+	if ref == nil || ref.Kind != "Pod" {
+		return ""
+	}
+	return ref.Name
+}
+
+// endpointPod returns a pod named by an endpoint
+func endpointPod(ctx context.Context, client *kubernetes.Clientset, nc *namespaceCache, namespace, name string) (*corev1.Pod, error) {
+	if nc != nil {
+		return nc.pod(name)
+	}
+	return client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// podByIP returns the pod with an IP in a namespace, nil when there is none
+func podByIP(ctx context.Context, client *kubernetes.Clientset, nc *namespaceCache, namespace, ip string) (*corev1.Pod, error) {
+	var pods []*corev1.Pod
+	if nc != nil {
+		cached, err := nc.pods.List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		pods = cached
+	} else {
+		list, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{FieldSelector: "status.podIP=" + ip})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			pods = append(pods, &list.Items[i])
+		}
+	}
+
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if pod.Status.PodIP == ip || slices.ContainsFunc(pod.Status.PodIPs, func(podIP corev1.PodIP) bool { return podIP.IP == ip }) {
+			return pod, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+)
+
+// defaultEnvFileTemplate renders entries like MYAPP_DB_URL=127.0.0.1:15432
+const defaultEnvFileTemplate = "{{.EnvName}}_URL={{.Host}}:{{.LocalPort}}"
+
+// EnvFileEntry is the data available to the env_file template for each active forward
+type EnvFileEntry struct {
+	Name       string // forward name (or service name)
+	EnvName    string // Name upper-cased with non-alphanumerics replaced by '_'
+	Cluster    string
+	Namespace  string
+	Service    string
+	Host       string
+	LocalPort  int
+	RemotePort int
+}
+
+// EnvFileWriter keeps a file of endpoints for all active forwards up to date
+type EnvFileWriter struct {
+	config   *EnvFileConfig
+	manager  *PortForwardManager
+	template *template.Template
+	mu       sync.Mutex
+	last     []byte
+}
+
+// NewEnvFileWriter creates a writer and registers it for manager updates
+func NewEnvFileWriter(config *EnvFileConfig, manager *PortForwardManager) (*EnvFileWriter, error) {
+	tmpl, err := template.New("env_file").Parse(config.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid env_file template: %w", err)
+	}
+
+	w := &EnvFileWriter{
+		config:   config,
+		manager:  manager,
+		template: tmpl,
+	}
+	manager.OnEvent(func(Event) { w.Write() })
+
+	return w, nil
+}
+
+// Write renders the file from the current forward states, skipping the write if nothing changed
+func (w *EnvFileWriter) Write() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by nanoporter - active port-forwards\n")
+
+	for _, pf := range w.manager.GetForwards() {
+		if pf.GetState() != StateActive {
+			continue
+		}
+		if err := w.template.Execute(&buf, newEnvFileEntry(pf)); err != nil {
+			slog.Warn("Failed to render env_file entry", "service", pf.Config.Service, "error", err)
+			continue
+		}
+		buf.WriteString("\n")
+	}
+
+	if bytes.Equal(buf.Bytes(), w.last) {
+		return
+	}
+
+	if err := writeFileAtomic(w.config.Path, buf.Bytes(), 0644); err != nil {
+		slog.Warn("Failed to write env_file", "path", w.config.Path, "error", err)
+		return
+	}
+	w.last = buf.Bytes()
+
+	slog.Debug("Updated env_file", "path", w.config.Path)
+}
+
+// newEnvFileEntry builds the template data for a forward
+func newEnvFileEntry(pf *PortForward) EnvFileEntry {
+	name := pf.Config.Name
+	if name == "" {
+		name = pf.Config.Service
+	}
+
+	return EnvFileEntry{
+		Name:       name,
+		EnvName:    envName(name),
+		Cluster:    pf.ClusterName,
+		Namespace:  pf.Config.Namespace,
+		Service:    pf.Config.Service,
+		Host:       pf.LocalAddress(),
+		LocalPort:  pf.LocalPort(),
+		RemotePort: pf.RemotePort(),
+	}
+}
+
+// envName converts a name like "myapp-db" into an environment variable prefix "MYAPP_DB"
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path,
+// so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
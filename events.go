@@ -0,0 +1,110 @@
+package main
+
+import (
+	"time"
+)
+
+// EventType identifies what changed about a port-forward
+type EventType string
+
+const (
+	// EventStateChanged is emitted when a forward's state, error or retry count changes
+	EventStateChanged EventType = "state_changed"
+	// EventHealthCheckFailed is emitted when an active forward's local port stops accepting connections
+	EventHealthCheckFailed EventType = "health_check_failed"
+	// EventBackupProgress is emitted when a forward's database backup changes state
+	EventBackupProgress EventType = "backup_progress"
+	// EventPodSwitched is emitted when a forward reconnects to a different pod
+	EventPodSwitched EventType = "pod_switched"
+	// EventForwardAdded is emitted when a forward is added at runtime
+	EventForwardAdded EventType = "forward_added"
+	// EventForwardRemoved is emitted when a forward is removed at runtime
+	EventForwardRemoved EventType = "forward_removed"
+)
+
+// Event describes a change to a port-forward. Forward is a snapshot taken when the event
+// was emitted, so consumers never need to lock the port-forward itself.
+type Event struct {
+	Type    EventType     `json:"type"`
+	Time    time.Time     `json:"time"`
+	Forward ForwardStatus `json:"forward"`
+
+	PreviousState ForwardState `json:"previous_state,omitempty"` // EventStateChanged
+	PreviousPod   string       `json:"previous_pod,omitempty"`   // EventPodSwitched
+	Error         string       `json:"error,omitempty"`          // EventHealthCheckFailed
+}
+
+// OnEvent registers a function called synchronously for every event. Listeners must return quickly.
+func (m *PortForwardManager) OnEvent(fn func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Subscribe returns a channel receiving events and a function ending the subscription.
+// Events are dropped when the subscriber falls more than buffer events behind.
+func (m *PortForwardManager) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	m.mu.Lock()
+	m.subscribers[ch] = struct{}{}
+	m.mu.Unlock()
+
+	unsubscribe := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// emit delivers an event to listeners and subscribers. Detached forwards aren't reported.
+func (m *PortForwardManager) emit(event Event) {
+	if event.Forward.detached {
+		return
+	}
+	event.Time = time.Now()
+
+	m.mu.RLock()
+	listeners := m.listeners
+	m.mu.RUnlock()
+
+	for _, fn := range listeners {
+		fn(event)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber is behind, skip the event
+		}
+	}
+}
+
+// emitStateChanged emits EventStateChanged with the state last reported for the forward
+func (m *PortForwardManager) emitStateChanged(pf *PortForward) {
+	pf.mu.Lock()
+	previous := pf.reportedState
+	pf.reportedState = pf.State
+	if previous == StateActive && pf.State != StateActive && !pf.ActiveSince.IsZero() {
+		pf.uptime += time.Since(pf.ActiveSince)
+	}
+	if previous != StateReconnecting && pf.State == StateReconnecting {
+		pf.reconnects++
+	}
+	pf.mu.Unlock()
+
+	m.emit(Event{Type: EventStateChanged, Forward: pf.Status(), PreviousState: previous})
+}
+
+// emitBackupProgress emits EventBackupProgress for a forward
+func (m *PortForwardManager) emitBackupProgress(pf *PortForward) {
+	m.emit(Event{Type: EventBackupProgress, Forward: pf.Status()})
+}
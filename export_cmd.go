@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Formats of the export subcommand
+const (
+	exportKubectl = "kubectl" // one command per forward
+	exportShell   = "shell"   // a script running all of them, restarted when they exit
+)
+
+// runExportCommand prints the configured forwards as kubectl port-forward commands, for
+// teammates without nanoporter or as a fallback when nanoporter itself is suspected
+func runExportCommand() {
+	exportFlags := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := exportFlags.String("config", defaultConfigPath, "Path to configuration file")
+	format := exportFlags.String("format", exportKubectl, "Output format: kubectl (one command per forward) or shell (a script running all of them)")
+	exportFlags.Parse(os.Args[2:])
+
+	if *format != exportKubectl && *format != exportShell {
+		fmt.Fprintf(os.Stderr, "Error: unknown format '%s' (expected kubectl or shell)\n", *format)
+		os.Exit(2)
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var lines []string
+	for _, cluster := range config.Clusters {
+		// Service ports and primaries are looked up in the cluster when it's reachable
+		var client *ClusterClient
+		if cluster.usesKubernetes() {
+			if c, err := NewClusterClient(cluster); err == nil {
+				if _, clientset := c.Get(); clientset != nil {
+					if _, err := clientset.Discovery().ServerVersion(); err == nil {
+						client = c
+					}
+				}
+			}
+		}
+		for _, forward := range cluster.Forwards {
+			command, note, err := exportForward(cluster, forward, client)
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("# %s/%s/%s skipped: %v", cluster.Name, forward.Namespace, forward.Service, err))
+				continue
+			}
+			if note != "" {
+				lines = append(lines, fmt.Sprintf("# %s/%s/%s: %s", cluster.Name, forward.Namespace, forward.Service, note))
+			}
+			if *format == exportShell {
+				command = "forward " + command + " &"
+			}
+			lines = append(lines, command)
+		}
+	}
+
+	if *format == exportKubectl {
+		fmt.Println(strings.Join(lines, "\n"))
+		return
+	}
+
+	fmt.Printf(`#!/bin/sh
+# Generated by nanoporter export from %s. Runs the forwards and restarts each one when it
+# exits; Ctrl+C stops all of them.
+trap 'trap - EXIT; kill 0' INT TERM EXIT
+
+forward() {
+  while true; do
+    "$@"
+    echo "$* exited, restarting in 2s" >&2
+    sleep 2
+  done
+}
+
+%s
+
+wait
+`, *configPath, strings.Join(lines, "\n"))
+}
+
+// exportForward returns the command that forwards like a forward of the config, and a note
+// about where it falls short. client is nil when the cluster can't be reached.
+func exportForward(cluster ClusterConfig, forward ForwardConfig, client *ClusterClient) (string, string, error) {
+	address := cluster.LocalAddress()
+
+	switch forward.Type {
+	case "service", "pod":
+		if forward.Service == wildcardService {
+			return "", "", fmt.Errorf("wildcard forwards are only expanded by nanoporter")
+		}
+		if forward.FanOut {
+			return "", "", fmt.Errorf("fan_out forwards are only expanded by nanoporter")
+		}
+		target, remotePort, note, err := exportTarget(cluster, forward, client)
+		if err != nil {
+			return "", "", err
+		}
+		args := []string{"kubectl"}
+		if cluster.Kubeconfig != "" {
+			args = append(args, "--kubeconfig", cluster.Kubeconfig)
+		}
+		if cluster.Context != "" {
+			args = append(args, "--context", cluster.Context)
+		}
+		args = append(args, "-n", forward.Namespace, "port-forward", target,
+			fmt.Sprintf("%d:%s", forward.LocalPort, remotePort))
+		if address != defaultBindAddress {
+			args = append(args, "--address", address)
+		}
+		return shellJoin(args), note, nil
+	case "ssh":
+		server := forward.SSH.Host
+		args := []string{"ssh", "-N"}
+		if host, port, err := net.SplitHostPort(server); err == nil {
+			server = host
+			args = append(args, "-p", port)
+		}
+		if forward.SSH.KeyFile != "" {
+			args = append(args, "-i", forward.SSH.KeyFile)
+		}
+		if forward.SSH.User != "" {
+			server = forward.SSH.User + "@" + server
+		}
+		args = append(args, "-L", net.JoinHostPort(address, strconv.Itoa(forward.LocalPort))+":"+
+			net.JoinHostPort(forward.Service, strconv.Itoa(forward.RemotePort)), server)
+		return shellJoin(args), "", nil
+	case "tcp":
+		return shellJoin([]string{"socat",
+			fmt.Sprintf("TCP-LISTEN:%d,bind=%s,reuseaddr,fork", forward.LocalPort, address),
+			"TCP:" + net.JoinHostPort(forward.Service, strconv.Itoa(forward.RemotePort))}), "", nil
+	}
+	return "", "", fmt.Errorf("%s forwards have no command-line equivalent", forward.Type)
+}
+
+// exportTarget returns the kubectl port-forward target and remote port of a Kubernetes
+// forward. kubectl reads the port of svc/NAME as a Service port, while remote_port is a
+// container port, so it's mapped to the Service port targeting it. A forward with primary
+// goes to the pod that is the primary right now, as kubectl can't follow a switchover.
+// Without the cluster, remote_port is used as is and noted.
+func exportTarget(cluster ClusterConfig, forward ForwardConfig, client *ClusterClient) (string, string, string, error) {
+	if forward.Type == "pod" {
+		// kubectl resolves named container ports of pods itself
+		return "pod/" + forward.Service, forward.RemotePortLabel(), "", nil
+	}
+	if client == nil {
+		return "svc/" + forward.Service, forward.RemotePortLabel(), "cluster unreachable, remote_port used as the Service port", nil
+	}
+
+	pf := newPortForward(cluster, forward, client)
+	pods, err := listTargetPods(pf)
+	if err != nil {
+		if forward.Primary != "" {
+			return "", "", "", err
+		}
+		pods = nil
+	}
+	var running []*corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning {
+			running = append(running, pod)
+		}
+	}
+
+	if forward.Primary != "" {
+		primaries, err := primaryPods(pf, running)
+		if err != nil {
+			return "", "", "", err
+		}
+		remotePort, err := resolveRemotePort(primaries[0], forward)
+		if err != nil {
+			return "", "", "", err
+		}
+		return "pod/" + primaries[0].Name, strconv.Itoa(remotePort), "current primary, not followed on switchover", nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, clientset := client.Get()
+	svc, err := clientset.CoreV1().Services(forward.Namespace).Get(ctx, forward.Service, metav1.GetOptions{})
+	if err != nil {
+		return "svc/" + forward.Service, forward.RemotePortLabel(), fmt.Sprintf("remote_port used as the Service port: %v", err), nil
+	}
+
+	// Named ports, of the forward or the Service's targetPort, are resolved on a pod
+	var pod *corev1.Pod
+	if len(running) > 0 {
+		pod = running[0]
+	}
+	containerPort := forward.RemotePort
+	if pod != nil {
+		if containerPort, err = resolveRemotePort(pod, forward); err != nil {
+			return "", "", "", err
+		}
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+			continue
+		}
+		target := port.TargetPort.IntValue()
+		if port.TargetPort.Type == intstr.String {
+			if forward.RemotePortName != "" && port.TargetPort.StrVal == forward.RemotePortName {
+				return "svc/" + forward.Service, strconv.Itoa(int(port.Port)), "", nil
+			}
+			if pod != nil {
+				target, _ = resolveRemotePort(pod, ForwardConfig{RemotePortName: port.TargetPort.StrVal})
+			}
+		} else if target == 0 {
+			// targetPort defaults to the port
+			target = int(port.Port)
+		}
+		if containerPort != 0 && target == containerPort {
+			return "svc/" + forward.Service, strconv.Itoa(int(port.Port)), "", nil
+		}
+	}
+	return "", "", "", fmt.Errorf("service %s has no port targeting container port %s", forward.Service, forward.RemotePortLabel())
+}
+
+// shellSafe matches arguments that need no quoting in a POSIX shell
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellJoin joins arguments into a POSIX shell command line, single-quoting where needed
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if shellSafe.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// fanOutInterval is how often the pods behind fan_out forwards are listed
+const fanOutInterval = 5 * time.Second
+
+// FanOut keeps a forward per pod of the services of fan_out forwards, adding and removing
+// them as pods come and go
+type FanOut struct {
+	manager *PortForwardManager
+	groups  []*fanOutGroup
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// fanOutGroup is a fan_out forward and the forwards of its pods
+type fanOutGroup struct {
+	cluster  ClusterConfig
+	forward  ForwardConfig
+	template *PortForward            // lists the service's pods, never started
+	forwards map[string]*PortForward // by pod name
+	slots    map[string]int          // local port offset by pod name
+}
+
+// hasFanOut reports whether any forward of the config has fan_out set
+func hasFanOut(config *Config) bool {
+	for _, cluster := range config.Clusters {
+		for _, forward := range cluster.Forwards {
+			if forward.FanOut {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewFanOut creates the pod forwards of the config's fan_out forwards
+func NewFanOut(config *Config, manager *PortForwardManager) *FanOut {
+	f := &FanOut{
+		manager: manager,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, cluster := range config.Clusters {
+		for _, forward := range cluster.Forwards {
+			if forward.FanOut {
+				f.groups = append(f.groups, &fanOutGroup{
+					cluster:  cluster,
+					forward:  forward,
+					forwards: make(map[string]*PortForward),
+					slots:    make(map[string]int),
+				})
+			}
+		}
+	}
+	return f
+}
+
+// Start lists the pods right away and then on every interval
+func (f *FanOut) Start() {
+	go func() {
+		defer close(f.done)
+
+		ticker := time.NewTicker(fanOutInterval)
+		defer ticker.Stop()
+
+		for {
+			for _, group := range f.groups {
+				f.sync(group)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-f.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops following the pods; their forwards stop with the manager
+func (f *FanOut) Stop() {
+	close(f.stop)
+	<-f.done
+}
+
+// sync adds forwards for new pods of a group's service and removes those of pods that are
+// gone. Pods that can't be listed keep their forwards.
+func (f *FanOut) sync(group *fanOutGroup) {
+	if f.manager.ClusterDisabled(group.cluster.Name) || f.manager.Paused() {
+		return
+	}
+	if group.template == nil {
+		clusterClient := f.manager.ClusterClient(group.cluster.Name)
+		if clusterClient == nil {
+			return
+		}
+		group.template = newPortForward(group.cluster, group.forward, clusterClient)
+	}
+	if group.template.cluster.Err() != nil {
+		return
+	}
+
+	pods, err := targetPods(group.template)
+	if err != nil {
+		slog.Warn("Failed to list pods of fan_out forward",
+			"cluster", group.cluster.Name,
+			"namespace", group.forward.Namespace,
+			"service", group.forward.Service,
+			"error", err,
+		)
+		return
+	}
+
+	current := make(map[string]*corev1.Pod)
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			current[pod.Name] = pod
+		}
+	}
+
+	for name, pf := range group.forwards {
+		if _, ok := current[name]; ok {
+			continue
+		}
+		if err := f.manager.RemoveForward(pf.ID); err != nil {
+			slog.Warn("Failed to remove forward of deleted pod", "forward", pf.ID, "error", err)
+		}
+		delete(group.forwards, name)
+		delete(group.slots, name)
+	}
+
+	// StatefulSet pods keep their ordinal; other pods take the lowest free one in name order
+	var ordered []*corev1.Pod
+	for _, pod := range current {
+		if _, ok := group.forwards[pod.Name]; !ok {
+			ordered = append(ordered, pod)
+		}
+	}
+	slices.SortFunc(ordered, func(a, b *corev1.Pod) int {
+		_, aOrdinal := podOrdinal(a)
+		_, bOrdinal := podOrdinal(b)
+		if aOrdinal != bOrdinal {
+			if aOrdinal {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, pod := range ordered {
+		slot, ok := podOrdinal(pod)
+		if !ok {
+			slot = group.freeSlot()
+		}
+
+		pf, err := f.manager.AddForward(group.cluster, group.podForward(pod.Name, slot))
+		if err != nil {
+			slog.Warn("Failed to add forward for pod",
+				"cluster", group.cluster.Name,
+				"namespace", group.forward.Namespace,
+				"pod", pod.Name,
+				"error", err,
+			)
+			continue
+		}
+		group.forwards[pod.Name] = pf
+		group.slots[pod.Name] = slot
+	}
+}
+
+// podForward returns the forward of one pod, listening on the group's local_port + slot
+func (group *fanOutGroup) podForward(podName string, slot int) ForwardConfig {
+	name := group.forward.Name
+	if name == "" {
+		name = group.forward.Service
+	}
+
+	forward := group.forward
+	forward.FanOut = false
+	forward.Type = "pod"
+	forward.Service = podName
+	forward.Name = name + "-" + strconv.Itoa(slot)
+	forward.LocalPort = group.forward.LocalPort + slot
+	return forward
+}
+
+// freeSlot returns the lowest local port offset no pod of the group uses
+func (group *fanOutGroup) freeSlot() int {
+	used := make(map[int]bool)
+	for _, slot := range group.slots {
+		used[slot] = true
+	}
+	slot := 0
+	for used[slot] {
+		slot++
+	}
+	return slot
+}
+
+// podOrdinal returns the ordinal of a StatefulSet pod, from its pod-index label or the
+// suffix of its name
+func podOrdinal(pod *corev1.Pod) (int, bool) {
+	if value, ok := pod.Labels[appsv1.PodIndexLabel]; ok {
+		if ordinal, err := strconv.Atoi(value); err == nil && ordinal >= 0 {
+			return ordinal, true
+		}
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind != "StatefulSet" {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(pod.Name, owner.Name+"-"); ok {
+			if ordinal, err := strconv.Atoi(suffix); err == nil && ordinal >= 0 {
+				return ordinal, true
+			}
+		}
+	}
+	return 0, false
+}
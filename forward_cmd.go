@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// addForwardParams are the parameters of the "add" control command
+type addForwardParams struct {
+	Cluster string        `json:"cluster"`
+	Forward ForwardConfig `json:"forward"`
+}
+
+// removeForwardParams are the parameters of the "remove" control command
+type removeForwardParams struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+}
+
+// runAddCommand appends a forward to the config file and starts it in a running instance
+func runAddCommand() {
+	addFlags := flag.NewFlagSet("add", flag.ExitOnError)
+	configPath := addFlags.String("config", defaultConfigPath, "Path to configuration file")
+	clusterName := addFlags.String("cluster", "", "Cluster to add the forward to")
+	namespace := addFlags.String("namespace", "", "Namespace of the service or pod")
+	service := addFlags.String("service", "", "Service or pod name")
+	resourceType := addFlags.String("type", "service", "Resource type: service or pod")
+	localPort := addFlags.Int("local", 0, "Local port")
+	remotePort := addFlags.Int("remote", 0, "Remote port")
+	name := addFlags.String("name", "", "Optional alias used in generated files")
+	addFlags.Parse(os.Args[2:])
+
+	if *clusterName == "" || *namespace == "" || *service == "" || *localPort == 0 || *remotePort == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: nanoporter add --cluster NAME --namespace NS --service SVC --local PORT --remote PORT")
+		addFlags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	forward := ForwardConfig{
+		Name:       *name,
+		Namespace:  *namespace,
+		Service:    *service,
+		Type:       *resourceType,
+		LocalPort:  *localPort,
+		RemotePort: *remotePort,
+	}
+
+	// Validate the config as it will look with the new forward
+	cluster := findCluster(config, *clusterName)
+	if cluster == nil {
+		fmt.Fprintf(os.Stderr, "Error: cluster '%s' not found in config\n", *clusterName)
+		os.Exit(1)
+	}
+	cluster.Forwards = append(cluster.Forwards, forward)
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := appendForwardToConfig(*configPath, *clusterName, forward); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Added %s/%s/%s on port %d to %s\n", *clusterName, *namespace, *service, *localPort, *configPath)
+
+	notifyRunningInstance(config.ControlSocket, "add", addForwardParams{Cluster: *clusterName, Forward: forward})
+}
+
+// runRemoveCommand deletes a forward from the config file and stops it in a running instance
+func runRemoveCommand() {
+	removeFlags := flag.NewFlagSet("remove", flag.ExitOnError)
+	configPath := removeFlags.String("config", defaultConfigPath, "Path to configuration file")
+	clusterName := removeFlags.String("cluster", "", "Cluster of the forward")
+	namespace := removeFlags.String("namespace", "", "Namespace of the forward")
+	service := removeFlags.String("service", "", "Service or pod name of the forward")
+	removeFlags.Parse(os.Args[2:])
+
+	if *clusterName == "" || *namespace == "" || *service == "" {
+		fmt.Fprintln(os.Stderr, "Usage: nanoporter remove --cluster NAME --namespace NS --service SVC")
+		removeFlags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := removeForwardFromConfig(*configPath, *clusterName, *namespace, *service); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed %s/%s/%s from %s\n", *clusterName, *namespace, *service, *configPath)
+
+	notifyRunningInstance(config.ControlSocket, "remove", removeForwardParams{
+		Cluster:   *clusterName,
+		Namespace: *namespace,
+		Service:   *service,
+	})
+}
+
+// notifyRunningInstance applies a config change to a running instance, if there is one
+func notifyRunningInstance(socketPath, command string, params any) {
+	client, err := DialControl(socketPath)
+	if err != nil {
+		fmt.Println("No running instance found; the change applies on the next start")
+		return
+	}
+	defer client.Close()
+
+	if err := client.Call(command, params, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: running instance did not apply the change: %v\n", err)
+		return
+	}
+	fmt.Println("Running instance updated")
+}
+
+// handleAddForward returns the control handler starting a forward added with `nanoporter add`
+func handleAddForward(config *Config, manager *PortForwardManager) ControlHandler {
+	return func(params json.RawMessage) (any, error) {
+		var req addForwardParams
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+
+		cluster := findCluster(config, req.Cluster)
+		if cluster == nil {
+			return nil, fmt.Errorf("cluster '%s' is not configured in the running instance", req.Cluster)
+		}
+
+		_, err := manager.AddForward(*cluster, req.Forward)
+		return nil, err
+	}
+}
+
+// handleRemoveForward returns the control handler stopping a forward removed with `nanoporter remove`
+func handleRemoveForward(manager *PortForwardManager) ControlHandler {
+	return func(params json.RawMessage) (any, error) {
+		var req removeForwardParams
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+
+		pf := manager.FindForward(req.Cluster, req.Namespace, req.Service)
+		if pf == nil {
+			return nil, fmt.Errorf("no port-forward for %s/%s/%s", req.Cluster, req.Namespace, req.Service)
+		}
+
+		return nil, manager.RemoveForward(pf.ID)
+	}
+}
+
+// findCluster returns the cluster with a name, or nil
+func findCluster(config *Config, name string) *ClusterConfig {
+	for i := range config.Clusters {
+		if config.Clusters[i].Name == name {
+			return &config.Clusters[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// Hook event names, exported to hook commands as NANOPORTER_EVENT
+const (
+	HookPostStart = "post_start"
+	HookPreStop   = "pre_stop"
+	HookOnFailure = "on_failure"
+
+	HookBackupPre         = "backup_pre"
+	HookBackupPostSuccess = "backup_post_success"
+	HookBackupPostFailure = "backup_post_failure"
+)
+
+// defaultHookTimeout bounds how long a single hook command may run
+const defaultHookTimeout = 30 * time.Second
+
+// shellCommand builds a command that runs a string through the platform shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// forwardEnv returns environment variables describing a port-forward
+func forwardEnv(pf *PortForward) []string {
+	pf.mu.RLock()
+	state, lastErr, retries := pf.State, pf.Error, pf.RetryCount
+	pf.mu.RUnlock()
+
+	return []string{
+		"NANOPORTER_CLUSTER=" + pf.ClusterName,
+		"NANOPORTER_NAMESPACE=" + pf.Config.Namespace,
+		"NANOPORTER_SERVICE=" + pf.Config.Service,
+		fmt.Sprintf("NANOPORTER_LOCAL_PORT=%d", pf.LocalPort()),
+		fmt.Sprintf("NANOPORTER_REMOTE_PORT=%d", pf.RemotePort()),
+		"NANOPORTER_STATE=" + string(state),
+		"NANOPORTER_ERROR=" + lastErr,
+		fmt.Sprintf("NANOPORTER_RETRY_COUNT=%d", retries),
+	}
+}
+
+// runHook runs the hook command configured for an event, if any, and waits for it to finish
+func runHook(pf *PortForward, event string) {
+	hooks := pf.Config.Hooks
+	if hooks == nil {
+		return
+	}
+
+	var command string
+	switch event {
+	case HookPostStart:
+		command = hooks.PostStart
+	case HookPreStop:
+		command = hooks.PreStop
+	case HookOnFailure:
+		command = hooks.OnFailure
+	}
+	if command == "" {
+		return
+	}
+
+	runHookCommand(pf, event, command, hooks.Timeout, nil)
+}
+
+// runBackupHook runs a database backup hook, if configured, with extra environment variables
+func runBackupHook(pf *PortForward, event, command string, timeout time.Duration, env []string) error {
+	if command == "" {
+		return nil
+	}
+	if err := runHookCommand(pf, event, command, timeout, env); err != nil {
+		return fmt.Errorf("%s hook failed: %w", event, err)
+	}
+	return nil
+}
+
+// runHookCommand runs a hook command with the forward's environment and waits for it to finish
+func runHookCommand(pf *PortForward, event, command string, timeout time.Duration, env []string) error {
+	if timeout == 0 {
+		timeout = defaultHookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(), forwardEnv(pf)...)
+	cmd.Env = append(cmd.Env, "NANOPORTER_EVENT="+event)
+	cmd.Env = append(cmd.Env, env...)
+
+	slog.Debug("Running hook",
+		"event", event,
+		"cluster", pf.ClusterName,
+		"service", pf.Config.Service,
+		"command", command,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		slog.Warn("Hook failed",
+			"event", event,
+			"cluster", pf.ClusterName,
+			"namespace", pf.Config.Namespace,
+			"service", pf.Config.Service,
+			"error", err,
+			"output", string(output),
+		)
+		return err
+	}
+
+	slog.Info("Hook completed",
+		"event", event,
+		"cluster", pf.ClusterName,
+		"service", pf.Config.Service,
+	)
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Markers delimiting the block of the hosts file managed by nanoporter
+const (
+	hostsBlockBegin = "# BEGIN nanoporter"
+	hostsBlockEnd   = "# END nanoporter"
+)
+
+// defaultHostsFilePath returns the hosts file location for the current OS
+func defaultHostsFilePath() string {
+	if runtime.GOOS == "windows" {
+		return `C:\Windows\System32\drivers\etc\hosts`
+	}
+	return "/etc/hosts"
+}
+
+// HostsManager adds and removes hosts file entries mapping forward hostnames to their loopback address
+type HostsManager struct {
+	config *HostsFileConfig
+	lines  []string
+}
+
+// NewHostsManager collects the hostnames of all configured forwards
+func NewHostsManager(config *Config) *HostsManager {
+	var lines []string
+	for _, cluster := range config.Clusters {
+		for _, forward := range cluster.Forwards {
+			if len(forward.Hostnames) == 0 {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s\t%s\t# %s/%s/%s", cluster.LocalAddress(),
+				strings.Join(forward.Hostnames, " "), cluster.Name, forward.Namespace, forward.Service))
+		}
+	}
+
+	return &HostsManager{
+		config: config.HostsFile,
+		lines:  lines,
+	}
+}
+
+// Apply writes the managed block into the hosts file, replacing any previous one
+func (h *HostsManager) Apply() error {
+	if len(h.lines) == 0 {
+		return nil
+	}
+
+	current, err := os.ReadFile(h.config.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(stripHostsBlock(current))
+	buf.WriteString(hostsBlockBegin + "\n")
+	for _, line := range h.lines {
+		buf.WriteString(line + "\n")
+	}
+	buf.WriteString(hostsBlockEnd + "\n")
+
+	if err := h.write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	slog.Info("Added hosts file entries", "path", h.config.Path, "count", len(h.lines))
+	return nil
+}
+
+// Cleanup removes the managed block from the hosts file
+func (h *HostsManager) Cleanup() error {
+	if len(h.lines) == 0 {
+		return nil
+	}
+
+	current, err := os.ReadFile(h.config.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	if !bytes.Contains(current, []byte(hostsBlockBegin)) {
+		return nil
+	}
+
+	if err := h.write(stripHostsBlock(current)); err != nil {
+		return err
+	}
+
+	slog.Info("Removed hosts file entries", "path", h.config.Path)
+	return nil
+}
+
+// write replaces the hosts file contents in place, elevating through sudo when configured
+func (h *HostsManager) write(data []byte) error {
+	err := os.WriteFile(h.config.Path, data, 0644)
+	if err == nil {
+		return nil
+	}
+	if !os.IsPermission(err) {
+		return fmt.Errorf("failed to write hosts file: %w", err)
+	}
+
+	if !h.config.Sudo || runtime.GOOS == "windows" {
+		return fmt.Errorf("no permission to write %s: run nanoporter with sufficient privileges or set hosts_file.sudo: true", h.config.Path)
+	}
+
+	slog.Info("Writing hosts file through sudo", "path", h.config.Path)
+
+	cmd := exec.Command("sudo", "tee", h.config.Path)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sudo tee %s failed: %w", h.config.Path, err)
+	}
+
+	return nil
+}
+
+// stripHostsBlock removes the nanoporter-managed block from hosts file contents
+func stripHostsBlock(data []byte) []byte {
+	var buf bytes.Buffer
+	inBlock := false
+
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == hostsBlockBegin:
+			inBlock = true
+		case trimmed == hostsBlockEnd:
+			inBlock = false
+		case !inBlock:
+			buf.WriteString(line)
+		}
+	}
+
+	// Make sure the block starts on its own line
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Actions of the forward list that keys can be bound to
+const (
+	actionQuit          = "quit"
+	actionUp            = "up"
+	actionDown          = "down"
+	actionTop           = "top"
+	actionBottom        = "bottom"
+	actionMark          = "mark"
+	actionVisual        = "visual"
+	actionAdd           = "add"
+	actionOpen          = "open"
+	actionRetry         = "retry"
+	actionRetryAll      = "retry_all"
+	actionDisable       = "disable"
+	actionBackupHistory = "backup_history"
+	actionHelp          = "help"
+	actionToggleMode    = "toggle_mode"
+	actionCapture       = "capture"
+	actionPauseAll      = "pause_all"
+)
+
+// defaultKeymap binds the actions to keys. A binding is a key as bubbletea names it
+// ("k", "ctrl+r", "pgup", "space"), or a sequence of them separated by spaces ("g g").
+var defaultKeymap = map[string][]string{
+	actionQuit:          {"q", "esc"},
+	actionUp:            {"up", "k"},
+	actionDown:          {"down", "j"},
+	actionTop:           {"g g", "home"},
+	actionBottom:        {"G", "end"},
+	actionMark:          {"space"},
+	actionVisual:        {"v"},
+	actionAdd:           {"a"},
+	actionOpen:          {"o"},
+	actionRetry:         {"r"},
+	actionRetryAll:      {"R"},
+	actionDisable:       {"d"},
+	actionBackupHistory: {"B"},
+	actionHelp:          {"?"},
+	actionToggleMode:    {"m"},
+	actionCapture:       {"c"},
+	actionPauseAll:      {"P"},
+}
+
+// keymapHelp describes the actions, in the order the help overlay lists them
+var keymapHelp = []struct {
+	action      string
+	description string
+}{
+	{actionUp, "Move the cursor up"},
+	{actionDown, "Move the cursor down"},
+	{actionTop, "Jump to the first port-forward"},
+	{actionBottom, "Jump to the last port-forward"},
+	{actionMark, "Mark or unmark the port-forward under the cursor"},
+	{actionVisual, "Select a range by moving the cursor; again to keep it marked"},
+	{actionAdd, "Add a port-forward"},
+	{actionOpen, "Open the selected port-forwards in the browser"},
+	{actionRetry, "Retry the selected port-forwards now"},
+	{actionRetryAll, "Retry all reconnecting and failed port-forwards now"},
+	{actionDisable, "Disable or enable the clusters of the selected port-forwards"},
+	{actionCapture, "Start or stop capturing the traffic of the selected port-forwards"},
+	{actionPauseAll, "Pause all port-forwards, or resume them"},
+	{actionBackupHistory, "Show the backup history"},
+	{actionToggleMode, "Switch between the wide table and compact mode"},
+	{actionHelp, "Show this help"},
+	{actionQuit, "Clear the selection, or quit and stop all port-forwards"},
+}
+
+// keymap resolves key presses to actions
+type keymap struct {
+	actions  map[string]string   // key sequence -> action
+	keys     map[string][]string // action -> key sequences
+	prefixes map[string]bool     // incomplete key sequences
+}
+
+// newKeymap builds the keymap from the defaults and the configured bindings, which replace
+// the default keys of their action
+func newKeymap(overrides map[string][]string) (keymap, error) {
+	km := keymap{
+		actions:  make(map[string]string),
+		keys:     make(map[string][]string),
+		prefixes: make(map[string]bool),
+	}
+
+	for action := range overrides {
+		if _, ok := defaultKeymap[action]; !ok {
+			names := make([]string, 0, len(defaultKeymap))
+			for name := range defaultKeymap {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return km, fmt.Errorf("unknown action '%s' (must be one of %s)", action, strings.Join(names, ", "))
+		}
+	}
+
+	// Sorted, so conflicts are reported the same way every time
+	actions := make([]string, 0, len(defaultKeymap))
+	for action := range defaultKeymap {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	for _, action := range actions {
+		bindings := defaultKeymap[action]
+		if keys, ok := overrides[action]; ok {
+			bindings = keys
+		}
+		for _, binding := range bindings {
+			seq := strings.Join(strings.Fields(binding), " ")
+			if seq == "" {
+				return km, fmt.Errorf("action '%s' has an empty key", action)
+			}
+			if other, ok := km.actions[seq]; ok {
+				return km, fmt.Errorf("key '%s' is bound to both '%s' and '%s'", binding, other, action)
+			}
+			km.actions[seq] = action
+			km.keys[action] = append(km.keys[action], seq)
+		}
+	}
+
+	// A key can't both run an action and start a longer sequence
+	for seq := range km.actions {
+		parts := strings.Fields(seq)
+		for i := 1; i < len(parts); i++ {
+			prefix := strings.Join(parts[:i], " ")
+			if action, ok := km.actions[prefix]; ok {
+				return km, fmt.Errorf("key '%s' of '%s' starts the sequence '%s' of '%s'",
+					prefix, action, seq, km.actions[seq])
+			}
+			km.prefixes[prefix] = true
+		}
+	}
+
+	return km, nil
+}
+
+// lookup resolves a key pressed after the pending keys of a sequence. It returns the
+// action, or the new pending keys when the sequence isn't complete yet.
+func (km keymap) lookup(pending, key string) (action, next string) {
+	if key == " " {
+		key = "space"
+	}
+	seq := key
+	if pending != "" {
+		seq = pending + " " + key
+	}
+	if action, ok := km.actions[seq]; ok {
+		return action, ""
+	}
+	if km.prefixes[seq] {
+		return "", seq
+	}
+	if pending != "" {
+		// A broken sequence; try the key on its own
+		return km.lookup("", key)
+	}
+	return "", ""
+}
+
+// help returns the first key bound to an action, as shown in the help text
+func (km keymap) help(action string) string {
+	keys := km.keys[action]
+	if len(keys) == 0 {
+		return ""
+	}
+	return keyName(keys[0])
+}
+
+// keyName returns how a key sequence is shown in help texts
+func keyName(seq string) string {
+	switch seq {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	}
+	return strings.ReplaceAll(seq, " ", "")
+}
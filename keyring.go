@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// keyringPrefix marks config values that are read from the OS keyring, e.g.
+// password: keyring:nanoporter/staging-db
+const keyringPrefix = "keyring:"
+
+// defaultKeyringService is the keyring service of references without one
+const defaultKeyringService = "nanoporter"
+
+// errKeyringNotFound is returned by the platform keyrings for a missing secret
+var errKeyringNotFound = errors.New("not found")
+
+// parseKeyringReference splits a reference like "nanoporter/staging-db" (the keyring: prefix
+// is optional) into the keyring service and account. A reference without a service uses
+// "nanoporter".
+func parseKeyringReference(ref string) (service, account string, err error) {
+	ref = strings.TrimPrefix(ref, keyringPrefix)
+	service, account, ok := strings.Cut(ref, "/")
+	if !ok {
+		service, account = defaultKeyringService, ref
+	}
+	if service == "" || account == "" {
+		return "", "", fmt.Errorf("invalid keyring reference '%s' (expected keyring:service/account)", ref)
+	}
+	return service, account, nil
+}
+
+// resolveKeyringReference returns the secret a keyring: value refers to; other values are
+// returned as they are
+func resolveKeyringReference(value string) (string, error) {
+	if !strings.HasPrefix(value, keyringPrefix) {
+		return value, nil
+	}
+	service, account, err := parseKeyringReference(value)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := keyringGet(service, account)
+	if errors.Is(err, errKeyringNotFound) {
+		return "", fmt.Errorf("no secret %s/%s in the OS keyring (store it with `nanoporter secret set %s/%s`)",
+			service, account, service, account)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s/%s from the OS keyring: %w", service, account, err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringGet reads a secret from the login keychain
+func keyringGet(service, account string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if strings.Contains(stderr.String(), "could not be found") {
+			return "", errKeyringNotFound
+		}
+		return "", fmt.Errorf("security: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// keyringSet stores a secret in the login keychain. The command is passed to security on
+// stdin, so the secret doesn't show up in the process list. The secret goes in hex, so no
+// character of it needs quoting.
+func keyringSet(service, account, secret string) error {
+	args := []string{"add-generic-password", "-U", "-s", service, "-a", account, "-X", hex.EncodeToString([]byte(secret))}
+	for i, arg := range args {
+		args[i] = securityQuote(arg)
+	}
+
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(strings.Join(args, " ") + "\n")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// securityQuote quotes an argument for the command line of `security -i`, which only knows
+// double quotes and backslash escapes
+func securityQuote(arg string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range arg {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// keyringDelete removes a secret from the login keychain
+func keyringDelete(service, account string) error {
+	out, err := exec.Command("security", "delete-generic-password", "-s", service, "-a", account).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "could not be found") {
+			return errKeyringNotFound
+		}
+		return fmt.Errorf("security: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
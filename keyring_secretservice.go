@@ -0,0 +1,50 @@
+//go:build !darwin && !windows
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringGet reads a secret from the Secret Service (GNOME Keyring, KWallet) with secret-tool
+func keyringGet(service, account string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			// secret-tool fails silently when nothing matches
+			return "", errKeyringNotFound
+		}
+		return "", fmt.Errorf("secret-tool: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// keyringSet stores a secret in the Secret Service; secret-tool reads it from stdin
+func keyringSet(service, account, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", service+"/"+account, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// keyringDelete removes a secret from the Secret Service
+func keyringDelete(service, account string) error {
+	if _, err := keyringGet(service, account); err != nil {
+		return err
+	}
+	out, err := exec.Command("secret-tool", "clear", "service", service, "account", account).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("secret-tool: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
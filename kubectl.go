@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Engines establishing the port-forwards of a Kubernetes cluster
+const (
+	engineNative  = "native"  // client-go's SPDY client, in-process (default)
+	engineKubectl = "kubectl" // a `kubectl port-forward` process per forward
+)
+
+// kubectlReadyTimeout bounds waiting for `kubectl port-forward` to listen
+const kubectlReadyTimeout = 30 * time.Second
+
+// kubectlForwarding matches the line `kubectl port-forward` prints once it listens
+var kubectlForwarding = regexp.MustCompile(`^Forwarding from \S+:(\d+) -> `)
+
+// establishKubectlForward forwards to a pod through `kubectl port-forward`, listening on a
+// random internal port that the forward's local port is relayed to, as behind nanoporter's
+// proxy. kubectl exiting counts as a lost connection.
+func (m *PortForwardManager) establishKubectlForward(pf *PortForward, podName string, remotePort int, release func()) error {
+	ctx, cancel := context.WithCancel(pf.ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "kubectl", kubectlArgs(pf, podName, remotePort)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create kubectl output pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create kubectl error pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start kubectl: %w", err)
+	}
+
+	ready := make(chan int, 1)
+	var mu sync.Mutex
+	var lastError string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		scanKubectlOutput(pf, stdout, func(line string) {
+			if match := kubectlForwarding.FindStringSubmatch(line); match != nil {
+				port, _ := strconv.Atoi(match[1])
+				select {
+				case ready <- port:
+				default:
+				}
+			}
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		scanKubectlOutput(pf, stderr, func(line string) {
+			mu.Lock()
+			lastError = line
+			mu.Unlock()
+		})
+	}()
+
+	exited := make(chan error, 1)
+	go func() {
+		// Wait may only be called once the output was read
+		wg.Wait()
+		err := cmd.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		if lastError != "" {
+			err = fmt.Errorf("kubectl port-forward exited: %s", lastError)
+		} else {
+			err = fmt.Errorf("kubectl port-forward exited: %w", err)
+		}
+		exited <- err
+	}()
+
+	var port int
+	select {
+	case port = <-ready:
+	case err := <-exited:
+		return err
+	case <-pf.ctx.Done():
+		<-exited
+		return nil
+	case <-time.After(kubectlReadyTimeout):
+		cancel()
+		<-exited
+		return fmt.Errorf("timeout waiting for kubectl port-forward to be ready")
+	}
+
+	pf.mu.Lock()
+	pf.viaProxy = true
+	pf.mu.Unlock()
+	listener, err := m.proxyListener(pf, net.JoinHostPort(defaultBindAddress, strconv.Itoa(port)))
+	if err != nil {
+		cancel()
+		<-exited
+		return err
+	}
+	defer listener.Close()
+
+	release()
+	pf.cluster.SetAuthExpired(false)
+	m.forwardReady(pf)
+
+	select {
+	case err := <-exited:
+		return err
+	case <-pf.ctx.Done():
+		<-exited
+		return nil
+	}
+}
+
+// kubectlArgs returns the arguments of `kubectl port-forward` to a forward's pod, on the
+// cluster's kubeconfig and context
+func kubectlArgs(pf *PortForward, podName string, remotePort int) []string {
+	var args []string
+	if pf.cluster.config.Kubeconfig != "" {
+		args = append(args, "--kubeconfig", pf.cluster.config.Kubeconfig)
+	}
+	if kubeContext := pf.cluster.Context(); kubeContext != "" {
+		args = append(args, "--context", kubeContext)
+	}
+	return append(args, "-n", pf.Config.Namespace, "port-forward", "pod/"+podName,
+		"--address", defaultBindAddress, fmt.Sprintf("0:%d", remotePort))
+}
+
+// scanKubectlOutput logs the lines of kubectl's output and hands each one to fn until the
+// output is closed
+func scanKubectlOutput(pf *PortForward, output io.Reader, fn func(line string)) {
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		slog.Debug("kubectl port-forward",
+			"cluster", pf.ClusterName,
+			"service", pf.Config.Service,
+			"output", line,
+		)
+		fn(line)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// kubeconfigPollInterval is how often kubeconfig files are checked for changes
+const kubeconfigPollInterval = 2 * time.Second
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// KubeconfigWatcher reloads clusters whose kubeconfig entries change on disk (e.g. after
+// `aws eks update-kubeconfig` or `tsh kube login`) and re-establishes their forwards
+type KubeconfigWatcher struct {
+	config       *Config
+	manager      *PortForwardManager
+	stamps       map[string]fileStamp // kubeconfig file -> last seen version
+	fingerprints map[string]string    // cluster name -> its kubeconfig entries
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+// NewKubeconfigWatcher creates a watcher for the kubeconfig files of all clusters
+func NewKubeconfigWatcher(config *Config, manager *PortForwardManager) *KubeconfigWatcher {
+	return &KubeconfigWatcher{
+		config:       config,
+		manager:      manager,
+		stamps:       make(map[string]fileStamp),
+		fingerprints: make(map[string]string),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start records the current kubeconfig files and checks them for changes periodically
+func (w *KubeconfigWatcher) Start() {
+	w.filesChanged()
+	for _, cluster := range w.config.Clusters {
+		if cluster.usesKubernetes() {
+			w.fingerprints[cluster.Name], _ = kubeContextFingerprint(cluster)
+		}
+	}
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(kubeconfigPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if w.filesChanged() {
+					w.reloadChangedClusters()
+				}
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops watching
+func (w *KubeconfigWatcher) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+// filesChanged records the version of every kubeconfig file and reports whether any
+// was written, created or removed since the last call
+func (w *KubeconfigWatcher) filesChanged() bool {
+	changed := false
+	for _, cluster := range w.config.Clusters {
+		if !cluster.usesKubernetes() {
+			continue
+		}
+		for _, path := range kubeconfigLoadingRules(cluster.Kubeconfig).GetLoadingPrecedence() {
+			var stamp fileStamp
+			if info, err := os.Stat(path); err == nil {
+				stamp = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			}
+
+			if previous, ok := w.stamps[path]; ok && previous != stamp {
+				changed = true
+			}
+			w.stamps[path] = stamp
+		}
+	}
+	return changed
+}
+
+// reloadChangedClusters reloads the clusters whose context, cluster or user entry changed
+func (w *KubeconfigWatcher) reloadChangedClusters() {
+	for _, cluster := range w.config.Clusters {
+		if !cluster.usesKubernetes() {
+			continue
+		}
+		fingerprint, err := kubeContextFingerprint(cluster)
+		if err != nil || fingerprint == w.fingerprints[cluster.Name] {
+			// Half-written files are picked up by the next change
+			continue
+		}
+		w.fingerprints[cluster.Name] = fingerprint
+
+		clusterClient := w.manager.ClusterClient(cluster.Name)
+		if clusterClient == nil {
+			continue
+		}
+
+		slog.Info("Kubeconfig changed, reloading cluster", "cluster", cluster.Name)
+		err = clusterClient.Reload()
+		audit("reload_kubeconfig", auditSourceWatcher, err, "cluster", cluster.Name)
+		if err != nil {
+			slog.Warn("Failed to reload cluster", "cluster", cluster.Name, "error", err)
+			continue
+		}
+
+		w.manager.ReconnectCluster(cluster.Name)
+	}
+}
+
+// kubeContextFingerprint returns the kubeconfig entries a cluster uses (its context and
+// the cluster and user it references) in a comparable form
+func kubeContextFingerprint(cluster ClusterConfig) (string, error) {
+	rawConfig, err := kubeconfigLoadingRules(cluster.Kubeconfig).Load()
+	if err != nil {
+		return "", err
+	}
+
+	contextName := cluster.Context
+	if contextName == "" {
+		contextName = rawConfig.CurrentContext
+	}
+	kubeContext, ok := rawConfig.Contexts[contextName]
+	if !ok {
+		return "", fmt.Errorf("context '%s' not found", contextName)
+	}
+
+	entries := struct {
+		Context  any `json:"context"`
+		Cluster  any `json:"cluster"`
+		AuthInfo any `json:"user"`
+	}{kubeContext, rawConfig.Clusters[kubeContext.Cluster], rawConfig.AuthInfos[kubeContext.AuthInfo]}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
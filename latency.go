@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+)
+
+// latencyWindow is how many latency samples the rolling average covers
+const latencyWindow = 10
+
+// pingTimeout bounds a protocol ping through a forward
+const pingTimeout = 5 * time.Second
+
+// Protocols a forward can be pinged with
+const (
+	pingPostgres = "postgres"
+	pingMySQL    = "mysql"
+	pingRedis    = "redis"
+	pingHTTP     = "http"
+	pingHTTPS    = "https"
+)
+
+// pingProtocol returns the protocol the health check pings a forward with, from its
+// backup engine, scheme or well-known remote port. Empty when the protocol isn't known.
+func pingProtocol(cfg ForwardConfig) string {
+	if cfg.DBBackup != nil {
+		switch cfg.DBBackup.Type {
+		case "", "postgres":
+			return pingPostgres
+		}
+		return ""
+	}
+	if cfg.LocalTLS != nil {
+		// The scheme is the one of the local endpoint; the target speaks plain HTTP
+		if cfg.Scheme != "" {
+			return pingHTTP
+		}
+	} else if cfg.Scheme != "" {
+		return cfg.Scheme
+	}
+
+	switch cfg.RemotePort {
+	case 5432:
+		return pingPostgres
+	case 3306:
+		return pingMySQL
+	case 6379:
+		return pingRedis
+	case 80, 8080:
+		return pingHTTP
+	case 443, 8443:
+		return pingHTTPS
+	}
+	return ""
+}
+
+// ping sends a request of the protocol over a connection to a forward and waits for the
+// first byte of the answer, which has to come from the remote end of the tunnel. Returns
+// the round-trip time.
+func ping(conn net.Conn, protocol string) (time.Duration, error) {
+	conn.SetDeadline(time.Now().Add(pingTimeout))
+	start := time.Now()
+
+	var request []byte
+	switch protocol {
+	case pingPostgres:
+		// SSLRequest, answered with a single 'S' or 'N'
+		request = []byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}
+	case pingMySQL:
+		// The server greets first
+	case pingRedis:
+		request = []byte("PING\r\n")
+	case pingHTTP:
+		request = []byte("HEAD / HTTP/1.0\r\nHost: localhost\r\n\r\n")
+	case pingHTTPS:
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		defer cancel()
+		// Only the handshake is timed; the certificate is the service's business
+		client := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+		if err := client.HandshakeContext(ctx); err != nil {
+			return 0, fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		return time.Since(start), nil
+	default:
+		return 0, fmt.Errorf("unknown ping protocol '%s'", protocol)
+	}
+
+	if len(request) > 0 {
+		if _, err := conn.Write(request); err != nil {
+			return 0, fmt.Errorf("failed to send ping: %w", err)
+		}
+	}
+	if _, err := bufio.NewReader(conn).ReadByte(); err != nil {
+		return 0, fmt.Errorf("no answer to ping: %w", err)
+	}
+	return time.Since(start), nil
+}
+
+// recordLatency adds a latency sample to the rolling window of the forward
+func (pf *PortForward) recordLatency(latency time.Duration) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	pf.latencies = append(pf.latencies, latency)
+	if len(pf.latencies) > latencyWindow {
+		pf.latencies = pf.latencies[len(pf.latencies)-latencyWindow:]
+	}
+}
+
+// latency returns the latest latency sample and the average of the window (caller holds pf.mu)
+func (pf *PortForward) latency() (last, avg time.Duration) {
+	if len(pf.latencies) == 0 {
+		return 0, 0
+	}
+	var total time.Duration
+	for _, l := range pf.latencies {
+		total += l
+	}
+	return pf.latencies[len(pf.latencies)-1], total / time.Duration(len(pf.latencies))
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// formatLatency formats a latency given in milliseconds
+func formatLatency(ms float64) string {
+	if ms < 10 {
+		return fmt.Sprintf("%.1fms", ms)
+	}
+	return fmt.Sprintf("%.0fms", ms)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// selfSignedValidity is how long a generated certificate is valid; it's renewed a week before
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// localTLSConfig returns the TLS settings of a forward's local listener, nil without local_tls.
+// The certificate is loaded on every connection attempt, so a renewed one is picked up on
+// the next reconnect.
+func localTLSConfig(pf *PortForward) (*tls.Config, error) {
+	settings := pf.Config.LocalTLS
+	if settings == nil {
+		return nil, nil
+	}
+
+	certFile, keyFile := settings.Cert, settings.Key
+	if settings.Auto {
+		var err error
+		certFile, keyFile, err = selfSignedCert(pf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load local_tls certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// selfSignedCert returns the files of the forward's self-signed certificate, generating it
+// when there is none yet or it's about to expire. Certificates are kept in the user cache
+// directory, so browsers only need to accept them once.
+func selfSignedCert(pf *PortForward) (certFile, keyFile string, err error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find the cache directory: %w", err)
+	}
+	dir := filepath.Join(cacheDir, "nanoporter", "local-tls")
+
+	name := pf.Config.Name
+	if name == "" {
+		name = pf.Config.Service
+	}
+	base := filepath.Join(dir, forwardFileName(pf))
+	certFile, keyFile = base+".crt", base+".key"
+
+	hosts := append([]string{"localhost", "127.0.0.1", "::1", pf.LocalAddress()}, pf.Config.Hostnames...)
+	if certCovers(certFile, hosts) {
+		return certFile, keyFile, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"nanoporter"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(selfSignedValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write key: %w", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	slog.Info("Generated self-signed certificate for local TLS",
+		"cluster", pf.ClusterName,
+		"service", pf.Config.Service,
+		"cert", certFile,
+		"hosts", strings.Join(hosts, ","),
+	)
+	return certFile, keyFile, nil
+}
+
+// certCovers reports whether a certificate file exists, is valid for another week and
+// names all hosts
+func certCovers(certFile string, hosts []string) bool {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return false
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil || time.Until(cert.NotAfter) < 7*24*time.Hour {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// forwardFileName returns a name for files belonging to a forward, from its cluster and
+// its name or service
+func forwardFileName(pf *PortForward) string {
+	name := pf.Config.Name
+	if name == "" {
+		name = pf.Config.Service
+	}
+	return strings.ToLower(envName(pf.ClusterName + "-" + name))
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLogBufferSize is how many log records the running instance keeps for `nanoporter logs`
+const defaultLogBufferSize = 2000
+
+// LogEntry is a log record kept in memory
+type LogEntry struct {
+	Seq       int64     `json:"seq"`
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+	Attrs     string    `json:"attrs,omitempty"` // key=value pairs
+	Cluster   string    `json:"cluster,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Service   string    `json:"service,omitempty"`
+}
+
+// String formats the entry like the text log handler
+func (e LogEntry) String() string {
+	line := fmt.Sprintf("%s %-5s %s", e.Time.Format("2006-01-02T15:04:05.000"), e.Level, e.Message)
+	if e.Attrs != "" {
+		line += " " + e.Attrs
+	}
+	return line
+}
+
+// matchesForward reports whether the entry concerns a "cluster/namespace/service" forward.
+// Cluster-wide entries (e.g. credential refreshes) match every forward of the cluster.
+func (e LogEntry) matchesForward(filter string) bool {
+	if filter == "" {
+		return true
+	}
+	if e.Cluster == "" {
+		return false
+	}
+
+	key := e.Cluster
+	for _, part := range []string{e.Namespace, e.Service} {
+		if part != "" {
+			key += "/" + part
+		}
+	}
+
+	return key == filter ||
+		strings.HasPrefix(key, filter+"/") ||
+		(e.Service == "" && strings.HasPrefix(filter, key+"/"))
+}
+
+// logBuffer is a fixed-size ring of recent log entries
+type logBuffer struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	size    int
+	next    int64 // sequence number of the next entry
+}
+
+// newLogBuffer creates a buffer keeping the last size entries
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{size: size}
+}
+
+// add appends an entry, dropping the oldest when full
+func (b *logBuffer) add(entry LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry.Seq = b.next
+	b.next++
+
+	if len(b.entries) >= b.size {
+		copy(b.entries, b.entries[1:])
+		b.entries = b.entries[:len(b.entries)-1]
+	}
+	b.entries = append(b.entries, entry)
+}
+
+// since returns up to limit of the newest entries with a sequence number >= seq that match
+// the forward filter, and the sequence number to continue from
+func (b *logBuffer) since(seq int64, filter string, limit int) ([]LogEntry, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var result []LogEntry
+	for _, entry := range b.entries {
+		if entry.Seq >= seq && entry.matchesForward(filter) {
+			result = append(result, entry)
+		}
+	}
+
+	if limit > 0 && len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+
+	return result, b.next
+}
+
+// bufferHandler is a slog handler that passes records on and keeps a copy in a logBuffer
+type bufferHandler struct {
+	next   slog.Handler
+	buffer *logBuffer
+	attrs  []slog.Attr
+}
+
+// newBufferHandler wraps a handler so its records are also kept in buffer
+func newBufferHandler(next slog.Handler, buffer *logBuffer) *bufferHandler {
+	return &bufferHandler{next: next, buffer: buffer}
+}
+
+// Enabled reports whether the wrapped handler handles records at the level
+func (h *bufferHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle keeps the record and passes it to the wrapped handler
+func (h *bufferHandler) Handle(ctx context.Context, r slog.Record) error {
+	entry := LogEntry{
+		Time:    r.Time,
+		Level:   r.Level.String(),
+		Message: r.Message,
+	}
+
+	var attrs []string
+	addAttr := func(a slog.Attr) bool {
+		value := a.Value.Resolve().String()
+		switch a.Key {
+		case "cluster":
+			entry.Cluster = value
+		case "namespace":
+			entry.Namespace = value
+		case "service":
+			entry.Service = value
+		}
+		if strings.ContainsAny(value, " \"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		attrs = append(attrs, a.Key+"="+value)
+		return true
+	}
+	for _, a := range h.attrs {
+		addAttr(a)
+	}
+	r.Attrs(addAttr)
+	entry.Attrs = strings.Join(attrs, " ")
+
+	h.buffer.add(entry)
+
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler adding attrs to every record
+func (h *bufferHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &bufferHandler{
+		next:   h.next.WithAttrs(attrs),
+		buffer: h.buffer,
+		attrs:  append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...),
+	}
+}
+
+// WithGroup returns a handler nesting attrs in a group
+func (h *bufferHandler) WithGroup(name string) slog.Handler {
+	return &bufferHandler{next: h.next.WithGroup(name), buffer: h.buffer, attrs: h.attrs}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// logsParams are the parameters of the "logs" control command
+type logsParams struct {
+	Since   int64  `json:"since"`
+	Forward string `json:"forward,omitempty"`
+	Limit   int    `json:"limit,omitempty"`
+}
+
+// logsResult is the result of the "logs" control command
+type logsResult struct {
+	Entries []LogEntry `json:"entries"`
+	Next    int64      `json:"next"`
+}
+
+// runLogsCommand prints log lines of the running instance, optionally following new ones
+func runLogsCommand() {
+	logsFlags := flag.NewFlagSet("logs", flag.ExitOnError)
+	configPath := logsFlags.String("config", defaultConfigPath, "Path to configuration file")
+	socketPath := logsFlags.String("socket", "", "Control socket of the running instance (default: from config)")
+	follow := logsFlags.Bool("follow", false, "Keep printing new log lines")
+	forward := logsFlags.String("forward", "", "Only show lines for a forward (cluster/namespace/service)")
+	lines := logsFlags.Int("lines", 100, "Number of recent lines to print")
+	logsFlags.Parse(os.Args[2:])
+
+	socket := *socketPath
+	if socket == "" {
+		socket = controlSocketFromConfig(*configPath)
+	}
+
+	client, err := DialControl(socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: no running instance on %s: %v\n", socket, err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	params := logsParams{Forward: *forward, Limit: *lines}
+	for {
+		var result logsResult
+		if err := client.Call("logs", params, &result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		for _, entry := range result.Entries {
+			fmt.Println(entry.String())
+		}
+
+		if !*follow {
+			return
+		}
+
+		// Only the initial batch is limited
+		params.Since = result.Next
+		params.Limit = 0
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// handleLogs returns the control handler serving buffered log lines
+func handleLogs(buffer *logBuffer) ControlHandler {
+	return func(params json.RawMessage) (any, error) {
+		var req logsParams
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, fmt.Errorf("invalid params: %w", err)
+			}
+		}
+
+		entries, next := buffer.since(req.Since, req.Forward, req.Limit)
+		return logsResult{Entries: entries, Next: next}, nil
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+)
+
+// CheckBindAddresses verifies that every cluster bind_address can be bound locally.
+// On macOS only 127.0.0.1 exists by default, so missing aliases are reported with
+// the commands needed to create them.
+func CheckBindAddresses(config *Config) error {
+	var missing []string
+	seen := make(map[string]bool)
+
+	for _, cluster := range config.Clusters {
+		if cluster.BindAddress == "" || seen[cluster.BindAddress] {
+			continue
+		}
+		seen[cluster.BindAddress] = true
+
+		listener, err := net.Listen("tcp", net.JoinHostPort(cluster.BindAddress, "0"))
+		if err != nil {
+			missing = append(missing, cluster.BindAddress)
+			continue
+		}
+		listener.Close()
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("loopback address(es) %s not available:\n%s",
+		strings.Join(missing, ", "), loopbackAliasInstructions(missing))
+}
+
+// loopbackAliasInstructions returns the commands that create loopback aliases on this OS
+func loopbackAliasInstructions(addresses []string) string {
+	var b strings.Builder
+	for _, addr := range addresses {
+		switch runtime.GOOS {
+		case "darwin":
+			fmt.Fprintf(&b, "  sudo ifconfig lo0 alias %s up\n", addr)
+		case "linux":
+			fmt.Fprintf(&b, "  sudo ip addr add %s/8 dev lo\n", addr)
+		default:
+			fmt.Fprintf(&b, "  add %s as an alias of the loopback interface\n", addr)
+		}
+	}
+	return b.String()
+}
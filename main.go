@@ -1,13 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"k8s.io/klog/v2"
@@ -21,10 +24,61 @@ func main() {
 	// Suppress Kubernetes client-go klog output immediately
 	klog.SetOutput(io.Discard)
 
-	// Check if backup command is requested
-	if len(os.Args) > 1 && os.Args[1] == "backup" {
-		runBackupCommand()
-		return
+	// Check if a subcommand is requested
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backup":
+			runBackupCommand()
+			return
+		case "restore":
+			runRestoreCommand()
+			return
+		case "add":
+			runAddCommand()
+			return
+		case "remove":
+			runRemoveCommand()
+			return
+		case "status":
+			runStatusCommand()
+			return
+		case "logs":
+			runLogsCommand()
+			return
+		case "doctor":
+			runDoctorCommand()
+			return
+		case "retry":
+			runRetryCommand()
+			return
+		case "enable", "disable":
+			runClusterCommand(os.Args[1])
+			return
+		case "pause", "resume":
+			runPauseCommand(os.Args[1])
+			return
+		case "run":
+			runRunCommand()
+			return
+		case "forward":
+			runForwardCommand()
+			return
+		case "export":
+			runExportCommand()
+			return
+		case "secret":
+			runSecretCommand()
+			return
+		case "service":
+			runServiceCommand()
+			return
+		case "self-update":
+			runSelfUpdateCommand()
+			return
+		case "version":
+			runVersionCommand()
+			return
+		}
 	}
 
 	// Initialize klog flags but don't parse them (we use our own flags)
@@ -40,7 +94,12 @@ func main() {
 	configPath := flag.String("config", defaultConfigPath, "Path to configuration file")
 	verbose := flag.Bool("verbose", false, "Enable verbose logging")
 	logFile := flag.String("log", "", "Log file path (default: stderr, or porter.log if TUI active)")
+	takeover := flag.Bool("takeover", false, "Take over ports held by other nanoporter instances")
+	forceFreePorts := flag.Bool("force-free-ports", false, "Offer to terminate non-nanoporter processes holding configured ports")
+	plainFlag := flag.Bool("plain", false, "Print state changes instead of showing the TUI (default when stdout isn't a terminal)")
+	service := flag.Bool("service", false, "Run as a service: print state changes and log to stderr for the journal")
 	flag.Parse()
+	plain := *service || usePlainOutput(*plainFlag)
 
 	// Setup logging
 	logLevel := slog.LevelInfo
@@ -61,6 +120,9 @@ func main() {
 		}
 		logOutput = f
 		closeLog = true
+	} else if *service {
+		// The service manager collects stderr
+		logOutput = os.Stderr
 	} else {
 		// Default to nanoporter.log to avoid interfering with TUI
 		f, err := os.OpenFile("nanoporter.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -73,9 +135,11 @@ func main() {
 		}
 	}
 
-	logger := slog.New(slog.NewTextHandler(logOutput, &slog.HandlerOptions{
+	// Keep recent log lines in memory for `nanoporter logs`
+	logs := newLogBuffer(defaultLogBufferSize)
+	logger := slog.New(newBufferHandler(slog.NewTextHandler(logOutput, &slog.HandlerOptions{
 		Level: logLevel,
-	}))
+	}), logs))
 	slog.SetDefault(logger)
 
 	if closeLog {
@@ -100,6 +164,24 @@ func main() {
 		"reconnect_delay", config.ReconnectDelay,
 	)
 
+	// Record who restarts, pauses or takes over what
+	if config.AuditLog != "" {
+		auditFile, err := OpenAuditLog(config.AuditLog)
+		if err != nil {
+			slog.Error("Failed to open audit log", "error", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer auditFile.Close()
+	}
+
+	// Make sure per-cluster loopback aliases exist
+	if err := CheckBindAddresses(config); err != nil {
+		slog.Error("Loopback aliases missing", "error", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Count total forwards
 	totalForwards := 0
 	for _, cluster := range config.Clusters {
@@ -107,14 +189,6 @@ func main() {
 	}
 	slog.Info("Total port-forwards configured", "count", totalForwards)
 
-	// Check for and kill conflicting Porter instances
-	slog.Info("Checking for port conflicts")
-	if err := CheckAndKillConflictingPorts(config); err != nil {
-		slog.Error("Failed to resolve port conflicts", "error", err)
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-
 	// Create port-forward manager
 	manager := NewPortForwardManager(config)
 
@@ -126,58 +200,260 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Continue the statistics of the previous run
+	if err := manager.RestoreRuntimeState(config.StateFile); err != nil {
+		slog.Warn("Failed to restore forward statistics", "error", err)
+	}
+
+	// Keep the endpoints file in sync with active forwards
+	if config.EnvFile != nil {
+		envFile, err := NewEnvFileWriter(config.EnvFile, manager)
+		if err != nil {
+			slog.Error("Failed to set up env_file", "error", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		envFile.Write()
+	}
+
+	// Export the status of all forwards for status lines and scripts
+	if config.StatusFile != "" {
+		NewStatusFileWriter(config.StatusFile, manager).Write()
+	}
+
+	// Run companion commands while their forwards are active
+	companions := NewCompanionSupervisor(manager)
+
+	// Check for conflicting Porter instances, taking them over only if requested
+	slog.Info("Checking for port conflicts", "takeover", *takeover, "force_free_ports", *forceFreePorts)
+	policy := ConflictPolicy{
+		Takeover:     *takeover,
+		ForceFree:    *forceFreePorts,
+		KillTimeout:  config.KillTimeout,
+		KillEscalate: config.KillEscalate,
+	}
+	if err := ResolvePortConflicts(config, manager, policy); err != nil {
+		slog.Error("Failed to resolve port conflicts", "error", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Map forward hostnames to loopback in the hosts file. After the handover, so an instance
+	// taken over has released the DNS listener and cleaned up its hosts entries.
+	if config.HostsFile != nil {
+		hosts := NewHostsManager(config)
+		if err := hosts.Apply(); err != nil {
+			slog.Error("Failed to update hosts file", "error", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := hosts.Cleanup(); err != nil {
+				slog.Warn("Failed to clean up hosts file", "error", err)
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}()
+	}
+
+	// Resolve forwarded service names locally
+	if config.DNS != nil {
+		dns := NewDNSServer(config)
+		err := dns.Start()
+		// A previous instance taken over may still be shutting down
+		for deadline := time.Now().Add(handoverShutdownTimeout); err != nil && *takeover && time.Now().Before(deadline); {
+			time.Sleep(250 * time.Millisecond)
+			err = dns.Start()
+		}
+		if err != nil {
+			slog.Error("Failed to start DNS resolver", "error", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer dns.Stop()
+	}
+
+	// Without the TUI, state changes are printed; subscribe before they start
+	var events <-chan Event
+	if plain {
+		var unsubscribe func()
+		events, unsubscribe = manager.Subscribe(100)
+		defer unsubscribe()
+		printForwards(manager)
+	}
+
 	// Start port-forwards and monitoring
 	slog.Info("Starting port-forwards")
 	manager.Start()
 
+	// Pick up rewritten kubeconfig files
+	kubeconfigWatcher := NewKubeconfigWatcher(config, manager)
+	kubeconfigWatcher.Start()
+	defer kubeconfigWatcher.Stop()
+
+	// Create forwards for annotated services
+	if config.Discovery != nil {
+		discoverer := NewDiscoverer(config, manager)
+		discoverer.Start()
+		defer discoverer.Stop()
+	}
+
+	// Follow the pods of fan_out forwards
+	if hasFanOut(config) {
+		fanOut := NewFanOut(config, manager)
+		fanOut.Start()
+		defer fanOut.Stop()
+	}
+
 	// Start database backups in background
-	go func() {
-		// Count databases to backup
-		dbCount := 0
-		for _, cluster := range config.Clusters {
-			for _, forward := range cluster.Forwards {
-				if forward.DBBackup != nil {
-					dbCount++
-				}
+	dbCount := 0
+	for _, cluster := range config.Clusters {
+		for _, forward := range cluster.Forwards {
+			if forward.DBBackup != nil {
+				dbCount++
 			}
 		}
+	}
+	var backupManager *BackupManager
+	if dbCount > 0 {
+		slog.Info("Initializing database backups", "count", dbCount)
 
-		if dbCount > 0 {
-			slog.Info("Initializing database backups", "count", dbCount)
-
-			// Create backup manager
-			backupManager, err := NewBackupManager(config, "backups")
-			if err != nil {
-				slog.Error("Failed to initialize backup manager", "error", err)
-				return
-			}
+		backupManager, err = NewBackupManager(config, defaultBackupDir)
+		if err != nil {
+			slog.Error("Failed to initialize backup manager", "error", err)
+			go pingBackupResult(config.BackupPing, nil, err)
+		}
+	}
+	if backupManager != nil {
+		go func() {
+			// Show the previous backups until the new ones finish
+			backupManager.RestoreState(manager)
 
 			// Run backups
-			if err := backupManager.BackupAllDatabases(manager); err != nil {
+			pingBackupStart(config.BackupPing)
+			err := backupManager.BackupAllDatabases(manager)
+			if err != nil {
 				slog.Warn("Backup process completed with errors", "error", err)
 			} else {
 				slog.Info("All database backups completed successfully")
 			}
-		}
-	}()
+			pingBackupResult(config.BackupPing, manager, err)
+
+			// Prune old backups periodically, also when backups fail
+			backupManager.CleanupAll()
+			ticker := time.NewTicker(backupCleanupInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				backupManager.CleanupAll()
+			}
+		}()
+	}
 
 	// Setup signal handler for graceful shutdown
+	quit := make(chan struct{})
+	closeQuit := sync.OnceFunc(func() { close(quit) })
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-sigChan
+		sig := <-sigChan
 		slog.Info("Received shutdown signal")
+		audit("shutdown", auditSourceSignal, nil, "signal", sig.String())
 		manager.Stop()
+		closeQuit()
 	}()
 
 	// Start TUI
-	slog.Info("Starting TUI")
-	model := NewTUIModel(manager)
+	model := NewTUIModel(manager, backupManager, *configPath)
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
-	if _, err := p.Run(); err != nil {
-		slog.Error("TUI error", "error", err)
-		manager.Stop()
+	// Start control socket so future instances can hand over gracefully
+	control := NewControlServer(config.ControlSocket)
+	if err := control.AllowPeers(config.ControlUsers, config.ControlGroups); err != nil {
+		slog.Error("Invalid control socket users", "error", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	control.Handle("release", func(params json.RawMessage) (any, error) {
+		var req releaseParams
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+		return nil, manager.ReleasePort(req.Address, req.Port, 10*time.Second)
+	})
+	control.Handle("status", func(params json.RawMessage) (any, error) {
+		return manager.Snapshot(), nil
+	})
+	control.Handle("logs", handleLogs(logs))
+	control.Handle("add", handleAddForward(config, manager))
+	control.Handle("remove", handleRemoveForward(manager))
+	control.Handle("retry", handleRetry(manager))
+	control.Handle("enable", handleCluster(manager.EnableCluster))
+	control.Handle("disable", handleCluster(manager.DisableCluster))
+	control.Handle("backup", handleBackup(config, manager, backupManager))
+	control.Handle("pause", handlePause(manager.PauseAll))
+	control.Handle("resume", handlePause(manager.ResumeAll))
+	control.Handle("shutdown", func(params json.RawMessage) (any, error) {
+		slog.Info("Shutdown requested via control socket")
+		go func() {
+			manager.Stop()
+			closeQuit()
+			if !plain {
+				p.Quit()
+			}
+		}()
+		return nil, nil
+	})
+	// Tell about newer releases, without holding up the start
+	if config.UpdateCheck {
+		go func() {
+			notice := updateNotice()
+			if notice == "" {
+				return
+			}
+			slog.Info("Update available", "notice", notice)
+			if plain {
+				fmt.Println(notice)
+			} else {
+				p.Send(noticeMsg(notice))
+			}
+		}()
+	}
+
+	if err := control.Start(); err != nil {
+		slog.Warn("Control socket unavailable", "error", err)
+	} else {
+		defer control.Stop()
+	}
+
+	// Serve the HTTP API for automation that can't reach the control socket
+	if config.API != nil {
+		api, err := NewAPIServer(config, manager, backupManager)
+		if err == nil {
+			err = api.Start()
+		}
+		if err != nil {
+			slog.Warn("API unavailable", "error", err)
+		} else {
+			defer api.Stop()
+		}
+	}
+
+	if plain {
+		slog.Info("Printing state changes instead of starting the TUI")
+		printEvents(events, quit)
+		err = nil
+	} else {
+		slog.Info("Starting TUI")
+		_, err = p.Run()
+		if err != nil {
+			slog.Error("TUI error", "error", err)
+			manager.Stop()
+		}
+	}
+	companions.StopAll()
+	if err := manager.SaveRuntimeState(config.StateFile); err != nil {
+		slog.Warn("Failed to save forward statistics", "error", err)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// mssqlBackupSuffix ends the names of SQL Server backup files
+	mssqlBackupSuffix = ".bak"
+	// defaultMSSQLBackupDir is where SQL Server containers write backups by default
+	defaultMSSQLBackupDir = "/var/opt/mssql/backup"
+)
+
+// BackupMSSQLDatabase backs up a SQL Server database. sqlcmd runs BACKUP DATABASE through
+// the forward into a file inside the database's container, which is streamed out with exec
+// into the backup directory and then removed from the container.
+func (m *BackupManager) BackupMSSQLDatabase(dbName string, conn *PortForward, creds *DBCredentials, pf *PortForward) (*BackupResult, error) {
+	started := time.Now()
+	timestamp := started.Format("2006-01-02_15-04-05")
+	dbBackupDir := m.databaseBackupDir(dbName, pf)
+
+	if err := os.MkdirAll(dbBackupDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database backup directory: %w", err)
+	}
+	if creds.Database == "" {
+		return nil, fmt.Errorf("no database name in the credentials or db_backup.database")
+	}
+
+	cfg := pf.Config.DBBackup
+	serverDir, container := defaultMSSQLBackupDir, ""
+	if cfg.MSSQL != nil {
+		if cfg.MSSQL.ServerBackupDir != "" {
+			serverDir = cfg.MSSQL.ServerBackupDir
+		}
+		container = cfg.MSSQL.Container
+	}
+
+	// The container runs Linux, whatever the local OS is
+	serverFile := path.Join(serverDir, fmt.Sprintf("nanoporter_%s_%s%s", dbName, timestamp, mssqlBackupSuffix))
+	gzFile := filepath.Join(dbBackupDir, fmt.Sprintf("%s_%s%s.gz", dbName, timestamp, mssqlBackupSuffix))
+	plainFile := ""
+	if cfg.KeepPlain {
+		plainFile = strings.TrimSuffix(gzFile, ".gz")
+	}
+
+	slog.Info("Starting database backup",
+		"database", dbName,
+		"file", gzFile,
+	)
+
+	// COPY_ONLY leaves the server's own backup chain alone
+	statement := fmt.Sprintf("BACKUP DATABASE %s TO DISK = N'%s' WITH COPY_ONLY, INIT",
+		quoteMSSQLName(creds.Database), strings.ReplaceAll(serverFile, "'", "''"))
+	cmd := exec.CommandContext(m.ctx, "sqlcmd",
+		"-S", fmt.Sprintf("%s,%d", conn.LocalAddress(), conn.LocalPort()),
+		"-U", creds.Username,
+		"-d", "master",
+		"-b", // exit with an error when the statement fails
+		"-C", // trust the server certificate, the connection goes through the forward anyway
+		"-Q", statement,
+	)
+	cmd.Env = append(os.Environ(), "SQLCMDPASSWORD="+creds.Password)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("sqlcmd failed: %w\nOutput: %s", err, string(output))
+	}
+
+	// Don't leave backups behind in the container, also when copying fails or is cancelled
+	defer func() {
+		if err := containerExec(context.Background(), conn, container, []string{"rm", "-f", serverFile}, io.Discard); err != nil {
+			slog.Warn("Failed to remove backup file from the container", "file", serverFile, "error", err)
+		}
+	}()
+
+	err := writeCompressed(func(w io.Writer) error {
+		return containerExec(m.ctx, conn, container, []string{"cat", serverFile}, w)
+	}, gzFile, plainFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy the backup out of the container: %w", err)
+	}
+
+	fileInfo, err := os.Stat(gzFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat backup file: %w", err)
+	}
+
+	sizeMB := float64(fileInfo.Size()) / (1024 * 1024)
+	duration := time.Since(started)
+
+	slog.Info("Database backup completed",
+		"database", dbName,
+		"file", gzFile,
+		"size_mb", sizeMB,
+		"duration", duration.Round(time.Second),
+	)
+
+	if err := m.cleanupOldBackups(dbBackupDir, backupMaxAge(cfg)); err != nil {
+		slog.Warn("Failed to cleanup old backups", "error", err)
+	}
+
+	return &BackupResult{File: gzFile, SizeMB: sizeMB, Duration: duration}, nil
+}
+
+// quoteMSSQLName quotes a SQL Server identifier
+func quoteMSSQLName(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// containerExec runs a command in the pod or docker container a forward is connected to,
+// writing its output to stdout, until ctx is canceled
+func containerExec(ctx context.Context, pf *PortForward, container string, command []string, stdout io.Writer) error {
+	target := pf.Status().Pod
+	if target == "" {
+		return fmt.Errorf("port forward %s isn't connected to a pod or container", pf.ID)
+	}
+
+	var stderr bytes.Buffer
+
+	if pf.Config.Type == "docker" {
+		cmd := exec.CommandContext(ctx, "docker", append([]string{"exec", target}, command...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+
+	restConfig, clientset := pf.cluster.Get()
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pf.Config.Namespace).
+		Name(target).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create exec request: %w", err)
+	}
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: stdout,
+		Stderr: &stderr,
+	}); err != nil {
+		return fmt.Errorf("exec in pod %s failed: %w: %s", target, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
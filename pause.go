@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// PauseAll stops every port-forward, freeing its local port, and keeps all of them from
+// reconnecting until ResumeAll. Cluster probes and service discovery pause too. Forwards of
+// disabled clusters stay disabled; counters and recent connections are kept.
+func (m *PortForwardManager) PauseAll() error {
+	m.mu.Lock()
+	if m.paused {
+		m.mu.Unlock()
+		return fmt.Errorf("port-forwards are already paused")
+	}
+	m.paused = true
+	var forwards []*PortForward
+	for _, pf := range m.forwards {
+		if !m.disabled[pf.ClusterName] {
+			forwards = append(forwards, pf)
+		}
+	}
+	m.mu.Unlock()
+
+	slog.Info("Pausing all port-forwards", "forwards", len(forwards))
+
+	var wg sync.WaitGroup
+	for _, pf := range forwards {
+		wg.Add(1)
+		go func(pf *PortForward) {
+			defer wg.Done()
+			m.suspendForward(pf, StatePaused)
+		}(pf)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// ResumeAll starts the port-forwards paused with PauseAll again
+func (m *PortForwardManager) ResumeAll() error {
+	m.mu.Lock()
+	if !m.paused {
+		m.mu.Unlock()
+		return fmt.Errorf("port-forwards are not paused")
+	}
+	m.paused = false
+	var forwards []*PortForward
+	for _, pf := range m.forwards {
+		if !m.disabled[pf.ClusterName] {
+			forwards = append(forwards, pf)
+		}
+	}
+	m.mu.Unlock()
+
+	slog.Info("Resuming all port-forwards", "forwards", len(forwards))
+
+	for _, pf := range forwards {
+		m.restartForward(pf)
+	}
+	m.probeClusters()
+
+	return nil
+}
+
+// Paused reports whether the port-forwards were paused with PauseAll
+func (m *PortForwardManager) Paused() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.paused
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runPauseCommand pauses or resumes all forwards of the running instance; command is
+// "pause" or "resume"
+func runPauseCommand(command string) {
+	pauseFlags := flag.NewFlagSet(command, flag.ExitOnError)
+	configPath := pauseFlags.String("config", defaultConfigPath, "Path to configuration file")
+	socketPath := pauseFlags.String("socket", "", "Control socket of the running instance (default: from config)")
+	pauseFlags.Parse(os.Args[2:])
+
+	socket := *socketPath
+	if socket == "" {
+		socket = controlSocketFromConfig(*configPath)
+	}
+
+	client, err := DialControl(socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: no running instance on %s: %v\n", socket, err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	if err := client.Call(command, nil, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if command == "pause" {
+		fmt.Println("Port-forwards paused")
+	} else {
+		fmt.Println("Port-forwards resumed")
+	}
+}
+
+// handlePause returns the control handler pausing or resuming all forwards
+func handlePause(apply func() error) ControlHandler {
+	return func(params json.RawMessage) (any, error) {
+		return nil, apply()
+	}
+}
@@ -0,0 +1,33 @@
+//go:build darwin
+
+package main
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerCredentials returns the user and process ID of a control connection's client, from
+// the socket's peer credentials
+func peerCredentials(conn net.Conn) (uid, pid int, ok bool) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, 0, false
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, 0, false
+	}
+
+	var cred *unix.Xucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		if cred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED); credErr == nil {
+			pid, credErr = unix.GetsockoptInt(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+		}
+	}); err != nil || credErr != nil {
+		return 0, 0, false
+	}
+	return int(cred.Uid), pid, true
+}
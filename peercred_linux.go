@@ -0,0 +1,31 @@
+//go:build linux
+
+package main
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerCredentials returns the user and process ID of a control connection's client, from
+// the socket's peer credentials
+func peerCredentials(conn net.Conn) (uid, pid int, ok bool) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, 0, false
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, 0, false
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil || credErr != nil {
+		return 0, 0, false
+	}
+	return int(cred.Uid), int(cred.Pid), true
+}
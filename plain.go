@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// usePlainOutput reports whether state changes are printed as lines instead of running the
+// TUI: with --plain, or when stdout isn't a terminal (nohup, CI, output piped to a file),
+// where the TUI's escape sequences would garble the output
+func usePlainOutput(plain bool) bool {
+	return plain || !term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// printForwards prints a line for every forward, before the forwards start
+func printForwards(manager *PortForwardManager) {
+	for _, fs := range manager.Snapshot() {
+		target := fs.Cluster + "/" + fs.Service
+		if fs.Namespace != "" {
+			target = fs.Cluster + "/" + fs.Namespace + "/" + fs.Service
+		}
+		fmt.Printf("Forwarding %s:%d -> %s:%s\n", fs.LocalAddress, fs.LocalPort, target, fs.RemotePortLabel())
+	}
+}
+
+// printEvents prints the state changes of the forwards as they happen, until done is closed
+func printEvents(events <-chan Event, done <-chan struct{}) {
+	for {
+		select {
+		case e := <-events:
+			if line := plainEventLine(e); line != "" {
+				fmt.Println(line)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// plainEventLine formats an event for plain output, "" for events that aren't printed
+func plainEventLine(e Event) string {
+	switch e.Type {
+	case EventStateChanged, EventPodSwitched, EventHealthCheckFailed, EventForwardAdded, EventForwardRemoved:
+	case EventBackupProgress:
+		if e.Forward.BackupState != BackupCompleted && e.Forward.BackupState != BackupFailed {
+			return ""
+		}
+	default:
+		return ""
+	}
+
+	line := e.Time.Local().Format("15:04:05") + " " + describeEvent(e)
+	switch {
+	case e.Type == EventBackupProgress && e.Forward.BackupState == BackupFailed:
+		line += ": " + e.Forward.BackupError
+	case e.Type == EventBackupProgress:
+		line += fmt.Sprintf(" (%.1f MB)", e.Forward.BackupSizeMB)
+	case e.Forward.State != StateActive && e.Forward.Error != "":
+		line += ": " + e.Forward.Error
+	case e.Forward.State == StateActive && e.Forward.Pod != "" && e.Type == EventStateChanged:
+		line += " via " + e.Forward.Pod
+	}
+	return line
+}
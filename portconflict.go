@@ -1,69 +1,311 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
-	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
-	"syscall"
+	"time"
 )
 
-// CheckAndKillConflictingPorts checks if any configured ports are in use by other nanoporter instances
-// and kills those instances
-func CheckAndKillConflictingPorts(config *Config) error {
-	portsToCheck := make(map[int]bool)
+// ConflictPolicy controls how port conflicts found at startup are resolved
+type ConflictPolicy struct {
+	// Takeover takes over ports held by other nanoporter instances
+	Takeover bool
+	// ForceFree terminates non-nanoporter processes holding configured ports after confirmation
+	ForceFree bool
+	// KillTimeout is how long to wait for a terminated process to exit and release its port
+	KillTimeout time.Duration
+	// KillEscalate force-kills processes that don't exit within KillTimeout
+	KillEscalate bool
+}
+
+// portHolder describes a non-nanoporter process holding a configured port
+type portHolder struct {
+	Address string
+	Port    int
+	PID     int
+	Process string
+}
+
+// endpoint returns the address and port the process holds, as host:port
+func (h portHolder) endpoint() string {
+	return net.JoinHostPort(h.Address, strconv.Itoa(h.Port))
+}
+
+// ResolvePortConflicts checks if any configured ports are in use by other processes.
+// By default forwards conflicting with another nanoporter instance are only reported and left
+// stopped so the others can start. With takeover enabled the ports are taken over: when the previous
+// instance exposes a control socket, ports are handed over one by one (release, then immediately
+// rebind here) so tunnels only see a short gap; otherwise the previous instance is killed.
+// Ports held by non-nanoporter processes fail startup unless ForceFree is set and the user confirms.
+func ResolvePortConflicts(config *Config, manager *PortForwardManager, policy ConflictPolicy) error {
+	// The same port may be used on several loopback addresses, and a process holding it on
+	// one of them doesn't keep the others from being bound
+	type endpoint struct {
+		address string
+		port    int
+	}
+	endpointsToCheck := make(map[endpoint]bool)
 
-	// Collect all local ports from config
+	// Collect all local addresses and ports from config
 	for _, cluster := range config.Clusters {
 		for _, forward := range cluster.Forwards {
-			portsToCheck[forward.LocalPort] = true
+			endpointsToCheck[endpoint{cluster.LocalAddress(), forward.LocalPort}] = true
+		}
+	}
+
+	// Try to reach a previous instance for a graceful handover
+	var handover *ControlClient
+	if policy.Takeover {
+		if client, err := DialControl(config.ControlSocket); err == nil {
+			slog.Info("Found previous nanoporter instance, attempting graceful handover",
+				"socket", config.ControlSocket,
+			)
+			handover = client
+			defer client.Close()
+		}
+	}
+
+	handedOver := 0
+	var foreign []portHolder
+
+	// Check each address and port for conflicts
+	for e := range endpointsToCheck {
+		address, port := e.address, e.port
+		if handover != nil {
+			ok, err := handoverPort(handover, manager, address, port)
+			if err != nil {
+				slog.Warn("Graceful handover failed, falling back to kill",
+					"address", address,
+					"port", port,
+					"error", err,
+				)
+			} else if ok {
+				handedOver++
+				continue
+			}
+		}
+
+		holder, err := checkPortConflict(manager, address, port, policy)
+		if err != nil {
+			return fmt.Errorf("failed to resolve port conflict for %s: %w", net.JoinHostPort(address, strconv.Itoa(port)), err)
+		}
+		if holder != nil {
+			if manager.reassignable(address, port) {
+				// Moved to another port when the forwards start
+				slog.Info("Port in use by another process, reassigning",
+					"address", address,
+					"port", port,
+					"pid", holder.PID,
+					"process", holder.Process,
+				)
+				continue
+			}
+			foreign = append(foreign, *holder)
 		}
 	}
 
-	// Check each port for conflicts
-	for port := range portsToCheck {
-		if err := checkAndKillPortConflict(port); err != nil {
-			return fmt.Errorf("failed to resolve port conflict for %d: %w", port, err)
+	// Tell the previous instance it is no longer needed, and let it close its control socket
+	// before this instance opens its own at the same path
+	if handover != nil {
+		if err := handover.Call("shutdown", nil, nil); err != nil {
+			slog.Warn("Failed to shut down previous instance", "error", err)
+		} else {
+			slog.Info("Handover complete", "ports", handedOver)
+			if err := waitForControlClosed(config.ControlSocket, handoverShutdownTimeout); err != nil {
+				slog.Warn("Previous instance is still running", "error", err)
+			}
 		}
 	}
 
+	if len(foreign) > 0 {
+		return freeForeignPorts(foreign, policy)
+	}
+
 	return nil
 }
 
-// checkAndKillPortConflict checks if a port is in use and kills the process if it's Porter
-func checkAndKillPortConflict(port int) error {
-	pid, processName, err := findProcessUsingPort(port)
+// freeForeignPorts terminates non-nanoporter processes holding configured ports after
+// an interactive confirmation. Without force it only reports the first conflict.
+func freeForeignPorts(holders []portHolder, policy ConflictPolicy) error {
+	sort.Slice(holders, func(i, j int) bool {
+		if holders[i].Port != holders[j].Port {
+			return holders[i].Port < holders[j].Port
+		}
+		return holders[i].Address < holders[j].Address
+	})
+
+	if !policy.ForceFree {
+		h := holders[0]
+		return fmt.Errorf("%s is in use by non-nanoporter process: %s (PID: %d), use --force-free-ports to terminate it",
+			h.endpoint(), h.Process, h.PID)
+	}
+
+	fmt.Println("The following processes hold ports configured for nanoporter:")
+	for _, h := range holders {
+		fmt.Printf("  %-21s  PID %-7d  %s\n", h.endpoint(), h.PID, h.Process)
+	}
+	fmt.Print("Terminate them? [y/N] ")
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		return fmt.Errorf("not terminating processes holding configured ports")
+	}
+
+	for _, h := range holders {
+		err := killProcess(h.PID, h.Address, h.Port, policy)
+		audit("terminate", auditSourceStartup, err, "address", h.Address, "port", h.Port, "pid", h.PID, "process", h.Process)
+		if err != nil {
+			return fmt.Errorf("failed to terminate %s (PID %d) holding %s: %w", h.Process, h.PID, h.endpoint(), err)
+		}
+		slog.Info("Terminated process holding configured port",
+			"address", h.Address,
+			"port", h.Port,
+			"pid", h.PID,
+			"process", h.Process,
+		)
+	}
+
+	return nil
+}
+
+// releaseParams are the parameters of the "release" control command
+type releaseParams struct {
+	Address string `json:"address,omitempty"` // the local address, any address when empty
+	Port    int    `json:"port"`
+}
+
+// handoverPort asks the previous instance to release a local address and port, then starts
+// our own forwards for it and waits until they are bound. Returns false if it wasn't held by
+// the previous instance.
+func handoverPort(client *ControlClient, manager *PortForwardManager, address string, port int) (bool, error) {
+	pid, processName, _ := findProcessUsingPort(address, port)
+	if pid == 0 || pid == os.Getpid() || !strings.Contains(processName, "nanoporter") {
+		return false, nil
+	}
+
+	start := time.Now()
+	if err := client.Call("release", releaseParams{Address: address, Port: port}, nil); err != nil {
+		return false, err
+	}
+
+	for _, pf := range manager.FindForwardsByPort(address, port) {
+		if err := waitForPortFree(address, port, 5*time.Second); err != nil {
+			return false, err
+		}
+
+		manager.StartForward(pf)
+		if err := WaitForPortForward(manager, pf, 30*time.Second); err != nil {
+			return true, fmt.Errorf("port released but forward failed to start: %w", err)
+		}
+	}
+
+	slog.Info("Port handed over from previous instance",
+		"address", address,
+		"port", port,
+		"gap", time.Since(start),
+	)
+
+	return true, nil
+}
+
+// handoverShutdownTimeout is how long a previous instance may take to exit after a handover
+const handoverShutdownTimeout = 15 * time.Second
+
+// waitForControlClosed polls until nothing answers on a control socket anymore. An exiting
+// instance removes the socket file before it stops accepting connections.
+func waitForControlClosed(path string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		conn, err := net.DialTimeout("unix", path, time.Second)
+		if err != nil {
+			return nil
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			return fmt.Errorf("control socket %s still answering after %s", path, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// waitForPortFree polls until a local port can be bound on an address
+func waitForPortFree(address string, port int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+		if err == nil {
+			listener.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("port %d still in use after %s", port, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// checkPortConflict checks if a port is in use by another nanoporter instance. With takeover the
+// other instance is killed, otherwise the forward for the port is marked failed and not started.
+// Non-nanoporter holders are returned to the caller.
+func checkPortConflict(manager *PortForwardManager, address string, port int, policy ConflictPolicy) (*portHolder, error) {
+	pid, processName, err := findProcessUsingPort(address, port)
 	if err != nil {
 		// Port not in use or error checking - proceed
-		return nil
+		return nil, nil
 	}
 
 	// If no process found, port is free
 	if pid == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// Check if it's a nanoporter process
 	if !strings.Contains(processName, "nanoporter") {
-		return fmt.Errorf("port %d is in use by non-nanoporter process: %s (PID: %d)", port, processName, pid)
+		return &portHolder{Address: address, Port: port, PID: pid, Process: processName}, nil
 	}
 
 	// Don't kill ourselves
 	if pid == os.Getpid() {
-		return nil
+		return nil, nil
 	}
 
 	slog.Info("Found conflicting nanoporter instance",
+		"address", address,
 		"port", port,
 		"pid", pid,
 		"process", processName,
 	)
 
+	if !policy.Takeover {
+		slog.Warn("Leaving forward stopped, use --takeover to take the port over",
+			"address", address,
+			"port", port,
+			"pid", pid,
+		)
+		for _, pf := range manager.FindForwardsByPort(address, port) {
+			if pf.Config.OnConflict == onConflictReassign {
+				continue
+			}
+			manager.FailForward(pf, fmt.Sprintf("port %d held by nanoporter PID %d (use --takeover)", port, pid))
+		}
+		return nil, nil
+	}
+
 	// Kill the process
-	if err := killProcess(pid); err != nil {
-		return fmt.Errorf("failed to kill conflicting nanoporter process (PID %d): %w", pid, err)
+	err = killProcess(pid, address, port, policy)
+	audit("takeover_kill", auditSourceStartup, err, "port", port, "pid", pid, "process", processName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to kill conflicting nanoporter process (PID %d): %w", pid, err)
 	}
 
 	slog.Info("Killed conflicting nanoporter instance",
@@ -71,142 +313,95 @@ func checkAndKillPortConflict(port int) error {
 		"pid", pid,
 	)
 
-	return nil
+	return nil, nil
 }
 
-// findProcessUsingPort finds the PID and name of the process using a port
-func findProcessUsingPort(port int) (int, string, error) {
-	// Try using lsof first (more reliable)
-	pid, name, err := findProcessWithLsof(port)
-	if err == nil && pid != 0 {
-		return pid, name, nil
-	}
-
-	// Fallback to ss command
-	pid, name, err = findProcessWithSS(port)
-	if err == nil && pid != 0 {
-		return pid, name, nil
-	}
-
-	// Port not in use or couldn't detect
-	return 0, "", nil
+// processInspector abstracts the OS-specific parts of port conflict detection
+type processInspector interface {
+	// FindProcessUsingPort returns the PID and name of the process listening on a port in a way
+	// that keeps it from being bound on address (PID 0 if none)
+	FindProcessUsingPort(address string, port int) (int, string, error)
+	// ProcessName returns the executable name of a process
+	ProcessName(pid int) (string, error)
+	// Terminate asks a process to shut down
+	Terminate(pid int) error
+	// ForceKill kills a process immediately
+	ForceKill(pid int) error
+	// IsRunning reports whether a process still exists
+	IsRunning(pid int) bool
 }
 
-// findProcessWithLsof uses lsof to find the process using a port
-func findProcessWithLsof(port int) (int, string, error) {
-	cmd := exec.Command("lsof", "-i", fmt.Sprintf(":%d", port), "-t", "-sTCP:LISTEN")
-	output, err := cmd.Output()
-	if err != nil {
-		// lsof returns error if no process found, which is fine
-		return 0, "", nil
-	}
+// errNativeLookupUnsupported is returned when the OS has no native port owner lookup
+var errNativeLookupUnsupported = errors.New("native port owner lookup not supported on this platform")
 
-	pidStr := strings.TrimSpace(string(output))
-	if pidStr == "" {
-		return 0, "", nil
-	}
+// platform is the process inspector for the current OS
+var platform = newProcessInspector()
 
-	// Handle multiple PIDs (take first one)
-	pids := strings.Split(pidStr, "\n")
-	pid, err := strconv.Atoi(pids[0])
-	if err != nil {
-		return 0, "", err
-	}
-
-	// Get process name
-	name, err := getProcessName(pid)
-	if err != nil {
-		return pid, "unknown", nil
-	}
-
-	return pid, name, nil
+// findProcessUsingPort finds the PID and name of the process using a port on an address,
+// either bound on that address or on all of them
+func findProcessUsingPort(address string, port int) (int, string, error) {
+	return platform.FindProcessUsingPort(address, port)
 }
 
-// findProcessWithSS uses ss command to find the process using a port
-func findProcessWithSS(port int) (int, string, error) {
-	cmd := exec.Command("ss", "-ltnp", fmt.Sprintf("sport = :%d", port))
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, "", nil
+// listenerBlocks reports whether a listener bound on host keeps address from being bound.
+// Wildcard listeners block every address; a hostname can't be compared and counts as a match.
+func listenerBlocks(host, address string) bool {
+	host, _, _ = strings.Cut(host, "%") // zone, e.g. fe80::1%lo0
+	if host == "" || host == "*" {
+		return true
 	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, fmt.Sprintf(":%d", port)) {
-			// Parse PID from ss output (format: users:(("process",pid=1234,fd=5)))
-			start := strings.Index(line, "pid=")
-			if start == -1 {
-				continue
-			}
-			start += 4
-			end := strings.Index(line[start:], ",")
-			if end == -1 {
-				end = strings.Index(line[start:], ")")
-			}
-			if end == -1 {
-				continue
-			}
-
-			pidStr := line[start : start+end]
-			pid, err := strconv.Atoi(pidStr)
-			if err != nil {
-				continue
-			}
-
-			// Get process name
-			name, err := getProcessName(pid)
-			if err != nil {
-				return pid, "unknown", nil
-			}
-
-			return pid, name, nil
-		}
+	ip := net.ParseIP(host)
+	target := net.ParseIP(address)
+	if ip == nil || target == nil || ip.IsUnspecified() {
+		return true
 	}
+	return ip.Equal(target)
+}
 
-	return 0, "", nil
+// addressesOverlap reports whether listeners on the two addresses would conflict for the
+// same port
+func addressesOverlap(a, b string) bool {
+	return listenerBlocks(a, b) || listenerBlocks(b, a)
 }
 
-// getProcessName gets the name of a process by PID
-func getProcessName(pid int) (string, error) {
-	cmdlinePath := fmt.Sprintf("/proc/%d/cmdline", pid)
-	data, err := os.ReadFile(cmdlinePath)
-	if err != nil {
-		return "", err
+// killProcess terminates a process and waits until it has exited and released the port,
+// escalating to a forced kill if the policy allows it
+func killProcess(pid int, address string, port int, policy ConflictPolicy) error {
+	if err := platform.Terminate(pid); err != nil {
+		return err
 	}
 
-	// cmdline is null-separated, take first part
-	parts := strings.Split(string(data), "\x00")
-	if len(parts) == 0 || parts[0] == "" {
-		return "unknown", nil
+	err := waitForRelease(pid, address, port, policy.KillTimeout)
+	if err == nil {
+		return nil
 	}
-
-	// Extract just the binary name
-	cmdline := parts[0]
-	// Get last part of path
-	if idx := strings.LastIndex(cmdline, "/"); idx != -1 {
-		cmdline = cmdline[idx+1:]
+	if !policy.KillEscalate {
+		return err
 	}
 
-	return cmdline, nil
-}
+	slog.Warn("Process did not exit in time, forcing kill",
+		"pid", pid,
+		"port", port,
+		"timeout", policy.KillTimeout,
+	)
 
-// killProcess kills a process by PID
-func killProcess(pid int) error {
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return err
+	if err := platform.ForceKill(pid); err != nil {
+		return fmt.Errorf("failed to force kill PID %d: %w", pid, err)
 	}
 
-	// Try SIGTERM first (graceful shutdown)
-	if err := process.Signal(syscall.SIGTERM); err != nil {
-		return err
-	}
+	return waitForRelease(pid, address, port, policy.KillTimeout)
+}
 
-	slog.Debug("Sent SIGTERM to process", "pid", pid)
+// waitForRelease polls until a process has exited and its port can be bound
+func waitForRelease(pid int, address string, port int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
 
-	// Give it a moment to shut down gracefully
-	// In a real implementation, you might want to wait and verify
-	// For now, we'll trust SIGTERM worked
+	for platform.IsRunning(pid) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("process %d still running after %s", pid, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 
-	return nil
+	return waitForPortFree(address, port, time.Until(deadline))
 }
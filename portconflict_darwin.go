@@ -0,0 +1,41 @@
+//go:build darwin
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// getProcessName gets the name of a process by PID via sysctl KERN_PROCARGS2,
+// falling back to ps when the arguments of the process aren't readable
+func getProcessName(pid int) (string, error) {
+	// Layout: int32 argc, then the NUL-terminated executable path, then argv
+	data, err := unix.SysctlRaw("kern.procargs2", pid)
+	if err != nil || len(data) < 5 {
+		return processNameFromPS(pid)
+	}
+
+	argc := binary.LittleEndian.Uint32(data[:4])
+	if argc == 0 {
+		return processNameFromPS(pid)
+	}
+
+	execPath := data[4:]
+	if end := bytes.IndexByte(execPath, 0); end != -1 {
+		execPath = execPath[:end]
+	}
+	if len(execPath) == 0 {
+		return processNameFromPS(pid)
+	}
+
+	return filepath.Base(string(execPath)), nil
+}
+
+// lookupPortOwner has no native implementation on macOS; lsof is always available there
+func lookupPortOwner(address string, port int) (int, string, error) {
+	return 0, "", errNativeLookupUnsupported
+}
@@ -0,0 +1,158 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListenState is the socket state of a listening socket in /proc/net/tcp
+const tcpListenState = "0A"
+
+// lookupPortOwner finds the process listening on a port on an address (or on all addresses)
+// by matching the socket inode from /proc/net/tcp{,6} against the file descriptors in /proc/*/fd
+func lookupPortOwner(address string, port int) (int, string, error) {
+	inodes := make(map[string]bool)
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if err := collectListeningInodes(table, address, port, inodes); err != nil && !os.IsNotExist(err) {
+			return 0, "", err
+		}
+	}
+
+	// Nobody is listening on the port
+	if len(inodes) == 0 {
+		return 0, "", nil
+	}
+
+	pid, err := findPIDBySocketInode(inodes)
+	if err != nil {
+		return 0, "", err
+	}
+
+	name, err := getProcessName(pid)
+	if err != nil {
+		return pid, "unknown", nil
+	}
+
+	return pid, name, nil
+}
+
+// collectListeningInodes adds the inodes of sockets listening on port in a way that blocks
+// address from a /proc/net/tcp table
+// Format: sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+func collectListeningInodes(path string, address string, port int, inodes map[string]bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // skip header
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListenState {
+			continue
+		}
+
+		// local_address is ADDR:PORT with the port in hex
+		idx := strings.LastIndex(fields[1], ":")
+		if idx == -1 {
+			continue
+		}
+		localPort, err := strconv.ParseUint(fields[1][idx+1:], 16, 16)
+		if err != nil || int(localPort) != port {
+			continue
+		}
+		if ip := parseProcAddress(fields[1][:idx]); ip == nil || !listenerBlocks(ip.String(), address) {
+			continue
+		}
+
+		if fields[9] != "0" {
+			inodes[fields[9]] = true
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseProcAddress parses an address of /proc/net/tcp{,6}: hex digits of the address in
+// 32-bit words in host byte order
+func parseProcAddress(value string) net.IP {
+	raw, err := hex.DecodeString(value)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.NativeEndian.Uint32(raw[i:]))
+	}
+	return ip
+}
+
+// findPIDBySocketInode scans /proc/*/fd for a file descriptor pointing at one of the socket inodes
+func findPIDBySocketInode(inodes map[string]bool) (int, error) {
+	procDirs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, dir := range procDirs {
+		pid, err := strconv.Atoi(dir.Name())
+		if err != nil {
+			continue
+		}
+
+		fdDir := filepath.Join("/proc", dir.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			// Processes of other users aren't readable without privileges
+			continue
+		}
+
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")] {
+				return pid, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("socket owner not visible (process may belong to another user)")
+}
+
+// getProcessName gets the name of a process by PID
+func getProcessName(pid int) (string, error) {
+	cmdlinePath := fmt.Sprintf("/proc/%d/cmdline", pid)
+	data, err := os.ReadFile(cmdlinePath)
+	if err != nil {
+		return "", err
+	}
+
+	// cmdline is null-separated, take first part
+	parts := strings.Split(string(data), "\x00")
+	if len(parts) == 0 || parts[0] == "" {
+		return "unknown", nil
+	}
+
+	// Extract just the binary name
+	cmdline := parts[0]
+	// Get last part of path
+	if idx := strings.LastIndex(cmdline, "/"); idx != -1 {
+		cmdline = cmdline[idx+1:]
+	}
+
+	return cmdline, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+// getProcessName gets the name of a process by PID using ps
+func getProcessName(pid int) (string, error) {
+	return processNameFromPS(pid)
+}
+
+// lookupPortOwner has no native implementation on this platform
+func lookupPortOwner(address string, port int) (int, string, error) {
+	return 0, "", errNativeLookupUnsupported
+}
@@ -0,0 +1,197 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// unixProcessInspector finds port owners natively where the OS allows it (falling back
+// to lsof/ss) and signals processes with SIGTERM
+type unixProcessInspector struct{}
+
+// newProcessInspector returns the process inspector for unix-like systems
+func newProcessInspector() processInspector {
+	return unixProcessInspector{}
+}
+
+// FindProcessUsingPort finds the PID and name of the process using a port on an address
+func (unixProcessInspector) FindProcessUsingPort(address string, port int) (int, string, error) {
+	// Native lookup doesn't depend on external binaries being installed
+	pid, name, err := lookupPortOwner(address, port)
+	if err == nil {
+		return pid, name, nil
+	}
+	slog.Debug("Native port owner lookup unavailable, falling back to lsof/ss", "port", port, "error", err)
+
+	// Try using lsof first (more reliable)
+	pid, name, err = findProcessWithLsof(address, port)
+	if err == nil && pid != 0 {
+		return pid, name, nil
+	}
+
+	// Fallback to ss command
+	pid, name, err = findProcessWithSS(address, port)
+	if err == nil && pid != 0 {
+		return pid, name, nil
+	}
+
+	// Port not in use or couldn't detect
+	return 0, "", nil
+}
+
+// ProcessName gets the name of a process by PID
+func (unixProcessInspector) ProcessName(pid int) (string, error) {
+	return getProcessName(pid)
+}
+
+// findProcessWithLsof uses lsof to find the process using a port on an address
+func findProcessWithLsof(address string, port int) (int, string, error) {
+	// -F pn prints a "p<pid>" line per process followed by "n<host>:<port>" lines per socket
+	cmd := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fpn")
+	output, err := cmd.Output()
+	if err != nil {
+		// lsof returns error if no process found, which is fine
+		return 0, "", nil
+	}
+
+	pid := 0
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p"):
+			pid, _ = strconv.Atoi(line[1:])
+		case strings.HasPrefix(line, "n") && pid != 0:
+			host, _, err := net.SplitHostPort(line[1:])
+			if err != nil || !listenerBlocks(host, address) {
+				continue
+			}
+
+			// Get process name
+			name, err := getProcessName(pid)
+			if err != nil {
+				return pid, "unknown", nil
+			}
+			return pid, name, nil
+		}
+	}
+
+	return 0, "", nil
+}
+
+// findProcessWithSS uses ss command to find the process using a port on an address
+func findProcessWithSS(address string, port int) (int, string, error) {
+	cmd := exec.Command("ss", "-ltnpH", fmt.Sprintf("sport = :%d", port))
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, "", nil
+	}
+
+	lines := strings.Split(string(output), "\n")
+	for _, line := range lines {
+		// State Recv-Q Send-Q Local-Address:Port Peer-Address:Port Process
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		host, localPort, err := net.SplitHostPort(fields[3])
+		if err != nil || localPort != strconv.Itoa(port) || !listenerBlocks(host, address) {
+			continue
+		}
+
+		// Parse PID from ss output (format: users:(("process",pid=1234,fd=5)))
+		start := strings.Index(line, "pid=")
+		if start == -1 {
+			continue
+		}
+		start += 4
+		end := strings.Index(line[start:], ",")
+		if end == -1 {
+			end = strings.Index(line[start:], ")")
+		}
+		if end == -1 {
+			continue
+		}
+
+		pidStr := line[start : start+end]
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			continue
+		}
+
+		// Get process name
+		name, err := getProcessName(pid)
+		if err != nil {
+			return pid, "unknown", nil
+		}
+
+		return pid, name, nil
+	}
+
+	return 0, "", nil
+}
+
+// processNameFromPS gets the name of a process by PID using ps
+func processNameFromPS(pid int) (string, error) {
+	output, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", fmt.Errorf("ps failed for PID %d: %w", pid, err)
+	}
+
+	name := strings.TrimSpace(string(output))
+	if name == "" {
+		return "", fmt.Errorf("process %d not found", pid)
+	}
+
+	// comm may be a full path on macOS
+	return filepath.Base(name), nil
+}
+
+// Terminate sends SIGTERM to a process
+func (unixProcessInspector) Terminate(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	// Try SIGTERM first (graceful shutdown)
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		return err
+	}
+
+	slog.Debug("Sent SIGTERM to process", "pid", pid)
+
+	return nil
+}
+
+// ForceKill sends SIGKILL to a process
+func (unixProcessInspector) ForceKill(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	if err := process.Signal(syscall.SIGKILL); err != nil {
+		return err
+	}
+
+	slog.Debug("Sent SIGKILL to process", "pid", pid)
+
+	return nil
+}
+
+// IsRunning checks whether a process exists by sending signal 0
+func (unixProcessInspector) IsRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
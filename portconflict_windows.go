@@ -0,0 +1,234 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	iphlpapi                = windows.NewLazySystemDLL("iphlpapi.dll")
+	procGetExtendedTcpTable = iphlpapi.NewProc("GetExtendedTcpTable")
+)
+
+const (
+	// tcpTableOwnerPIDListener requests only listening sockets with their owning PID
+	tcpTableOwnerPIDListener = 3
+
+	// Row sizes of MIB_TCPROW_OWNER_PID and MIB_TCP6ROW_OWNER_PID
+	tcpRowOwnerPIDSize  = 24
+	tcp6RowOwnerPIDSize = 56
+
+	// stillActive is the exit code reported for processes that haven't exited
+	stillActive = 259
+)
+
+// windowsProcessInspector finds port owners with the IP helper API (falling back to
+// netstat) and stops processes with taskkill
+type windowsProcessInspector struct{}
+
+// newProcessInspector returns the process inspector for Windows
+func newProcessInspector() processInspector {
+	return windowsProcessInspector{}
+}
+
+// FindProcessUsingPort finds the PID and name of the process listening on a port on an address
+func (w windowsProcessInspector) FindProcessUsingPort(address string, port int) (int, string, error) {
+	pid, err := lookupPortOwner(address, port)
+	if err != nil {
+		slog.Debug("GetExtendedTcpTable failed, falling back to netstat", "port", port, "error", err)
+
+		cmd := exec.Command("netstat", "-ano", "-p", "TCP")
+		output, err := cmd.Output()
+		if err != nil {
+			return 0, "", fmt.Errorf("netstat failed: %w", err)
+		}
+		pid = parseNetstatListener(string(output), address, port)
+	}
+
+	if pid == 0 {
+		return 0, "", nil
+	}
+
+	name, err := w.ProcessName(pid)
+	if err != nil {
+		return pid, "unknown", nil
+	}
+
+	return pid, name, nil
+}
+
+// lookupPortOwner finds the PID listening on a port on an address (or on all addresses) via
+// GetExtendedTcpTable (IPv4 and IPv6)
+func lookupPortOwner(address string, port int) (int, error) {
+	for _, family := range []uint32{windows.AF_INET, windows.AF_INET6} {
+		table, err := getTCPListenerTable(family)
+		if err != nil {
+			return 0, err
+		}
+
+		rowSize, addrOffset, addrLen, portOffset, pidOffset := tcpRowOwnerPIDSize, 4, 4, 8, 20
+		if family == windows.AF_INET6 {
+			rowSize, addrOffset, addrLen, portOffset, pidOffset = tcp6RowOwnerPIDSize, 0, 16, 20, 52
+		}
+
+		// Table layout: DWORD dwNumEntries followed by rows
+		count := int(binary.LittleEndian.Uint32(table[:4]))
+		for i := 0; i < count; i++ {
+			row := table[4+i*rowSize:]
+			if len(row) < rowSize {
+				break
+			}
+
+			// Port is stored in network byte order in the low 16 bits
+			localPort := int(binary.BigEndian.Uint16(row[portOffset : portOffset+2]))
+			// The address is in network byte order too
+			localAddr := net.IP(row[addrOffset : addrOffset+addrLen])
+			if localPort == port && listenerBlocks(localAddr.String(), address) {
+				return int(binary.LittleEndian.Uint32(row[pidOffset : pidOffset+4])), nil
+			}
+		}
+	}
+
+	return 0, nil
+}
+
+// getTCPListenerTable returns the raw MIB_TCP(6)TABLE_OWNER_PID for listening sockets
+func getTCPListenerTable(family uint32) ([]byte, error) {
+	if err := procGetExtendedTcpTable.Find(); err != nil {
+		return nil, err
+	}
+
+	var size uint32
+	for attempt := 0; attempt < 3; attempt++ {
+		buf := make([]byte, max(size, 4))
+		ret, _, _ := procGetExtendedTcpTable.Call(
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(unsafe.Pointer(&size)),
+			0,
+			uintptr(family),
+			tcpTableOwnerPIDListener,
+			0,
+		)
+		switch windows.Errno(ret) {
+		case 0:
+			return buf, nil
+		case windows.ERROR_INSUFFICIENT_BUFFER:
+			// size now holds the required length; the table may grow between calls
+			continue
+		default:
+			return nil, windows.Errno(ret)
+		}
+	}
+
+	return nil, fmt.Errorf("TCP table kept growing")
+}
+
+// parseNetstatListener extracts the PID listening on a port on an address from `netstat -ano`
+// output
+// Format:   TCP    127.0.0.1:8080    0.0.0.0:0    LISTENING    1234
+func parseNetstatListener(output string, address string, port int) int {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[0] != "TCP" || fields[3] != "LISTENING" {
+			continue
+		}
+		host, localPort, err := net.SplitHostPort(fields[1])
+		if err != nil || localPort != strconv.Itoa(port) || !listenerBlocks(host, address) {
+			continue
+		}
+
+		pid, err := strconv.Atoi(fields[4])
+		if err != nil || pid == 0 {
+			continue
+		}
+		return pid
+	}
+
+	return 0
+}
+
+// ProcessName gets the image name of a process by PID, falling back to tasklist
+func (windowsProcessInspector) ProcessName(pid int) (string, error) {
+	if handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid)); err == nil {
+		defer windows.CloseHandle(handle)
+
+		buf := make([]uint16, windows.MAX_LONG_PATH)
+		size := uint32(len(buf))
+		if err := windows.QueryFullProcessImageName(handle, 0, &buf[0], &size); err == nil {
+			return filepath.Base(windows.UTF16ToString(buf[:size])), nil
+		}
+	}
+
+	cmd := exec.Command("tasklist", "/FI", fmt.Sprintf("PID eq %d", pid), "/FO", "CSV", "/NH")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("tasklist failed: %w", err)
+	}
+
+	// Output: "nanoporter.exe","1234","Console","1","12,345 K"
+	records, err := csv.NewReader(strings.NewReader(string(output))).ReadAll()
+	if err != nil || len(records) == 0 || len(records[0]) == 0 {
+		return "", fmt.Errorf("process %d not found", pid)
+	}
+
+	return records[0][0], nil
+}
+
+// Terminate asks a process to exit with taskkill, killing it if that fails
+func (windowsProcessInspector) Terminate(pid int) error {
+	// Try a graceful taskkill first (sends WM_CLOSE)
+	if err := exec.Command("taskkill", "/PID", strconv.Itoa(pid)).Run(); err == nil {
+		slog.Debug("Sent taskkill to process", "pid", pid)
+		return nil
+	}
+
+	// Console applications don't handle WM_CLOSE, so terminate forcibly
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := process.Kill(); err != nil {
+		return err
+	}
+
+	slog.Debug("Killed process", "pid", pid)
+
+	return nil
+}
+
+// ForceKill terminates a process immediately
+func (windowsProcessInspector) ForceKill(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
+
+// IsRunning checks whether a process is still active
+func (windowsProcessInspector) IsRunning(pid int) bool {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(handle)
+
+	var code uint32
+	if err := windows.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
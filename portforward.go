@@ -2,11 +2,18 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"math/rand/v2"
 	"net"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +24,7 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 // ForwardState represents the state of a port-forward
@@ -26,8 +34,11 @@ const (
 	StateStarting     ForwardState = "starting"
 	StateActive       ForwardState = "active"
 	StateReconnecting ForwardState = "reconnecting"
+	StateAuthExpired  ForwardState = "auth-expired"
 	StateFailed       ForwardState = "failed"
 	StateStopped      ForwardState = "stopped"
+	StateDisabled     ForwardState = "disabled" // cluster disabled at runtime
+	StatePaused       ForwardState = "paused"   // all forwards paused at runtime
 )
 
 // BackupState represents the state of a database backup
@@ -43,13 +54,16 @@ const (
 
 // PortForward manages a single port-forward connection
 type PortForward struct {
+	ID          string // cluster/namespace/service:local_port
 	Config      ForwardConfig
 	ClusterName string
+	BindAddress string
 	State       ForwardState
 	Error       string
 	LastCheck   time.Time
 	ReconnectAt time.Time
 	RetryCount  int
+	ActiveSince time.Time // when the forward last became active
 
 	// Backup status
 	BackupState  BackupState
@@ -57,134 +71,1128 @@ type PortForward struct {
 	BackupTime   time.Time
 	BackupSizeMB float64
 
-	mu         sync.RWMutex
-	client     *kubernetes.Clientset
-	restConfig *rest.Config
-	stopChan   chan struct{}
-	readyChan  chan struct{}
-	ctx        context.Context
-	cancel     context.CancelFunc
+	mu            sync.RWMutex
+	reportedState ForwardState        // state of the last EventStateChanged
+	pod           string              // pod the forward is connected to
+	podUID        string              // UID of the pod (ID of the container for docker forwards)
+	podSince      time.Time           // when the forward selected the pod
+	remotePort    int                 // remote_port, resolved on the pod for a named container port
+	failingSince  time.Time           // first failure since the forward was last active
+	latencies     []time.Duration     // latest round trips of health check pings
+	localPort     int                 // port listened on: local_port, or the one on_conflict moved the forward to
+	rejected      int                 // connections refused by allowed_cidrs
+	refused       int                 // connections refused by max_connections
+	openConns     int                 // connections open through nanoporter's listener
+	capture       *trafficCapture     // latest traffic capture, stopped or running
+	viaProxy      bool                // the current connection listens through nanoporter's proxy
+	connections   []*ConnectionRecord // latest connections accepted by nanoporter's listener
+	accessLog     *slog.Logger        // writes access_log, opened on first use
+	healthChecks  map[string]bool     // client addresses of health check connections
+	statsSince    time.Time           // when the statistics below started, possibly in an earlier run
+	uptime        time.Duration       // time active, not counting the current connection
+	reconnects    int                 // times the forward started reconnecting
+	cluster       *ClusterClient
+	backoff       BackoffConfig // resolved backoff of the cluster
+	stopChan      chan struct{}
+	readyChan     chan struct{}
+	retryNow      chan struct{} // interrupts the backoff delay
+	waitingRetry  bool          // in the backoff delay, so retryNow is read
+	ctx           context.Context
+	cancel        context.CancelFunc
+	started       bool
+	detached      bool            // started with StartDetachedForward, reported to no one
+	handed        *handedListener // listener bound before the forward started, nil if it binds its own
+	done          chan struct{}
 }
 
 // PortForwardManager manages all port-forwards
 type PortForwardManager struct {
-	forwards   []*PortForward
-	config     *Config
-	mu         sync.RWMutex
-	updateChan chan *PortForward
+	forwards    []*PortForward
+	clusters    map[string]*ClusterClient
+	config      *Config
+	mu          sync.RWMutex
+	running     bool
+	listeners   []func(Event)
+	subscribers map[chan Event]struct{}
+	disabled    map[string]bool // clusters disabled at runtime
+	paused      bool            // all forwards paused with PauseAll
+
+	dialSlots chan struct{} // bounds how many port-forwards are established at once
+	dialMu    sync.Mutex
+	nextDial  time.Time // earliest start of the next dial
+
+	reconnectLimits map[string]flowcontrol.RateLimiter // reconnect_rate token bucket by cluster
 }
 
+// dialStagger spaces out establishing port-forwards so they don't all hit the API server at once
+const dialStagger = 25 * time.Millisecond
+
 // NewPortForwardManager creates a new port-forward manager
 func NewPortForwardManager(config *Config) *PortForwardManager {
-	return &PortForwardManager{
-		forwards:   make([]*PortForward, 0),
-		config:     config,
-		updateChan: make(chan *PortForward, 100),
+	m := &PortForwardManager{
+		forwards:    make([]*PortForward, 0),
+		clusters:    make(map[string]*ClusterClient),
+		config:      config,
+		subscribers: make(map[chan Event]struct{}),
+		disabled:    make(map[string]bool),
+
+		reconnectLimits: make(map[string]flowcontrol.RateLimiter),
+	}
+	if config.StartupConcurrency > 0 {
+		m.dialSlots = make(chan struct{}, config.StartupConcurrency)
 	}
+	return m
 }
 
 // Initialize sets up all port-forwards from configuration
 func (m *PortForwardManager) Initialize() error {
-	for _, cluster := range m.config.Clusters {
-		// Load kubeconfig for this cluster
-		restConfig, clientset, err := loadKubeconfig(cluster.Kubeconfig, cluster.Context)
+	usedPorts := usedLocalPorts(m.config)
+
+	for i := range m.config.Clusters {
+		cluster := &m.config.Clusters[i]
+
+		// Clusters grouping only SSH tunnels don't need a kubeconfig
+		if !cluster.usesKubernetes() {
+			for _, fwdConfig := range cluster.Forwards {
+				m.forwards = append(m.forwards, newPortForward(*cluster, fwdConfig, nil))
+			}
+			continue
+		}
+
+		// Load kubeconfig for this cluster. A broken cluster doesn't keep the others from
+		// starting: its forwards wait for credentials and reload the kubeconfig while retrying.
+		clusterClient, err := NewClusterClient(*cluster)
+		if err != nil {
+			slog.Warn("Failed to load kubeconfig, will keep retrying", "cluster", cluster.Name, "error", err)
+			clusterClient = newUnavailableClusterClient(*cluster, err)
+		}
+		m.clusters[cluster.Name] = clusterClient
+
+		// Replace wildcard forwards with the services they match, so everything
+		// reading the config later (hosts file, DNS, port conflicts) sees them
+		if clusterClient.Err() == nil {
+			_, client := clusterClient.Get()
+			err = expandWildcardForwards(cluster, client, usedPorts)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to load kubeconfig for cluster %s: %w", cluster.Name, err)
+			slog.Warn("Skipping wildcard forwards, restart to discover them",
+				"cluster", cluster.Name,
+				"forwards", dropWildcardForwards(cluster),
+				"error", err,
+			)
 		}
 
-		// Create port-forward instances
+		// Create port-forward instances; those of fan_out forwards follow the pods
 		for _, fwdConfig := range cluster.Forwards {
-			ctx, cancel := context.WithCancel(context.Background())
-			pf := &PortForward{
-				Config:      fwdConfig,
-				ClusterName: cluster.Name,
-				State:       StateStarting,
-				client:      clientset,
-				restConfig:  restConfig,
-				stopChan:    make(chan struct{}),
-				readyChan:   make(chan struct{}),
-				ctx:         ctx,
-				cancel:      cancel,
+			if fwdConfig.FanOut {
+				continue
 			}
-			m.forwards = append(m.forwards, pf)
+			m.forwards = append(m.forwards, newPortForward(*cluster, fwdConfig, clusterClient))
 		}
 	}
 
 	return nil
 }
 
-// Start begins all port-forwards and monitoring
+// newPortForward creates a port-forward in the starting state
+func newPortForward(cluster ClusterConfig, fwdConfig ForwardConfig, clusterClient *ClusterClient) *PortForward {
+	ctx, cancel := context.WithCancel(context.Background())
+	var backoff BackoffConfig
+	if cluster.Backoff != nil {
+		backoff = *cluster.Backoff
+	}
+	return &PortForward{
+		ID:          forwardID(cluster.Name, fwdConfig),
+		Config:      fwdConfig,
+		ClusterName: cluster.Name,
+		BindAddress: cluster.BindAddress,
+		State:       StateStarting,
+		localPort:   fwdConfig.LocalPort,
+		remotePort:  fwdConfig.RemotePort,
+		statsSince:  time.Now(),
+		cluster:     clusterClient,
+		backoff:     backoff,
+		stopChan:    make(chan struct{}),
+		readyChan:   make(chan struct{}),
+		retryNow:    make(chan struct{}, 1),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// forwardID identifies a port-forward; the local port tells apart several ports of one service
+func forwardID(clusterName string, fwdConfig ForwardConfig) string {
+	return fmt.Sprintf("%s/%s/%s:%d", clusterName, fwdConfig.Namespace, fwdConfig.Service, fwdConfig.LocalPort)
+}
+
+// removeForwardTimeout is how long RemoveForward waits for a port-forward's listener to close
+const removeForwardTimeout = 10 * time.Second
+
+// Waiting for the previous start_order phase: how long at most before starting anyway, and
+// how often its port-forwards are checked
+const (
+	startPhaseTimeout  = 30 * time.Second
+	startPhaseInterval = 200 * time.Millisecond
+)
+
+// Start begins all port-forwards and monitoring. Port-forwards with a higher start_order
+// are started in the background, after those with a lower one.
 func (m *PortForwardManager) Start() {
-	// Start each port-forward
-	for _, pf := range m.forwards {
-		go m.runPortForward(pf)
+	m.mu.Lock()
+	m.running = true
+	forwards := make([]*PortForward, len(m.forwards))
+	copy(forwards, m.forwards)
+	m.mu.Unlock()
+
+	phases := startPhases(forwards)
+	if len(phases) > 0 {
+		m.startPhase(phases[0])
+	}
+	if len(phases) > 1 {
+		go m.startLaterPhases(phases[0], phases[1:])
 	}
 
 	// Start health monitor
+	m.probeClusters()
 	go m.healthMonitor()
 }
 
+// startPhases groups port-forwards by start_order, lowest first
+func startPhases(forwards []*PortForward) [][]*PortForward {
+	byOrder := make(map[int][]*PortForward)
+	var orders []int
+	for _, pf := range forwards {
+		order := pf.Config.StartOrder
+		if _, ok := byOrder[order]; !ok {
+			orders = append(orders, order)
+		}
+		byOrder[order] = append(byOrder[order], pf)
+	}
+	slices.Sort(orders)
+
+	phases := make([][]*PortForward, 0, len(orders))
+	for _, order := range orders {
+		phases = append(phases, byOrder[order])
+	}
+	return phases
+}
+
+// startPhase starts each port-forward of a phase that hasn't already been started by a
+// handover, with its traffic capture when capture.start is set
+func (m *PortForwardManager) startPhase(phase []*PortForward) {
+	for _, pf := range phase {
+		if pf.Config.Capture != nil && pf.Config.Capture.Start {
+			if _, err := m.StartCapture(pf); err != nil {
+				slog.Error("Failed to start traffic capture", "cluster", pf.ClusterName, "service", pf.Config.Service, "error", err)
+			}
+		}
+		m.StartForward(pf)
+	}
+}
+
+// startLaterPhases starts the phases after the first one, each once the previous one is
+// established and start_delay passed. Port-forwards disabled, paused or removed while
+// waiting are skipped; stopping the manager ends it.
+func (m *PortForwardManager) startLaterPhases(previous []*PortForward, phases [][]*PortForward) {
+	for _, phase := range phases {
+		if !m.waitForPhase(previous) {
+			return
+		}
+
+		var waiting []*PortForward
+		for _, pf := range phase {
+			if m.GetForward(pf.ID) == pf && pf.GetState() == StateStarting {
+				waiting = append(waiting, pf)
+			}
+		}
+		slog.Info("Starting next port-forwards",
+			"start_order", phase[0].Config.StartOrder,
+			"count", len(waiting),
+		)
+		m.startPhase(waiting)
+		previous = waiting
+	}
+}
+
+// waitForPhase waits until none of the port-forwards of a phase is starting anymore, at
+// most startPhaseTimeout, and then for start_delay. Returns false if the manager was
+// stopped meanwhile.
+func (m *PortForwardManager) waitForPhase(phase []*PortForward) bool {
+	starting := func(pf *PortForward) bool {
+		return pf.GetState() == StateStarting && m.GetForward(pf.ID) == pf
+	}
+	deadline := time.Now().Add(startPhaseTimeout)
+	for slices.ContainsFunc(phase, starting) && time.Now().Before(deadline) {
+		if !m.isRunning() {
+			return false
+		}
+		time.Sleep(startPhaseInterval)
+	}
+
+	time.Sleep(m.config.StartDelay)
+	return m.isRunning()
+}
+
+// isRunning reports whether the manager was started and not stopped since
+func (m *PortForwardManager) isRunning() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.running
+}
+
+// StartForward starts a single port-forward if it isn't running yet
+func (m *PortForwardManager) StartForward(pf *PortForward) {
+	pf.mu.Lock()
+	if pf.started {
+		pf.mu.Unlock()
+		return
+	}
+	pf.started = true
+	pf.done = make(chan struct{})
+	pf.mu.Unlock()
+
+	go m.runPortForward(pf)
+}
+
+// AddForward adds a port-forward and starts it if the manager is running. Safe to call
+// concurrently and at any time. The cluster's client is reused if the cluster is already
+// known, otherwise it is loaded first.
+func (m *PortForwardManager) AddForward(cluster ClusterConfig, fwdConfig ForwardConfig) (*PortForward, error) {
+	if err := validateForward(cluster.Name, fwdConfig); err != nil {
+		return nil, err
+	}
+	if fwdConfig.Service == wildcardService {
+		return nil, fmt.Errorf("wildcard forwards can only be configured in the config file")
+	}
+	if fwdConfig.FanOut {
+		return nil, fmt.Errorf("fan_out forwards can only be configured in the config file")
+	}
+
+	m.mu.Lock()
+	clusterClient := m.clusters[cluster.Name]
+	m.mu.Unlock()
+
+	if clusterClient == nil && fwdConfig.IsKubernetes() {
+		var err error
+		clusterClient, err = NewClusterClient(cluster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig for cluster %s: %w", cluster.Name, err)
+		}
+	}
+
+	pf := newPortForward(cluster, fwdConfig, clusterClient)
+
+	m.mu.Lock()
+	if existing := m.clusters[cluster.Name]; existing != nil {
+		pf.cluster = existing
+	} else if clusterClient != nil {
+		m.clusters[cluster.Name] = clusterClient
+	}
+	for _, other := range m.forwards {
+		if other.ID == pf.ID {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("port-forward %s already exists", pf.ID)
+		}
+		// The port the other forward listens on now, which on_conflict may have moved
+		if other.LocalPort() == pf.localPort && addressesOverlap(other.LocalAddress(), pf.LocalAddress()) {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("local port %s is already used by '%s/%s/%s'",
+				net.JoinHostPort(pf.LocalAddress(), strconv.Itoa(pf.localPort)), other.ClusterName, other.Config.Namespace, other.Config.Service)
+		}
+	}
+	m.forwards = append(m.forwards, pf)
+	running := m.running && !m.disabled[cluster.Name] && !m.paused
+	if m.disabled[cluster.Name] {
+		pf.State = StateDisabled
+	} else if m.paused {
+		pf.State = StatePaused
+	}
+	m.mu.Unlock()
+
+	slog.Info("Port-forward added",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+		"local_port", pf.Config.LocalPort,
+	)
+
+	m.emit(Event{Type: EventForwardAdded, Forward: pf.Status()})
+	if running {
+		m.StartForward(pf)
+	}
+
+	return pf, nil
+}
+
+// detachedIDSuffix tells the IDs of detached forwards apart from those of the forwards
+// they were copied from
+const detachedIDSuffix = "#backup"
+
+// StartDetachedForward starts a port-forward that isn't listed with the manager's forwards,
+// so it's neither shown nor health-checked, e.g. a temporary forward for a backup. It
+// listens on a free port of the default loopback address, bound before it returns so no
+// other process can take it, overriding local_port. It has an ID of its own ending in
+// #backup, emits no events and runs no lifecycle hooks.
+func (m *PortForwardManager) StartDetachedForward(clusterName string, fwdConfig ForwardConfig) (*PortForward, error) {
+	m.mu.RLock()
+	clusterClient := m.clusters[clusterName]
+	m.mu.RUnlock()
+	if clusterClient == nil && fwdConfig.IsKubernetes() {
+		return nil, fmt.Errorf("cluster %s has no Kubernetes client", clusterName)
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(defaultBindAddress, "0"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find a free local port: %w", err)
+	}
+	fwdConfig.LocalPort = listener.Addr().(*net.TCPAddr).Port
+
+	var cluster ClusterConfig
+	for _, c := range m.config.Clusters {
+		if c.Name == clusterName {
+			cluster = c
+			break
+		}
+	}
+
+	fwdConfig.Hooks = nil
+	pf := newPortForward(cluster, fwdConfig, clusterClient)
+	pf.ID += detachedIDSuffix
+	pf.BindAddress = defaultBindAddress
+	pf.detached = true
+	pf.handed = newHandedListener(listener)
+	m.StartForward(pf)
+
+	return pf, nil
+}
+
+// StopDetachedForward stops a port-forward started with StartDetachedForward and waits for
+// its listener to close
+func (m *PortForwardManager) StopDetachedForward(pf *PortForward) {
+	pf.mu.Lock()
+	done := pf.done
+	pf.mu.Unlock()
+
+	pf.cancel()
+
+	select {
+	case <-done:
+	case <-time.After(removeForwardTimeout):
+		slog.Warn("Timeout waiting for port-forward to stop", "id", pf.ID)
+	}
+	pf.handed.Close()
+}
+
+// RemoveForward stops a port-forward by ID, runs its pre_stop hook if it was active, waits
+// for its listener to close and removes it from the manager. Safe to call concurrently.
+func (m *PortForwardManager) RemoveForward(id string) error {
+	m.mu.Lock()
+	index := -1
+	for i, other := range m.forwards {
+		if other.ID == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		m.mu.Unlock()
+		return fmt.Errorf("no port-forward %s", id)
+	}
+	pf := m.forwards[index]
+	m.forwards = append(m.forwards[:index:index], m.forwards[index+1:]...)
+	m.mu.Unlock()
+
+	if pf.GetState() == StateActive {
+		runHook(pf, HookPreStop)
+	}
+
+	pf.mu.Lock()
+	done := pf.done
+	cancel := pf.cancel
+	pf.State = StateStopped
+	pf.mu.Unlock()
+
+	cancel()
+
+	slog.Info("Port-forward removed",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+		"local_port", pf.LocalPort(),
+	)
+
+	m.emit(Event{Type: EventForwardRemoved, Forward: pf.Status()})
+
+	if done == nil {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(removeForwardTimeout):
+		return fmt.Errorf("timeout waiting for port %d to be released", pf.LocalPort())
+	}
+}
+
+// GetForward returns the port-forward with an ID, or nil
+func (m *PortForwardManager) GetForward(id string) *PortForward {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, pf := range m.forwards {
+		if pf.ID == id {
+			return pf
+		}
+	}
+	return nil
+}
+
+// FindForward returns the port-forward of a cluster/namespace/service, or nil
+func (m *PortForwardManager) FindForward(cluster, namespace, service string) *PortForward {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, pf := range m.forwards {
+		if pf.ClusterName == cluster && pf.Config.Namespace == namespace && pf.Config.Service == service {
+			return pf
+		}
+	}
+	return nil
+}
+
+// ClusterClient returns the client of a cluster, or nil if the cluster isn't known
+func (m *PortForwardManager) ClusterClient(name string) *ClusterClient {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.clusters[name]
+}
+
+// FailForward marks a port-forward as failed without starting it
+func (m *PortForwardManager) FailForward(pf *PortForward, reason string) {
+	pf.mu.Lock()
+	pf.started = true
+	pf.State = StateFailed
+	pf.Error = reason
+	pf.mu.Unlock()
+
+	m.emitStateChanged(pf)
+}
+
+// FindForwardsByPort returns the port-forwards bound to a local port on an address, or on
+// any address when address is empty
+func (m *PortForwardManager) FindForwardsByPort(address string, port int) []*PortForward {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []*PortForward
+	for _, pf := range m.forwards {
+		if pf.LocalPort() == port && (address == "" || pf.LocalAddress() == address) {
+			result = append(result, pf)
+		}
+	}
+	return result
+}
+
+// ReleasePort stops the port-forwards bound to a local port on an address (any address when
+// empty) and waits until their listeners have been closed, so another process can bind it
+func (m *PortForwardManager) ReleasePort(address string, port int, timeout time.Duration) error {
+	forwards := m.FindForwardsByPort(address, port)
+	if len(forwards) == 0 {
+		return fmt.Errorf("no port-forward bound to port %d", port)
+	}
+
+	deadline := time.After(timeout)
+
+	for _, pf := range forwards {
+		if pf.GetState() == StateActive {
+			runHook(pf, HookPreStop)
+		}
+
+		pf.mu.Lock()
+		done := pf.done
+		cancel := pf.cancel
+		// Mark as stopped first so the health monitor doesn't revive it
+		pf.State = StateStopped
+		pf.mu.Unlock()
+
+		cancel()
+
+		if done == nil {
+			continue
+		}
+
+		select {
+		case <-done:
+			slog.Info("Released port for handover",
+				"cluster", pf.ClusterName,
+				"namespace", pf.Config.Namespace,
+				"service", pf.Config.Service,
+				"local_address", pf.LocalAddress(),
+				"local_port", port,
+			)
+		case <-deadline:
+			return fmt.Errorf("timeout waiting for port %d to be released", port)
+		}
+	}
+
+	return nil
+}
+
 // runPortForward manages the lifecycle of a single port-forward
 func (m *PortForwardManager) runPortForward(pf *PortForward) {
+	defer close(pf.done)
+
+	reconnecting := false
 	for {
 		select {
 		case <-pf.ctx.Done():
 			pf.setState(StateStopped)
-			m.notifyUpdate(pf)
+			m.emitStateChanged(pf)
 			return
 		default:
+			if reconnecting && m.waitReconnectToken(pf) != nil {
+				// Stopped while waiting
+				continue
+			}
+			reconnecting = true
+
 			if err := m.establishPortForward(pf); err != nil {
+				// Expired credentials: rebuild the client and retry right away
+				nextState := StateReconnecting
+				if pf.cluster != nil && (isUnauthorized(err) || errors.Is(err, errClusterUnavailable)) {
+					if refreshErr := pf.cluster.Refresh(); refreshErr == nil {
+						slog.Info("Retrying port-forward with refreshed credentials",
+							"cluster", pf.ClusterName,
+							"service", pf.Config.Service,
+						)
+						continue
+					} else if refreshErr != errRefreshThrottled {
+						slog.Warn("Failed to refresh credentials", "cluster", pf.ClusterName, "error", refreshErr)
+					}
+
+					// Still rejected: wait for new credentials
+					pf.cluster.SetAuthExpired(true)
+					nextState = StateAuthExpired
+				}
+
+				pf.mu.Lock()
+				if pf.failingSince.IsZero() {
+					pf.failingSince = time.Now()
+				}
+				pf.mu.Unlock()
+
+				// Forwards waiting for credentials keep waiting, others may give up
+				if nextState == StateReconnecting {
+					if reason := m.giveUpReason(pf); reason != "" {
+						m.giveUp(pf, reason, err)
+						go runHook(pf, HookOnFailure)
+						return
+					}
+				}
+
 				pf.setError(err.Error())
-				pf.setState(StateReconnecting)
-				m.notifyUpdate(pf)
+				pf.setState(nextState)
+				m.emitStateChanged(pf)
+				go runHook(pf, HookOnFailure)
 
 				// Calculate backoff delay
-				delay := m.calculateBackoff(pf.RetryCount)
+				delay := m.calculateBackoff(pf)
 				pf.mu.Lock()
 				pf.ReconnectAt = time.Now().Add(delay)
 				pf.RetryCount++
+				pf.waitingRetry = true
 				pf.mu.Unlock()
 
-				slog.Warn("Port-forward failed, will retry",
-					"cluster", pf.ClusterName,
-					"namespace", pf.Config.Namespace,
-					"service", pf.Config.Service,
-					"error", err.Error(),
-					"retry_in", delay,
-					"retry_count", pf.RetryCount,
-				)
+				// While the API server doesn't answer, the cluster's forwards wait for
+				// it to come back instead of each retrying on its own
+				retry := time.After(delay)
+				reachable := pf.clusterReachable()
+				if nextState == StateReconnecting && pf.cluster != nil && pf.cluster.Unreachable() {
+					retry = nil
+					pf.mu.Lock()
+					pf.ReconnectAt = time.Time{}
+					pf.mu.Unlock()
+
+					slog.Warn("Port-forward failed, will retry once the API server is reachable",
+						"cluster", pf.ClusterName,
+						"namespace", pf.Config.Namespace,
+						"service", pf.Config.Service,
+						"error", err.Error(),
+						"retry_count", pf.RetryCount,
+					)
+				} else {
+					slog.Warn("Port-forward failed, will retry",
+						"cluster", pf.ClusterName,
+						"namespace", pf.Config.Namespace,
+						"service", pf.Config.Service,
+						"error", err.Error(),
+						"retry_in", delay,
+						"retry_count", pf.RetryCount,
+					)
+				}
 
 				select {
-				case <-time.After(delay):
-					continue
+				case <-retry:
+				case <-reachable:
+				case <-pf.credentialsRefreshed():
+					// Another forward obtained new credentials for this cluster
+				case <-pf.retryNow:
 				case <-pf.ctx.Done():
+					pf.endRetryWait()
+					pf.setState(StateStopped)
+					m.emitStateChanged(pf)
 					return
 				}
+				pf.endRetryWait()
+			}
+		}
+	}
+}
+
+// endRetryWait marks the backoff delay as over and drops a retry requested while it ended
+func (pf *PortForward) endRetryWait() {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	pf.waitingRetry = false
+	select {
+	case <-pf.retryNow:
+	default:
+	}
+}
+
+// giveUpReason returns why a failing port-forward should stop retrying, or "" to keep retrying
+func (m *PortForwardManager) giveUpReason(pf *PortForward) string {
+	pf.mu.RLock()
+	defer pf.mu.RUnlock()
+
+	if m.config.MaxRetries > 0 && pf.RetryCount >= m.config.MaxRetries {
+		return fmt.Sprintf("gave up after %d retries", pf.RetryCount)
+	}
+	if m.config.RetryWindow > 0 && time.Since(pf.failingSince) >= m.config.RetryWindow {
+		return fmt.Sprintf("gave up after failing for %s", formatDuration(time.Since(pf.failingSince)))
+	}
+	return ""
+}
+
+// giveUp moves a port-forward to the failed state for good. Its goroutine ends, RetryForward
+// starts it again.
+func (m *PortForwardManager) giveUp(pf *PortForward, reason string, err error) {
+	pf.mu.Lock()
+	pf.State = StateFailed
+	pf.Error = fmt.Sprintf("%s: %v", reason, err)
+	pf.ReconnectAt = time.Time{}
+	pf.started = false
+	pf.mu.Unlock()
+	m.emitStateChanged(pf)
+
+	slog.Error("Port-forward failed permanently",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+		"reason", reason,
+		"error", err.Error(),
+	)
+}
+
+// RetryForward skips the remaining backoff delay of a reconnecting port-forward, or restarts
+// a failed one, whether it gave up retrying or failed to start
+func (m *PortForwardManager) RetryForward(id string) error {
+	pf := m.GetForward(id)
+	if pf == nil {
+		return fmt.Errorf("no port-forward %s", id)
+	}
+
+	pf.mu.Lock()
+	switch pf.State {
+	case StateReconnecting, StateAuthExpired:
+		pf.ReconnectAt = time.Now()
+		// Signalled under the lock, so a retry requested while the forward is connecting
+		// doesn't cut its next backoff delay short
+		if pf.waitingRetry {
+			select {
+			case pf.retryNow <- struct{}{}:
+			default:
+				// A retry is already pending
 			}
 		}
+		pf.mu.Unlock()
+
+		slog.Info("Retrying port-forward now",
+			"cluster", pf.ClusterName,
+			"namespace", pf.Config.Namespace,
+			"service", pf.Config.Service,
+		)
+		m.emitStateChanged(pf)
+		return nil
+	case StateFailed:
+	default:
+		state := pf.State
+		pf.mu.Unlock()
+		return fmt.Errorf("port-forward %s is %s, nothing to retry", id, state)
+	}
+
+	if pf.done != nil {
+		select {
+		case <-pf.done:
+		default:
+			pf.mu.Unlock()
+			return fmt.Errorf("port-forward %s is still stopping", id)
+		}
 	}
+	pf.mu.Unlock()
+
+	slog.Info("Retrying failed port-forward",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+	)
+	m.restartForward(pf)
+
+	return nil
+}
+
+// restartForward starts a port-forward whose goroutine has ended from scratch
+func (m *PortForwardManager) restartForward(pf *PortForward) {
+	pf.mu.Lock()
+	pf.started = false
+	pf.State = StateStarting
+	pf.Error = ""
+	pf.RetryCount = 0
+	pf.ReconnectAt = time.Time{}
+	pf.failingSince = time.Time{}
+	pf.mu.Unlock()
+	m.emitStateChanged(pf)
+
+	m.mu.RLock()
+	running := m.running
+	m.mu.RUnlock()
+	if running {
+		m.StartForward(pf)
+	}
+}
+
+// RetryForwards retries every reconnecting or failed port-forward now and returns how many
+// were retried
+func (m *PortForwardManager) RetryForwards() int {
+	retried := 0
+	for _, pf := range m.GetForwards() {
+		if pf.Retryable() && m.RetryForward(pf.ID) == nil {
+			retried++
+		}
+	}
+	return retried
+}
+
+// DisableCluster stops all port-forwards of a cluster and keeps them from reconnecting
+// until EnableCluster is called
+func (m *PortForwardManager) DisableCluster(name string) error {
+	if findCluster(m.config, name) == nil {
+		return fmt.Errorf("no cluster %s", name)
+	}
+
+	m.mu.Lock()
+	if m.disabled[name] {
+		m.mu.Unlock()
+		return fmt.Errorf("cluster %s is already disabled", name)
+	}
+	m.disabled[name] = true
+	forwards := m.clusterForwards(name)
+	m.mu.Unlock()
+
+	slog.Info("Disabling cluster", "cluster", name, "forwards", len(forwards))
+
+	var wg sync.WaitGroup
+	for _, pf := range forwards {
+		wg.Add(1)
+		go func(pf *PortForward) {
+			defer wg.Done()
+			m.suspendForward(pf, StateDisabled)
+		}(pf)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// suspendForward stops a port-forward, waits for its goroutine to end and leaves it in
+// state (disabled or paused) until it's restarted
+func (m *PortForwardManager) suspendForward(pf *PortForward, state ForwardState) {
+	if pf.GetState() == StateActive {
+		runHook(pf, HookPreStop)
+	}
+
+	pf.mu.Lock()
+	done := pf.done
+	started := pf.started
+	cancel := pf.cancel
+	pf.mu.Unlock()
+
+	cancel()
+
+	if started && done != nil {
+		select {
+		case <-done:
+		case <-time.After(removeForwardTimeout):
+			slog.Warn("Timeout waiting for port-forward to stop",
+				"cluster", pf.ClusterName,
+				"namespace", pf.Config.Namespace,
+				"service", pf.Config.Service,
+			)
+		}
+	}
+
+	ctx, newCancel := context.WithCancel(context.Background())
+	pf.mu.Lock()
+	pf.State = state
+	pf.Error = ""
+	pf.ReconnectAt = time.Time{}
+	pf.started = false
+	pf.ctx = ctx
+	pf.cancel = newCancel
+	pf.mu.Unlock()
+	m.emitStateChanged(pf)
+}
+
+// EnableCluster starts the port-forwards of a cluster disabled with DisableCluster again
+func (m *PortForwardManager) EnableCluster(name string) error {
+	m.mu.Lock()
+	if !m.disabled[name] {
+		m.mu.Unlock()
+		return fmt.Errorf("cluster %s is not disabled", name)
+	}
+	delete(m.disabled, name)
+	forwards := m.clusterForwards(name)
+	paused := m.paused
+	m.mu.Unlock()
+
+	slog.Info("Enabling cluster", "cluster", name, "forwards", len(forwards))
+
+	for _, pf := range forwards {
+		if paused {
+			// Started with the others on ResumeAll
+			pf.mu.Lock()
+			pf.State = StatePaused
+			pf.mu.Unlock()
+			m.emitStateChanged(pf)
+			continue
+		}
+		m.restartForward(pf)
+	}
+
+	return nil
+}
+
+// ReconnectCluster re-establishes the active port-forwards of a cluster, e.g. after its
+// kubeconfig changed
+func (m *PortForwardManager) ReconnectCluster(name string) {
+	m.mu.RLock()
+	forwards := m.clusterForwards(name)
+	m.mu.RUnlock()
+
+	for _, pf := range forwards {
+		if pf.GetState() == StateActive {
+			pf.reconnect()
+		}
+	}
+}
+
+// ClusterDisabled reports whether a cluster was disabled with DisableCluster
+func (m *PortForwardManager) ClusterDisabled(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.disabled[name]
+}
+
+// clusterForwards returns the port-forwards of a cluster. The caller must hold m.mu.
+func (m *PortForwardManager) clusterForwards(name string) []*PortForward {
+	var result []*PortForward
+	for _, pf := range m.forwards {
+		if pf.ClusterName == name {
+			result = append(result, pf)
+		}
+	}
+	return result
+}
+
+// Retryable reports whether the port-forward is waiting for a retry or gave up
+func (pf *PortForward) Retryable() bool {
+	state := pf.GetState()
+	return state == StateReconnecting || state == StateAuthExpired || state == StateFailed
+}
+
+// forwardReady marks a port-forward active once its local port accepts connections
+func (m *PortForwardManager) forwardReady(pf *PortForward) {
+	pf.setState(StateActive)
+	pf.setError("")
+	pf.mu.Lock()
+	pf.RetryCount = 0
+	pf.failingSince = time.Time{}
+	pf.ActiveSince = time.Now()
+	pf.latencies = nil // measured through the previous connection
+	pf.mu.Unlock()
+	m.emitStateChanged(pf)
+
+	slog.Info("Port-forward established",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+		"local_address", pf.LocalAddress(),
+		"local_port", pf.LocalPort(),
+		"remote_port", pf.RemotePort(),
+	)
+
+	go runHook(pf, HookPostStart)
+}
+
+// credentialsRefreshed returns a channel closed when the forward's cluster gets new
+// credentials; forwards not using Kubernetes get a channel that is never closed
+func (pf *PortForward) credentialsRefreshed() <-chan struct{} {
+	if pf.cluster == nil {
+		return nil
+	}
+	return pf.cluster.Refreshed()
+}
+
+// clusterReachable returns a channel closed when the forward's cluster API server answers
+// again; forwards not using Kubernetes get a channel that is never closed
+func (pf *PortForward) clusterReachable() <-chan struct{} {
+	if pf.cluster == nil {
+		return nil
+	}
+	return pf.cluster.Reachable()
+}
+
+// waitReconnectToken waits for a token of the reconnect_rate bucket of a Kubernetes
+// forward's cluster, so a cluster coming back isn't hit by all its forwards at once
+func (m *PortForwardManager) waitReconnectToken(pf *PortForward) error {
+	if pf.cluster == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	limiter := m.reconnectLimits[pf.ClusterName]
+	if limiter == nil {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(float32(m.config.ReconnectRate), m.config.ReconnectBurst)
+		m.reconnectLimits[pf.ClusterName] = limiter
+	}
+	m.mu.Unlock()
+
+	return limiter.Wait(pf.ctx)
+}
+
+// acquireDialSlot waits until fewer than startup_concurrency port-forwards are being
+// established and dialStagger has passed since the previous one started. The returned
+// function frees the slot and may be called more than once.
+func (m *PortForwardManager) acquireDialSlot(ctx context.Context) (func(), error) {
+	if m.dialSlots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case m.dialSlots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	m.dialMu.Lock()
+	wait := time.Until(m.nextDial)
+	m.nextDial = time.Now().Add(max(wait, 0) + dialStagger)
+	m.dialMu.Unlock()
+
+	if wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			<-m.dialSlots
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-m.dialSlots })
+	}, nil
 }
 
 // establishPortForward creates a port-forward connection
 func (m *PortForwardManager) establishPortForward(pf *PortForward) error {
+	release, err := m.acquireDialSlot(pf.ctx)
+	if err != nil {
+		// Stopped while waiting
+		return nil
+	}
+	defer release()
+
+	if pf.Config.OnConflict == onConflictReassign {
+		if err := m.reassignPort(pf); err != nil {
+			return err
+		}
+	}
+
+	switch pf.Config.Type {
+	case "ssh":
+		return m.establishSSHForward(pf, release)
+	case "docker":
+		return m.establishDockerForward(pf, release)
+	case "tcp":
+		return m.establishTCPForward(pf, release)
+	}
+
+	if err := pf.cluster.Err(); err != nil {
+		return err
+	}
+
 	// Find the target pod
-	podName, err := m.findPod(pf)
+	pod, err := m.findPod(pf)
 	if err != nil {
 		return fmt.Errorf("failed to find pod: %w", err)
 	}
+	podName := pod.Name
+
+	remotePort, err := resolveRemotePort(pod, pf.Config)
+	if err != nil {
+		return err
+	}
+	pf.mu.Lock()
+	pf.remotePort = remotePort
+	pf.mu.Unlock()
+
+	// A recreated pod of a StatefulSet keeps its name, so pods are told apart by UID
+	if previousPod, switched := pf.setPod(pod.Name, string(pod.UID)); switched {
+		slog.Info("Port-forward switched pods",
+			"cluster", pf.ClusterName,
+			"namespace", pf.Config.Namespace,
+			"service", pf.Config.Service,
+			"previous_pod", previousPod,
+			"pod", podName,
+			"uid", pod.UID,
+		)
+		m.emit(Event{Type: EventPodSwitched, Forward: pf.Status(), PreviousPod: previousPod})
+	}
+
+	if pf.Config.Primary != "" {
+		defer m.watchPrimary(pf, podName)()
+	}
+
+	if pf.cluster.config.Engine == engineKubectl {
+		return m.establishKubectlForward(pf, podName, remotePort, release)
+	}
 
 	// Create port-forward request
 	path := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/portforward",
 		pf.Config.Namespace, podName)
 
-	hostIP := pf.restConfig.Host
+	restConfig, _ := pf.cluster.Get()
+
+	hostIP := restConfig.Host
 	serverURL, err := url.Parse(hostIP)
 	if err != nil {
 		return fmt.Errorf("failed to parse API server URL: %w", err)
 	}
 	serverURL.Path = path
 
-	transport, upgrader, err := spdy.RoundTripperFor(pf.restConfig)
+	transport, upgrader, err := spdy.RoundTripperFor(restConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create SPDY round tripper: %w", err)
 	}
@@ -194,9 +1202,25 @@ func (m *PortForwardManager) establishPortForward(pf *PortForward) error {
 	stopChan := make(chan struct{}, 1)
 	readyChan := make(chan struct{})
 
-	ports := []string{fmt.Sprintf("%d:%d", pf.Config.LocalPort, pf.Config.RemotePort)}
+	ports := []string{fmt.Sprintf("%d:%d", pf.LocalPort(), remotePort)}
+
+	// Without a bind address keep listening on both 127.0.0.1 and ::1
+	addresses := []string{"localhost"}
+	if pf.BindAddress != "" {
+		addresses = []string{pf.BindAddress}
+	}
+
+	// Behind nanoporter's proxy client-go listens on a random port
+	proxied := m.proxied(pf)
+	pf.mu.Lock()
+	pf.viaProxy = proxied
+	pf.mu.Unlock()
+	if proxied {
+		ports = []string{fmt.Sprintf("0:%d", remotePort)}
+		addresses = []string{defaultBindAddress}
+	}
 
-	fw, err := portforward.New(dialer, ports, stopChan, readyChan, nil, nil)
+	fw, err := portforward.NewOnAddresses(dialer, addresses, ports, stopChan, readyChan, nil, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create port forwarder: %w", err)
 	}
@@ -209,20 +1233,29 @@ func (m *PortForwardManager) establishPortForward(pf *PortForward) error {
 	// Wait for ready or error
 	select {
 	case <-readyChan:
-		pf.setState(StateActive)
-		pf.setError("")
-		pf.mu.Lock()
-		pf.RetryCount = 0
-		pf.mu.Unlock()
-		m.notifyUpdate(pf)
+		if proxied {
+			forwarded, err := fw.GetPorts()
+			if err == nil && len(forwarded) == 0 {
+				err = fmt.Errorf("no ports forwarded")
+			}
+			if err != nil {
+				close(stopChan)
+				<-errChan
+				return fmt.Errorf("failed to get the port-forward's internal port: %w", err)
+			}
+			internal := net.JoinHostPort(defaultBindAddress, strconv.Itoa(int(forwarded[0].Local)))
+			listener, err := m.proxyListener(pf, internal)
+			if err != nil {
+				close(stopChan)
+				<-errChan
+				return err
+			}
+			defer listener.Close()
+		}
 
-		slog.Info("Port-forward established",
-			"cluster", pf.ClusterName,
-			"namespace", pf.Config.Namespace,
-			"service", pf.Config.Service,
-			"local_port", pf.Config.LocalPort,
-			"remote_port", pf.Config.RemotePort,
-		)
+		release()
+		pf.cluster.SetAuthExpired(false)
+		m.forwardReady(pf)
 
 		// Wait for error or stop
 		select {
@@ -233,6 +1266,8 @@ func (m *PortForwardManager) establishPortForward(pf *PortForward) error {
 			return fmt.Errorf("port-forward closed unexpectedly")
 		case <-pf.ctx.Done():
 			close(stopChan)
+			// Wait for the forwarder to close its listeners so the port is free
+			<-errChan
 			return nil
 		}
 
@@ -245,45 +1280,137 @@ func (m *PortForwardManager) establishPortForward(pf *PortForward) error {
 }
 
 // findPod finds the appropriate pod for port-forwarding
-func (m *PortForwardManager) findPod(pf *PortForward) (string, error) {
+func (m *PortForwardManager) findPod(pf *PortForward) (*corev1.Pod, error) {
+	pods, err := targetPods(pf)
+	if err != nil {
+		return nil, err
+	}
+
+	if pf.Config.Type == "pod" {
+		if pods[0].Status.Phase != corev1.PodRunning {
+			return nil, fmt.Errorf("pod is not running: %s", pods[0].Status.Phase)
+		}
+		return pods[0], nil
+	}
+
+	if pf.Config.Primary != "" {
+		if pods, err = primaryPods(pf, pods); err != nil {
+			return nil, err
+		}
+	}
+
+	// Find first running pod
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning {
+			return pod, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no running pods found for service %s", pf.Config.Service)
+}
+
+// targetPods returns the pod a forward targets, or the pods of its service, from the
+// cluster's cache of the namespace when it's available and from the API server otherwise
+func targetPods(pf *PortForward) ([]*corev1.Pod, error) {
+	if nc := pf.cluster.namespaceCache(pf.Config.Namespace); nc != nil {
+		return cachedTargetPods(pf, nc)
+	}
+	return listTargetPods(pf)
+}
+
+// cachedTargetPods returns the pod a forward targets, or the pods selected by its service,
+// from the namespace's cache. Cached objects are shared and must not be modified.
+func cachedTargetPods(pf *PortForward, nc *namespaceCache) ([]*corev1.Pod, error) {
+	if pf.Config.Type == "pod" {
+		pod, err := nc.pod(pf.Config.Service)
+		if err != nil {
+			return nil, err
+		}
+		return []*corev1.Pod{pod}, nil
+	}
+
+	svc, err := nc.service(pf.Config.Service)
+	if err != nil {
+		return nil, err
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return endpointPods(pf, nc, svc)
+	}
+	return nc.selectPods(svc.Spec.Selector)
+}
+
+// resolveRemotePort returns the port of a pod a Kubernetes forward connects to: its named
+// container port, or remote_port once a pod target is known to declare it. Pods that
+// declare no ports, and the pods of services, are forwarded to as configured.
+func resolveRemotePort(pod *corev1.Pod, forward ForwardConfig) (int, error) {
+	var declared []string
+	for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+		for _, port := range container.Ports {
+			if forward.RemotePortName != "" && port.Name == forward.RemotePortName {
+				return int(port.ContainerPort), nil
+			}
+			if forward.RemotePortName == "" && int(port.ContainerPort) == forward.RemotePort {
+				return forward.RemotePort, nil
+			}
+			label := strconv.Itoa(int(port.ContainerPort))
+			if port.Name != "" {
+				label = port.Name + "=" + label
+			}
+			declared = append(declared, label)
+		}
+	}
+	if forward.RemotePortName == "" && (forward.Type != "pod" || len(declared) == 0) {
+		return forward.RemotePort, nil
+	}
+
+	available := "none declared"
+	if len(declared) > 0 {
+		available = strings.Join(declared, ", ")
+	}
+	return 0, fmt.Errorf("pod %s has no container port %s (available: %s)", pod.Name, forward.RemotePortLabel(), available)
+}
+
+// listTargetPods returns the pod a forward targets, or the pods selected by its service,
+// from the API server
+func listTargetPods(pf *PortForward) ([]*corev1.Pod, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	_, client := pf.cluster.Get()
+
 	if pf.Config.Type == "pod" {
 		// Direct pod reference
-		pod, err := pf.client.CoreV1().Pods(pf.Config.Namespace).Get(ctx, pf.Config.Service, metav1.GetOptions{})
+		pod, err := client.CoreV1().Pods(pf.Config.Namespace).Get(ctx, pf.Config.Service, metav1.GetOptions{})
 		if err != nil {
-			return "", err
-		}
-		if pod.Status.Phase != corev1.PodRunning {
-			return "", fmt.Errorf("pod is not running: %s", pod.Status.Phase)
+			return nil, err
 		}
-		return pod.Name, nil
+		return []*corev1.Pod{pod}, nil
 	}
 
 	// Service reference - find pod via selector
-	svc, err := pf.client.CoreV1().Services(pf.Config.Namespace).Get(ctx, pf.Config.Service, metav1.GetOptions{})
+	svc, err := client.CoreV1().Services(pf.Config.Namespace).Get(ctx, pf.Config.Service, metav1.GetOptions{})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if len(svc.Spec.Selector) == 0 {
+		return endpointPods(pf, nil, svc)
 	}
 
 	// List pods matching service selector
 	selector := metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: svc.Spec.Selector})
-	pods, err := pf.client.CoreV1().Pods(pf.Config.Namespace).List(ctx, metav1.ListOptions{
+	list, err := client.CoreV1().Pods(pf.Config.Namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: selector,
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Find first running pod
-	for _, pod := range pods.Items {
-		if pod.Status.Phase == corev1.PodRunning {
-			return pod.Name, nil
-		}
+	pods := make([]*corev1.Pod, len(list.Items))
+	for i := range list.Items {
+		pods[i] = &list.Items[i]
 	}
-
-	return "", fmt.Errorf("no running pods found for service %s", pf.Config.Service)
+	return pods, nil
 }
 
 // healthMonitor continuously checks port-forward health
@@ -300,6 +1427,7 @@ func (m *PortForwardManager) healthMonitor() {
 		for _, pf := range forwards {
 			go m.checkHealth(pf)
 		}
+		m.probeClusters()
 	}
 }
 
@@ -316,7 +1444,7 @@ func (m *PortForwardManager) checkHealth(pf *PortForward) {
 	}
 
 	// Try to connect to local port
-	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", pf.Config.LocalPort), 2*time.Second)
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(pf.LocalAddress(), strconv.Itoa(pf.LocalPort())), 2*time.Second)
 	if err != nil {
 		slog.Warn("Health check failed",
 			"cluster", pf.ClusterName,
@@ -324,34 +1452,87 @@ func (m *PortForwardManager) checkHealth(pf *PortForward) {
 			"service", pf.Config.Service,
 			"error", err.Error(),
 		)
+		m.emit(Event{Type: EventHealthCheckFailed, Forward: pf.Status(), Error: err.Error()})
 
-		// Trigger reconnection by canceling context
-		pf.cancel()
-
-		// Create new context for next attempt
-		ctx, cancel := context.WithCancel(context.Background())
-		pf.mu.Lock()
-		pf.ctx = ctx
-		pf.cancel = cancel
-		pf.mu.Unlock()
+		pf.reconnect()
+		return
+	}
+	defer conn.Close()
+	pf.healthCheckConnected(conn)
 
+	// The dial only reaches the local listener; a ping has to go through the tunnel
+	protocol := pingProtocol(pf.Config)
+	if protocol == "" {
 		return
 	}
-	conn.Close()
+	if pf.Config.LocalTLS != nil {
+		// The listener terminates TLS; the protocol behind it is pinged through it
+		conn = tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	}
+	latency, err := ping(conn, protocol)
+	if err != nil {
+		slog.Debug("Ping failed",
+			"cluster", pf.ClusterName,
+			"namespace", pf.Config.Namespace,
+			"service", pf.Config.Service,
+			"protocol", protocol,
+			"error", err.Error(),
+		)
+		return
+	}
+	pf.recordLatency(latency)
 }
 
-// calculateBackoff returns the delay for the next reconnection attempt
-func (m *PortForwardManager) calculateBackoff(retryCount int) time.Duration {
-	if retryCount == 0 {
-		return m.config.ReconnectDelay
+// reconnect closes the current connection of a port-forward; runPortForward then establishes
+// a new one
+func (pf *PortForward) reconnect() {
+	// Swap in the context for the next attempt before canceling the current one
+	ctx, cancel := context.WithCancel(context.Background())
+	pf.mu.Lock()
+	previousCancel := pf.cancel
+	pf.ctx = ctx
+	pf.cancel = cancel
+	pf.mu.Unlock()
+
+	previousCancel()
+}
+
+// calculateBackoff returns the delay for the next reconnection attempt:
+// base * multiplier^retries, capped at max and randomized by +/- jitter
+func (m *PortForwardManager) calculateBackoff(pf *PortForward) time.Duration {
+	backoff := pf.backoff
+	if backoff.Base == 0 && m.config.Backoff != nil {
+		backoff = *m.config.Backoff
+	}
+	if backoff.Base == 0 {
+		backoff.Base = m.config.ReconnectDelay
+	}
+
+	pf.mu.Lock()
+	retryCount := pf.RetryCount
+	pf.mu.Unlock()
+
+	delay := float64(backoff.Base) * math.Pow(max(backoff.Multiplier, 1), float64(retryCount))
+	if backoff.Max > 0 && delay > float64(backoff.Max) {
+		delay = float64(backoff.Max)
 	}
 
-	// Exponential backoff: 2^n seconds, max 60 seconds
-	delay := time.Duration(1<<uint(retryCount)) * time.Second
-	if delay > 60*time.Second {
-		delay = 60 * time.Second
+	if backoff.Jitter != nil && *backoff.Jitter > 0 {
+		delay *= 1 + *backoff.Jitter*(2*rand.Float64()-1)
 	}
-	return delay
+
+	return time.Duration(delay)
+}
+
+// Snapshot returns the status of all port-forwards
+func (m *PortForwardManager) Snapshot() []ForwardStatus {
+	forwards := m.GetForwards()
+
+	statuses := make([]ForwardStatus, 0, len(forwards))
+	for _, pf := range forwards {
+		statuses = append(statuses, pf.Status())
+	}
+	return statuses
 }
 
 // GetForwards returns all port-forwards
@@ -364,27 +1545,31 @@ func (m *PortForwardManager) GetForwards() []*PortForward {
 	return result
 }
 
-// GetUpdateChannel returns the channel for receiving updates
-func (m *PortForwardManager) GetUpdateChannel() <-chan *PortForward {
-	return m.updateChan
-}
-
-// Stop gracefully stops all port-forwards
+// Stop gracefully stops all port-forwards, running their pre_stop hooks first
 func (m *PortForwardManager) Stop() {
+	// Forwards added from now on aren't started
+	m.mu.Lock()
+	m.running = false
+	m.mu.Unlock()
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	var wg sync.WaitGroup
 	for _, pf := range m.forwards {
-		pf.cancel()
+		if pf.GetState() != StateActive {
+			continue
+		}
+		wg.Add(1)
+		go func(pf *PortForward) {
+			defer wg.Done()
+			runHook(pf, HookPreStop)
+		}(pf)
 	}
-}
+	wg.Wait()
 
-// notifyUpdate sends an update notification
-func (m *PortForwardManager) notifyUpdate(pf *PortForward) {
-	select {
-	case m.updateChan <- pf:
-	default:
-		// Channel full, skip update
+	for _, pf := range m.forwards {
+		pf.cancel()
 	}
 }
 
@@ -402,6 +1587,13 @@ func (pf *PortForward) setError(err string) {
 	pf.Error = err
 }
 
+// GetBackupState returns the state of the forward's database backup
+func (pf *PortForward) GetBackupState() BackupState {
+	pf.mu.RLock()
+	defer pf.mu.RUnlock()
+	return pf.BackupState
+}
+
 // setBackupState updates the backup state
 func (pf *PortForward) setBackupState(state BackupState) {
 	pf.mu.Lock()
@@ -409,6 +1601,19 @@ func (pf *PortForward) setBackupState(state BackupState) {
 	pf.BackupState = state
 }
 
+// claimBackup marks the backup pending unless one is pending or running already, returning
+// the state it replaced
+func (pf *PortForward) claimBackup() (BackupState, bool) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	previous := pf.BackupState
+	if previous == BackupPending || previous == BackupRunning {
+		return previous, false
+	}
+	pf.BackupState = BackupPending
+	return previous, true
+}
+
 // setBackupError updates the backup error message
 func (pf *PortForward) setBackupError(err string) {
 	pf.mu.Lock()
@@ -426,6 +1631,170 @@ func (pf *PortForward) setBackupCompleted(sizeMB float64) {
 	pf.BackupError = ""
 }
 
+// restoreBackupState sets the backup metadata recorded before a restart
+func (pf *PortForward) restoreBackupState(record *backupRecord) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	pf.BackupState = record.State
+	pf.BackupError = record.Error
+	pf.BackupTime = record.Time
+	pf.BackupSizeMB = record.SizeMB
+}
+
+// ForwardStatus is a point-in-time copy of a port-forward's state
+type ForwardStatus struct {
+	ID              string             `json:"id"`
+	Cluster         string             `json:"cluster"`
+	Name            string             `json:"name,omitempty"`
+	Namespace       string             `json:"namespace"`
+	Service         string             `json:"service"`
+	Type            string             `json:"type"`
+	LocalAddress    string             `json:"local_address"`
+	LocalPort       int                `json:"local_port"`
+	RemotePort      int                `json:"remote_port"`                // 0 until a named container port is resolved
+	RemotePortName  string             `json:"remote_port_name,omitempty"` // container port name given as remote_port
+	Pod             string             `json:"pod,omitempty"`
+	PodUID          string             `json:"pod_uid,omitempty"`
+	PodSince        time.Time          `json:"pod_since,omitzero"`
+	Scheme          string             `json:"scheme,omitempty"`
+	LocalTLS        bool               `json:"local_tls,omitempty"`
+	State           ForwardState       `json:"state"`
+	Error           string             `json:"error,omitempty"`
+	RetryCount      int                `json:"retry_count"`
+	LastCheck       time.Time          `json:"last_check,omitzero"`
+	ReconnectAt     time.Time          `json:"reconnect_at,omitzero"`
+	ActiveSince     time.Time          `json:"active_since,omitzero"`
+	StatsSince      time.Time          `json:"stats_since"`    // start of uptime_seconds and reconnects, kept across restarts
+	UptimeSeconds   float64            `json:"uptime_seconds"` // total time active
+	Reconnects      int                `json:"reconnects"`
+	ConfiguredPort  int                `json:"configured_port,omitempty"` // local_port from the config when on_conflict moved the forward
+	Rejected        int                `json:"rejected,omitempty"`        // connections refused by allowed_cidrs
+	Refused         int                `json:"refused,omitempty"`         // connections refused by max_connections
+	OpenConnections int                `json:"open_connections"`
+	MaxConnections  int                `json:"max_connections,omitempty"`
+	Capture         string             `json:"capture,omitempty"`        // file the traffic is being captured to
+	Connections     []ConnectionRecord `json:"connections,omitempty"`    // latest connections, newest first
+	LatencyMS       float64            `json:"latency_ms,omitempty"`     // round trip of the last health check ping
+	LatencyAvgMS    float64            `json:"latency_avg_ms,omitempty"` // average of the last pings
+	HasBackup       bool               `json:"has_backup"`
+	BackupState     BackupState        `json:"backup_state,omitempty"`
+	BackupError     string             `json:"backup_error,omitempty"`
+	BackupTime      time.Time          `json:"backup_time,omitzero"`
+	BackupSizeMB    float64            `json:"backup_size_mb,omitempty"`
+
+	detached bool // of a forward started with StartDetachedForward
+}
+
+// Status returns a snapshot of the port-forward (thread-safe)
+func (pf *PortForward) Status() ForwardStatus {
+	pf.mu.RLock()
+	defer pf.mu.RUnlock()
+
+	latency, latencyAvg := pf.latency()
+	capture := ""
+	if pf.capture != nil && pf.capture.active() {
+		capture = pf.capture.path
+	}
+	configuredPort := 0
+	if pf.localPort != pf.Config.LocalPort {
+		configuredPort = pf.Config.LocalPort
+	}
+	return ForwardStatus{
+		ID:              pf.ID,
+		Cluster:         pf.ClusterName,
+		Name:            pf.Config.Name,
+		Namespace:       pf.Config.Namespace,
+		Service:         pf.Config.Service,
+		Type:            pf.Config.Type,
+		LocalAddress:    pf.LocalAddress(),
+		LocalPort:       pf.localPort,
+		RemotePort:      pf.remotePort,
+		RemotePortName:  pf.Config.RemotePortName,
+		Pod:             pf.pod,
+		PodUID:          pf.podUID,
+		PodSince:        pf.podSince,
+		Scheme:          pf.Config.Scheme,
+		LocalTLS:        pf.Config.LocalTLS != nil,
+		State:           pf.State,
+		Error:           pf.Error,
+		RetryCount:      pf.RetryCount,
+		LastCheck:       pf.LastCheck,
+		ReconnectAt:     pf.ReconnectAt,
+		ActiveSince:     pf.ActiveSince,
+		StatsSince:      pf.statsSince,
+		UptimeSeconds:   pf.totalUptime().Seconds(),
+		Reconnects:      pf.reconnects,
+		ConfiguredPort:  configuredPort,
+		Rejected:        pf.rejected,
+		Refused:         pf.refused,
+		OpenConnections: pf.openConns,
+		MaxConnections:  pf.Config.MaxConnections,
+		Capture:         capture,
+		Connections:     pf.recentConnections(),
+		LatencyMS:       milliseconds(latency),
+		LatencyAvgMS:    milliseconds(latencyAvg),
+		HasBackup:       pf.Config.DBBackup != nil,
+		BackupState:     pf.BackupState,
+		BackupError:     pf.BackupError,
+		BackupTime:      pf.BackupTime,
+		BackupSizeMB:    pf.BackupSizeMB,
+		detached:        pf.detached,
+	}
+}
+
+// setPod records the pod (or container) the forward connects to. Returns the previous pod
+// and whether the forward switched from another one.
+func (pf *PortForward) setPod(name, uid string) (previous string, switched bool) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	previous = pf.pod
+	if name == pf.pod && uid == pf.podUID {
+		return previous, false
+	}
+	switched = pf.pod != ""
+	pf.pod = name
+	pf.podUID = uid
+	pf.podSince = time.Now()
+	return previous, switched
+}
+
+// RemotePortLabel returns the remote port for display: the container port name for a
+// named one, with the port once resolved
+func (fs ForwardStatus) RemotePortLabel() string {
+	switch {
+	case fs.RemotePortName == "":
+		return strconv.Itoa(fs.RemotePort)
+	case fs.RemotePort == 0:
+		return fs.RemotePortName
+	}
+	return fmt.Sprintf("%s(%d)", fs.RemotePortName, fs.RemotePort)
+}
+
+// RemotePort returns the port the forward connects to, once resolved for a named
+// container port (thread-safe)
+func (pf *PortForward) RemotePort() int {
+	pf.mu.RLock()
+	defer pf.mu.RUnlock()
+	return pf.remotePort
+}
+
+// LocalPort returns the port the forward listens on, which on_conflict: reassign may have
+// moved away from local_port (thread-safe)
+func (pf *PortForward) LocalPort() int {
+	pf.mu.RLock()
+	defer pf.mu.RUnlock()
+	return pf.localPort
+}
+
+// LocalAddress returns the loopback address the port-forward listens on
+func (pf *PortForward) LocalAddress() string {
+	if pf.BindAddress != "" {
+		return pf.BindAddress
+	}
+	return defaultBindAddress
+}
+
 // GetState returns the current state (thread-safe)
 func (pf *PortForward) GetState() ForwardState {
 	pf.mu.RLock()
@@ -440,13 +1809,23 @@ func (pf *PortForward) GetError() string {
 	return pf.Error
 }
 
-// loadKubeconfig loads a kubeconfig file and returns a REST config and clientset
-func loadKubeconfig(kubeconfigPath, context string) (*rest.Config, *kubernetes.Clientset, error) {
-	loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath}
+// kubeconfigLoadingRules returns the loading rules for an explicit kubeconfig path, or the
+// default discovery ($KUBECONFIG, which may list several files, then ~/.kube/config) if empty
+func kubeconfigLoadingRules(kubeconfigPath string) *clientcmd.ClientConfigLoadingRules {
+	if kubeconfigPath == "" {
+		return clientcmd.NewDefaultClientConfigLoadingRules()
+	}
+	return &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath}
+}
+
+// loadKubeconfig loads the kubeconfig of a cluster and returns a REST config, with the
+// cluster's client-side throttling and timeout, and clientset
+func loadKubeconfig(cluster ClusterConfig) (*rest.Config, *kubernetes.Clientset, error) {
+	loadingRules := kubeconfigLoadingRules(cluster.Kubeconfig)
 	configOverrides := &clientcmd.ConfigOverrides{}
 
-	if context != "" {
-		configOverrides.CurrentContext = context
+	if cluster.Context != "" {
+		configOverrides.CurrentContext = cluster.Context
 	}
 
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
@@ -455,6 +1834,11 @@ func loadKubeconfig(kubeconfigPath, context string) (*rest.Config, *kubernetes.C
 	if err != nil {
 		return nil, nil, err
 	}
+	config.QPS = cluster.QPS
+	config.Burst = cluster.Burst
+	if cluster.Timeout > 0 {
+		config.Timeout = cluster.Timeout
+	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
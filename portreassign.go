@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+)
+
+// Values of on_conflict
+const (
+	onConflictFail     = "fail"     // a taken local port fails the forward
+	onConflictReassign = "reassign" // a taken local port moves the forward to a free one
+)
+
+// reassignable reports whether all forwards on a local address and port move to another port
+// when it's taken
+func (m *PortForwardManager) reassignable(address string, port int) bool {
+	forwards := m.FindForwardsByPort(address, port)
+	for _, pf := range forwards {
+		if pf.Config.OnConflict != onConflictReassign {
+			return false
+		}
+	}
+	return len(forwards) > 0
+}
+
+// reassignPort moves a forward with on_conflict: reassign to a free local port when another
+// process holds its port. A forward keeps the port it was moved to while that stays free, so
+// clients aren't moved around on every reconnect; when it's taken too, the configured port is
+// tried first.
+func (m *PortForwardManager) reassignPort(pf *PortForward) error {
+	address := pf.LocalAddress()
+
+	port := pf.LocalPort()
+	configured := pf.Config.LocalPort
+
+	if portFree(address, port) {
+		return nil
+	}
+	if port != configured && portFree(address, configured) {
+		m.setLocalPort(pf, configured)
+		return nil
+	}
+
+	from, to := configured+1, 65535
+	if pf.Config.FallbackPorts != "" {
+		// Checked with the config
+		from, to, _ = parsePortRange(pf.Config.FallbackPorts)
+	}
+	used := m.usedPorts(pf, address)
+	for candidate := from; candidate <= to; candidate++ {
+		if !used[candidate] && portFree(address, candidate) {
+			m.setLocalPort(pf, candidate)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("local port %d is in use and no free port was found in %d-%d", configured, from, to)
+}
+
+// setLocalPort records the local port a forward listens on in place of its configured one
+func (m *PortForwardManager) setLocalPort(pf *PortForward, port int) {
+	configured := pf.Config.LocalPort
+
+	pf.mu.Lock()
+	previous := pf.localPort
+	pf.localPort = port
+	pf.mu.Unlock()
+
+	if port == configured {
+		slog.Info("Configured local port free again, moving back",
+			"cluster", pf.ClusterName,
+			"namespace", pf.Config.Namespace,
+			"service", pf.Config.Service,
+			"local_port", port,
+			"previous_port", previous,
+		)
+		return
+	}
+	slog.Warn("Local port in use, reassigned",
+		"cluster", pf.ClusterName,
+		"namespace", pf.Config.Namespace,
+		"service", pf.Config.Service,
+		"configured_port", configured,
+		"local_port", port,
+	)
+}
+
+// usedPorts returns the local ports the other forwards on an address are configured with or
+// were moved to
+func (m *PortForwardManager) usedPorts(pf *PortForward, address string) map[int]bool {
+	used := make(map[int]bool)
+	for _, other := range m.GetForwards() {
+		if other == pf || other.LocalAddress() != address {
+			continue
+		}
+		used[other.Config.LocalPort] = true
+		used[other.LocalPort()] = true
+	}
+	return used
+}
+
+// portFree reports whether a local port can be bound on an address
+func portFree(address string, port int) bool {
+	listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
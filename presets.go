@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// CNPGConfig names the CloudNativePG Cluster a forward connects to; its service, port and
+// backup credentials follow from the operator's naming
+type CNPGConfig struct {
+	Cluster string `yaml:"cluster"`           // name of the Cluster resource
+	Service string `yaml:"service,omitempty"` // "rw" (default, the primary), "ro" (replicas) or "r" (any instance)
+}
+
+// cnpgPort is the port CloudNativePG instances serve PostgreSQL on
+const cnpgPort = 5432
+
+// cnpgFieldMapping maps credential fields to the keys of the app secret CloudNativePG
+// creates for a Cluster
+var cnpgFieldMapping = map[string]string{
+	"database": "dbname",
+	"username": "username",
+	"password": "password",
+}
+
+// applyCNPGDefaults fills in the service, remote port and name of a forward to a
+// CloudNativePG Cluster, and the app secret of its db_backup unless it has credentials
+func applyCNPGDefaults(forward *ForwardConfig) {
+	cnpg := forward.CNPG
+	if cnpg == nil || cnpg.Cluster == "" {
+		return
+	}
+
+	service := cnpg.Service
+	if service == "" {
+		service = "rw"
+	}
+	applyPresetTarget(forward, cnpg.Cluster+"-"+service, cnpgPort, cnpg.Cluster)
+
+	backup := forward.DBBackup
+	if backup == nil || backup.SecretName != "" || backup.SecretPreset != "" || backup.Vault != nil || backup.Password != "" {
+		return
+	}
+	backup.SecretName = cnpg.Cluster + "-app"
+	if len(backup.FieldMapping) == 0 {
+		backup.FieldMapping = maps.Clone(cnpgFieldMapping)
+	}
+}
+
+// applyPresetTarget fills in the service, remote port and name of a forward to a preset's
+// service, unless they are set explicitly
+func applyPresetTarget(forward *ForwardConfig, service string, port int, name string) {
+	if forward.Service == "" {
+		forward.Service = service
+	}
+	if forward.Type == "" {
+		forward.Type = "service"
+	}
+	if forward.RemotePort == 0 && forward.RemotePortName == "" {
+		forward.RemotePort = port
+	}
+	if forward.Name == "" {
+		forward.Name = name
+	}
+}
+
+// validateCNPG checks the cnpg block of a forward
+func validateCNPG(cnpg *CNPGConfig) error {
+	if cnpg.Cluster == "" {
+		return fmt.Errorf("cluster is required")
+	}
+	switch cnpg.Service {
+	case "", "rw", "ro", "r":
+		return nil
+	}
+	return fmt.Errorf("invalid service '%s' (must be 'rw', 'ro' or 'r')", cnpg.Service)
+}
+
+// Secret presets of database operators, usable as a db_backup's secret_preset
+const (
+	secretPresetZalando = "zalando"
+)
+
+// zalandoFieldMapping maps credential fields to the keys of the secrets the Zalando
+// postgres-operator creates per user; they don't name a database
+var zalandoFieldMapping = map[string]string{
+	"username": "username",
+	"password": "password",
+}
+
+// applySecretPreset fills in the secret name and field mapping of a db_backup from its
+// secret_preset, unless they are set explicitly
+func applySecretPreset(backup *DBBackupConfig) {
+	if backup == nil || backup.SecretPreset != secretPresetZalando || backup.Cluster == "" || backup.Username == "" {
+		return
+	}
+
+	// The operator's default secret_name_template, with underscores in the username
+	// replaced as Kubernetes names don't allow them
+	if backup.SecretName == "" {
+		backup.SecretName = fmt.Sprintf("%s.%s.credentials.postgresql.acid.zalan.do",
+			strings.ReplaceAll(backup.Username, "_", "-"), backup.Cluster)
+	}
+	if len(backup.FieldMapping) == 0 {
+		backup.FieldMapping = maps.Clone(zalandoFieldMapping)
+	}
+	// Like libpq, connect to the database named after the user unless told otherwise
+	if backup.Database == "" {
+		backup.Database = backup.Username
+	}
+}
+
+// validateSecretPreset checks the secret_preset of a db_backup and the names it needs
+func validateSecretPreset(backup *DBBackupConfig) error {
+	if backup.SecretPreset != secretPresetZalando {
+		return fmt.Errorf("unknown preset '%s' (must be '%s')", backup.SecretPreset, secretPresetZalando)
+	}
+	switch {
+	case backup.Cluster == "":
+		return fmt.Errorf("cluster is required")
+	case backup.Username == "":
+		return fmt.Errorf("username is required")
+	case strings.Contains(backup.Username, "${") || strings.HasPrefix(backup.Username, keyringPrefix):
+		return fmt.Errorf("username must be a plain name, as it is part of the secret name")
+	case backup.Vault != nil:
+		return fmt.Errorf("can't be combined with vault")
+	case backup.Type != "" && backup.Type != "postgres":
+		return fmt.Errorf("%s is only for postgres backups", backup.SecretPreset)
+	}
+	return nil
+}
+
+// BitnamiConfig names the Helm release of a Bitnami chart a forward connects to; its
+// service, port and backup credentials follow from the chart's naming
+type BitnamiConfig struct {
+	Chart   string `yaml:"chart"`   // "postgresql", "mysql", "mongodb" or "redis"
+	Release string `yaml:"release"` // name of the Helm release
+}
+
+// bitnamiChart is the port and primary service of a Bitnami chart, and for the charts
+// nanoporter can back up the keys of the passwords in its secret
+type bitnamiChart struct {
+	port          int
+	serviceSuffix string // after the release's full name
+	adminUser     string // the user whose password is in adminKey
+	adminKey      string
+	userKey       string // password of the user created by auth.username
+}
+
+// bitnamiCharts are the supported Bitnami charts by name, with their default (standalone)
+// architecture
+var bitnamiCharts = map[string]bitnamiChart{
+	"postgresql": {port: 5432, adminUser: "postgres", adminKey: "postgres-password", userKey: "password"},
+	"mysql":      {port: 3306},
+	"mongodb":    {port: 27017},
+	"redis":      {port: 6379, serviceSuffix: "-master"},
+}
+
+// bitnamiFullname returns the name a Bitnami chart gives its resources, the release name
+// with the chart appended unless it already contains it
+func bitnamiFullname(bitnami *BitnamiConfig) string {
+	if strings.Contains(bitnami.Release, bitnami.Chart) {
+		return bitnami.Release
+	}
+	return bitnami.Release + "-" + bitnami.Chart
+}
+
+// applyBitnamiDefaults fills in the service, remote port and name of a forward to a Bitnami
+// release, and the secret of its db_backup unless it has credentials
+func applyBitnamiDefaults(forward *ForwardConfig) {
+	bitnami := forward.Bitnami
+	if bitnami == nil || bitnami.Release == "" {
+		return
+	}
+	chart, ok := bitnamiCharts[bitnami.Chart]
+	if !ok {
+		return
+	}
+
+	fullname := bitnamiFullname(bitnami)
+	applyPresetTarget(forward, fullname+chart.serviceSuffix, chart.port, fullname)
+
+	backup := forward.DBBackup
+	if backup == nil || backup.SecretName != "" || backup.SecretPreset != "" || backup.Vault != nil || backup.Password != "" {
+		return
+	}
+	backup.SecretName = fullname
+	admin := backup.Username == "" || backup.Username == chart.adminUser
+	if len(backup.FieldMapping) == 0 {
+		// The secret holds only passwords: the admin's, and that of the user the chart
+		// creates when auth.username is set
+		key := chart.userKey
+		if admin {
+			backup.Username = chart.adminUser
+			key = chart.adminKey
+		}
+		backup.FieldMapping = map[string]string{"password": key}
+	}
+	// Everyone may connect to the postgres database, so the chart's user would silently
+	// dump that instead of its own; its database (auth.database) is required instead
+	if backup.Database == "" && admin {
+		backup.Database = "postgres"
+	}
+}
+
+// validateBitnami checks the bitnami block of a forward
+func validateBitnami(forward ForwardConfig) error {
+	bitnami := forward.Bitnami
+	if _, ok := bitnamiCharts[bitnami.Chart]; !ok {
+		return fmt.Errorf("unknown chart '%s' (must be 'postgresql', 'mysql', 'mongodb' or 'redis')", bitnami.Chart)
+	}
+	if bitnami.Release == "" {
+		return fmt.Errorf("release is required")
+	}
+	// Of these charts, nanoporter can only back up PostgreSQL
+	if forward.DBBackup != nil && bitnami.Chart != "postgresql" {
+		return fmt.Errorf("db_backup isn't supported for the %s chart", bitnami.Chart)
+	}
+	if backup := forward.DBBackup; backup != nil && backup.SecretName == bitnamiFullname(bitnami) && backup.Database == "" {
+		return fmt.Errorf("db_backup.database is required for user '%s' (the chart's auth.database)", backup.Username)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// primaryCheckInterval is how often a forward to a primary checks that its pod still is one
+const primaryCheckInterval = 5 * time.Second
+
+// primaryPresets are the label selectors of the primary for common HA operators, usable by
+// name as a forward's primary
+var primaryPresets = map[string]string{
+	"patroni": "role in (master,primary)",
+	"zalando": "spilo-role=master",
+	"cnpg":    "cnpg.io/instanceRole=primary",
+}
+
+// primarySelector parses a forward's primary setting, a preset name or a label selector
+func primarySelector(primary string) (labels.Selector, error) {
+	if preset, ok := primaryPresets[primary]; ok {
+		primary = preset
+	}
+	selector, err := labels.Parse(primary)
+	if err != nil {
+		return nil, err
+	}
+	if selector.Empty() {
+		return nil, fmt.Errorf("selector matches every pod")
+	}
+	return selector, nil
+}
+
+// primaryPods returns the pods of a forward's service that its primary selector matches
+func primaryPods(pf *PortForward, pods []*corev1.Pod) ([]*corev1.Pod, error) {
+	selector, err := primarySelector(pf.Config.Primary)
+	if err != nil {
+		return nil, fmt.Errorf("invalid primary '%s': %w", pf.Config.Primary, err)
+	}
+
+	var primaries []*corev1.Pod
+	for _, pod := range pods {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			primaries = append(primaries, pod)
+		}
+	}
+	if len(primaries) == 0 {
+		return nil, fmt.Errorf("none of the %d pods of service %s is the primary (%s)", len(pods), pf.Config.Service, selector)
+	}
+	return primaries, nil
+}
+
+// watchPrimary reconnects a forward once the pod it connects to is no longer the primary,
+// e.g. after a switchover, so it follows the new one. The returned function stops watching.
+func (m *PortForwardManager) watchPrimary(pf *PortForward, podName string) func() {
+	selector, err := primarySelector(pf.Config.Primary)
+	if err != nil {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(pf.ctx)
+	go func() {
+		ticker := time.NewTicker(primaryCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			pod, err := latestPod(ctx, pf, podName)
+			if err != nil {
+				// A deleted pod breaks the connection by itself
+				continue
+			}
+			if selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+
+			slog.Info("Pod is no longer the primary, reconnecting",
+				"cluster", pf.ClusterName,
+				"namespace", pf.Config.Namespace,
+				"service", pf.Config.Service,
+				"pod", podName,
+			)
+			pf.reconnect()
+			return
+		}
+	}()
+	return cancel
+}
+
+// latestPod returns the current state of the pod a forward connects to
+func latestPod(ctx context.Context, pf *PortForward, podName string) (*corev1.Pod, error) {
+	if nc := pf.cluster.namespaceCache(pf.Config.Namespace); nc != nil {
+		return nc.pod(podName)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, client := pf.cluster.Get()
+	return client.CoreV1().Pods(pf.Config.Namespace).Get(ctx, podName, metav1.GetOptions{})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// backupsDoneMsg is sent once the backups have finished or been cancelled after quitting
+type backupsDoneMsg struct{}
+
+// dialogStyle frames the quit confirmation
+var dialogStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("220")).
+	Padding(0, 1)
+
+// runningBackups returns the forwards whose database is being dumped
+func (m model) runningBackups() []ForwardStatus {
+	var running []ForwardStatus
+	for _, fs := range m.forwards {
+		if fs.BackupState == BackupRunning {
+			running = append(running, fs)
+		}
+	}
+	return running
+}
+
+// requestQuit quits, unless backups are running: stopping the forwards would cut off their
+// dumps, so the quit dialog asks what to do with them first
+func (m model) requestQuit() (tea.Model, tea.Cmd) {
+	if m.backups == nil || len(m.runningBackups()) == 0 {
+		return m.quit()
+	}
+	m.quitDialog = true
+	return m, nil
+}
+
+// updateQuitDialog handles a key while the quit dialog is open or backups are being waited for
+func (m model) updateQuitDialog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+
+	if m.quitWaiting != "" {
+		// Only aborting is left
+		if key == "a" || key == "ctrl+c" {
+			m.backups.Cancel()
+			return m.quit()
+		}
+		return m, nil
+	}
+
+	switch key {
+	case "w":
+		m.quitWaiting = "Waiting for the running backups to finish before quitting..."
+		m.backups.Drain()
+		return m, waitForBackups(m.backups)
+	case "c":
+		m.quitWaiting = "Cancelling the running backups before quitting..."
+		m.backups.Cancel()
+		return m, waitForBackups(m.backups)
+	case "a", "ctrl+c":
+		m.backups.Cancel()
+		return m.quit()
+	case "esc", "n":
+		m.quitDialog = false
+	}
+	return m, nil
+}
+
+// waitForBackups waits until no backup is running or starts anymore
+func waitForBackups(backups *BackupManager) tea.Cmd {
+	return func() tea.Msg {
+		<-backups.Done()
+		backups.WaitTriggered()
+		return backupsDoneMsg{}
+	}
+}
+
+// quitDialogView renders the quit confirmation with the backups in progress
+func (m model) quitDialogView() string {
+	var b strings.Builder
+
+	b.WriteString(failedStyle.Bold(true).Render("Backups are running"))
+	b.WriteString("\n\n")
+	for _, fs := range m.runningBackups() {
+		b.WriteString(fmt.Sprintf("  🔄 %s/%s/%s\n", fs.Cluster, fs.Namespace, fs.Service))
+	}
+	b.WriteString("\n")
+
+	if m.quitWaiting != "" {
+		b.WriteString(m.quitWaiting)
+		b.WriteString("\n")
+		b.WriteString(helpStyle.UnsetMarginTop().Render("a abort now (the running dumps are lost)"))
+	} else {
+		b.WriteString("Quitting now stops the port-forwards the dumps go through.\n")
+		b.WriteString(helpStyle.UnsetMarginTop().Render("w wait for them • c cancel them and remove their partial files • a abort anyway • esc back"))
+	}
+
+	return dialogStyle.Render(b.String())
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tcpDialTimeout bounds connecting to the upstream of a "tcp" forward
+const tcpDialTimeout = 10 * time.Second
+
+// establishTCPForward relays the forward's local port to service:remote_port. The upstream
+// is checked on every health check, so an unreachable host shows up like a lost pod.
+func (m *PortForwardManager) establishTCPForward(pf *PortForward, release func()) error {
+	target := net.JoinHostPort(pf.Config.Service, strconv.Itoa(pf.Config.RemotePort))
+	dial := func() (net.Conn, error) {
+		return net.DialTimeout("tcp", target, tcpDialTimeout)
+	}
+
+	conn, err := dial()
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", target, err)
+	}
+	conn.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	broken := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(m.config.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				conn, err := dial()
+				if err != nil {
+					broken <- fmt.Errorf("upstream %s unreachable: %w", target, err)
+					return
+				}
+				conn.Close()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return m.relayForward(pf, release, dial, broken)
+}
+
+// relayForward serves a port-forward that doesn't go through Kubernetes: it listens on the
+// forward's local port and copies every accepted connection to one opened by dial. It
+// returns nil once the forward is stopped, or the error received from broken when the
+// upstream (e.g. the SSH connection) fails. release frees the startup slot once listening.
+func (m *PortForwardManager) relayForward(pf *PortForward, release func(), dial func() (net.Conn, error), broken <-chan error) error {
+	listener, err := m.listen(pf)
+	if err != nil {
+		return err
+	}
+
+	// Connections still open when the forward stops are closed with it, as they are when a
+	// Kubernetes forward's tunnel goes away
+	conns := newConnSet()
+	defer conns.closeAll()
+
+	accepted := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				accepted <- err
+				return
+			}
+			if !pf.admit(conn) || !conns.add(conn) {
+				conn.Close()
+				continue
+			}
+
+			go func() {
+				defer conns.remove(conn)
+				relayConn(pf, conn, dial)
+			}()
+		}
+	}()
+
+	release()
+	m.forwardReady(pf)
+
+	var result error
+	select {
+	case <-pf.ctx.Done():
+	case err := <-broken:
+		result = err
+	case err := <-accepted:
+		result = fmt.Errorf("listener failed: %w", err)
+	}
+
+	listener.Close()
+	return result
+}
+
+// connSet is the set of connections a relay has accepted and not closed yet
+type connSet struct {
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// newConnSet returns an empty connection set
+func newConnSet() *connSet {
+	return &connSet{conns: make(map[net.Conn]struct{})}
+}
+
+// add adds a connection, returning false once the set has been closed
+func (s *connSet) add(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+// remove removes a connection that has been closed
+func (s *connSet) remove(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// closeAll closes every connection of the set and refuses further ones
+func (s *connSet) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// handedListener is a listener bound before its forward started. Every connection attempt
+// of the forward accepts from it in turn; it stays open until Close.
+type handedListener struct {
+	net.Listener
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newHandedListener starts accepting connections on a listener for the attempts to take
+func newHandedListener(listener net.Listener) *handedListener {
+	h := &handedListener{
+		Listener: listener,
+		conns:    make(chan net.Conn),
+		closed:   make(chan struct{}),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			select {
+			case h.conns <- conn:
+			case <-h.closed:
+				conn.Close()
+				return
+			}
+		}
+	}()
+	return h
+}
+
+// attempt returns the listener of one connection attempt, whose Close leaves h open
+func (h *handedListener) attempt() net.Listener {
+	return &attemptListener{handed: h, done: make(chan struct{})}
+}
+
+// Close closes the underlying listener
+func (h *handedListener) Close() error {
+	h.closeOnce.Do(func() { close(h.closed) })
+	return h.Listener.Close()
+}
+
+// attemptListener accepts the connections of a handedListener until it's closed
+type attemptListener struct {
+	handed    *handedListener
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Accept waits for the next connection of the handed listener
+func (a *attemptListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-a.handed.conns:
+		return conn, nil
+	case <-a.done:
+		return nil, net.ErrClosed
+	case <-a.handed.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close ends this attempt's Accept calls
+func (a *attemptListener) Close() error {
+	a.closeOnce.Do(func() { close(a.done) })
+	return nil
+}
+
+// Addr returns the handed listener's address
+func (a *attemptListener) Addr() net.Addr {
+	return a.handed.Addr()
+}
+
+// listen opens the local listener of a forward with its socket options, terminating TLS
+// when local_tls is set
+func (m *PortForwardManager) listen(pf *PortForward) (net.Listener, error) {
+	tlsConfig, err := localTLSConfig(pf)
+	if err != nil {
+		return nil, err
+	}
+
+	var listener net.Listener
+	if pf.handed != nil {
+		listener = pf.handed.attempt()
+	} else {
+		address := net.JoinHostPort(pf.LocalAddress(), strconv.Itoa(pf.LocalPort()))
+		if listener, err = listenLocal(address, m.socketOptions(pf)); err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	return listener, nil
+}
+
+// relayConn copies data between a local connection and a new upstream connection until
+// either side closes
+func relayConn(pf *PortForward, local net.Conn, dial func() (net.Conn, error)) {
+	defer pf.connectionDone()
+	defer local.Close()
+	record := pf.connectionOpened(local.RemoteAddr().String())
+
+	upstream, err := dial()
+	if err != nil {
+		slog.Warn("Failed to connect upstream",
+			"cluster", pf.ClusterName,
+			"service", pf.Config.Service,
+			"error", err,
+		)
+		pf.connectionClosed(record, 0, 0, err)
+		return
+	}
+	defer upstream.Close()
+
+	// Connections opened while the forward captures are recorded until they close
+	var fromClient, fromServer io.Reader = local, upstream
+	if capture := pf.activeCapture(); capture != nil {
+		id := capture.open(local.RemoteAddr(), local.LocalAddr())
+		defer capture.closeConn(id)
+		fromClient = io.TeeReader(local, captureTap{capture: capture, id: id, fromClient: true})
+		fromServer = io.TeeReader(upstream, captureTap{capture: capture, id: id})
+	}
+
+	var bytesIn, bytesOut int64
+	done := make(chan struct{}, 2)
+	go func() {
+		bytesIn, _ = io.Copy(upstream, fromClient)
+		done <- struct{}{}
+	}()
+	go func() {
+		bytesOut, _ = io.Copy(local, fromServer)
+		done <- struct{}{}
+	}()
+
+	// Closing both sides ends the other copy
+	<-done
+	local.Close()
+	upstream.Close()
+	<-done
+
+	pf.connectionClosed(record, bytesIn, bytesOut, nil)
+}
+
+// proxyListener listens on a Kubernetes forward's local port like listen and relays every
+// allowed connection to the port client-go listens on internally
+func (m *PortForwardManager) proxyListener(pf *PortForward, internal string) (net.Listener, error) {
+	listener, err := m.listen(pf)
+	if err != nil {
+		return nil, err
+	}
+
+	dial := func() (net.Conn, error) {
+		return net.DialTimeout("tcp", internal, tcpDialTimeout)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				// Closed once the forward stops
+				return
+			}
+			if !pf.admit(conn) {
+				conn.Close()
+				continue
+			}
+			go relayConn(pf, conn, dial)
+		}
+	}()
+
+	return listener, nil
+}
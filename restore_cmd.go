@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// restorePassword is the superuser password of local restore containers
+	restorePassword = "postgres"
+	// defaultRestorePort is the local port restore containers are published on
+	defaultRestorePort = 54320
+	// restoreReadyTimeout bounds waiting for a restore container to accept connections
+	restoreReadyTimeout = 60 * time.Second
+)
+
+// dumpVersionPattern finds the server version in the header of a pg_dump file
+var dumpVersionPattern = regexp.MustCompile(`^-- Dumped from database version (\d+)`)
+
+// runRestoreCommand loads a backup into a local PostgreSQL container
+func runRestoreCommand() {
+	restoreFlags := flag.NewFlagSet("restore", flag.ExitOnError)
+	configPath := restoreFlags.String("config", defaultConfigPath, "Path to configuration file")
+	backupDir := restoreFlags.String("dir", defaultBackupDir, "Directory backups are stored in")
+	localDocker := restoreFlags.Bool("local-docker", false, "Restore into a local Docker PostgreSQL container")
+	clusterName := restoreFlags.String("cluster", "", "Cluster of the database when several clusters back up the same service")
+	file := restoreFlags.String("file", "", "Dump to restore (default: the latest backup)")
+	port := restoreFlags.Int("port", defaultRestorePort, "Local port the container is published on")
+	restoreFlags.Parse(os.Args[2:])
+
+	if !*localDocker || restoreFlags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: nanoporter restore --local-docker [--cluster NAME] [--file DUMP] DATABASE")
+		restoreFlags.PrintDefaults()
+		os.Exit(2)
+	}
+	dbName := restoreFlags.Arg(0)
+
+	dump := *file
+	if dump == "" {
+		dir, err := restoreBackupDir(*configPath, *backupDir, *clusterName, dbName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if dump, err = latestDump(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if _, err := restoreIntoDocker(os.Stdout, dump, dbName, *port); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// restoreBackupDir returns the directory the backups of a database are stored in,
+// following backup_dir of the matching forward when the config file has one
+func restoreBackupDir(configPath, backupDir, clusterName, dbName string) (string, error) {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		// Backups are still found in the default layout
+		return filepath.Join(backupDir, dbName), nil
+	}
+
+	var dirs []string
+	for _, cluster := range config.Clusters {
+		if clusterName != "" && cluster.Name != clusterName {
+			continue
+		}
+		for _, forward := range cluster.Forwards {
+			if forward.DBBackup != nil && (forward.Service == dbName || forward.Name == dbName) {
+				dirs = append(dirs, forwardBackupDir(backupDir, cluster.Name, forward.Service, forward))
+			}
+		}
+	}
+
+	switch len(dirs) {
+	case 0:
+		return filepath.Join(backupDir, dbName), nil
+	case 1:
+		return dirs[0], nil
+	default:
+		return "", fmt.Errorf("'%s' is backed up in several clusters, select one with --cluster", dbName)
+	}
+}
+
+// latestDump returns the newest database dump in a directory
+func latestDump(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	// Names end in a sortable timestamp
+	var dumps []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasSuffix(name, ".sql.gz") && !strings.HasSuffix(name, globalsSuffix) {
+			dumps = append(dumps, name)
+		}
+	}
+	if len(dumps) == 0 {
+		return "", fmt.Errorf("no backups found in %s", dir)
+	}
+	sort.Strings(dumps)
+
+	return filepath.Join(dir, dumps[len(dumps)-1]), nil
+}
+
+// restoreIntoDocker starts (or reuses) a PostgreSQL container matching the major version
+// of the dump, replaces the database with the dump and prints how to connect to out.
+// Returns the local port of the container.
+func restoreIntoDocker(out io.Writer, dump, dbName string, port int) (int, error) {
+	// Globals dumped with the database are restored with it
+	files := []string{dump}
+	globals := strings.TrimSuffix(dump, ".sql.gz") + globalsSuffix
+	if _, err := os.Stat(globals); err == nil {
+		files = append(files, globals)
+	} else {
+		globals = ""
+	}
+
+	// Refuse to restore corrupted dumps
+	for _, file := range files {
+		switch err := verifyChecksum(file); {
+		case errors.Is(err, errNoChecksum):
+			fmt.Fprintf(out, "Warning: %s has no checksum, it can't be verified\n", file)
+		case err != nil:
+			return 0, err
+		}
+	}
+
+	major, err := dumpMajorVersion(dump)
+	if err != nil {
+		return 0, err
+	}
+	image := "postgres:" + major
+	container := "nanoporter-restore-" + dbName
+
+	fmt.Fprintf(out, "Restoring %s into %s (%s)\n", dump, container, image)
+	port, err = ensureRestoreContainer(container, image, port)
+	if err != nil {
+		return 0, err
+	}
+
+	// A fresh copy every time; --force (PostgreSQL 13+) disconnects open sessions
+	dropArgs := []string{"exec", container, "dropdb", "-U", "postgres", "--if-exists"}
+	if version, _ := strconv.Atoi(major); version >= 13 {
+		dropArgs = append(dropArgs, "--force")
+	}
+	if _, err := dockerOutput(context.Background(), append(dropArgs, dbName)...); err != nil {
+		return 0, fmt.Errorf("failed to drop the previous copy: %w", err)
+	}
+	if _, err := dockerOutput(context.Background(), "exec", container,
+		"createdb", "-U", "postgres", dbName); err != nil {
+		return 0, fmt.Errorf("failed to create database: %w", err)
+	}
+
+	// Roles first, so grants and ownership in the dump resolve
+	if globals != "" {
+		fmt.Fprintf(out, "Loading global objects from %s\n", globals)
+		if err := loadDump(out, container, "postgres", globals); err != nil {
+			return 0, err
+		}
+	}
+
+	fmt.Fprintln(out, "Loading dump...")
+	if err := loadDump(out, container, dbName, dump); err != nil {
+		return 0, err
+	}
+
+	fmt.Fprintf(out, "\n✓ Restored %s\n\n", dbName)
+	fmt.Fprintf(out, "  Host:     127.0.0.1\n")
+	fmt.Fprintf(out, "  Port:     %d\n", port)
+	fmt.Fprintf(out, "  Database: %s\n", dbName)
+	fmt.Fprintf(out, "  User:     postgres\n")
+	fmt.Fprintf(out, "  Password: %s\n\n", restorePassword)
+	fmt.Fprintf(out, "  %s\n", restoreURL(port, dbName))
+	return port, nil
+}
+
+// dumpMajorVersion reads the PostgreSQL major version a dump was taken from
+func dumpMajorVersion(dump string) (string, error) {
+	f, err := os.Open(dump)
+	if err != nil {
+		return "", fmt.Errorf("failed to open dump: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read dump: %w", err)
+	}
+	defer gz.Close()
+
+	// The version is part of the header comment
+	scanner := bufio.NewScanner(gz)
+	for i := 0; i < 50 && scanner.Scan(); i++ {
+		if match := dumpVersionPattern.FindStringSubmatch(scanner.Text()); match != nil {
+			return match[1], nil
+		}
+	}
+
+	return "", fmt.Errorf("no server version found in %s", dump)
+}
+
+// ensureRestoreContainer starts the restore container, creating it when it doesn't exist,
+// and returns the local port it's published on
+func ensureRestoreContainer(container, image string, port int) (int, error) {
+	existing, err := dockerOutput(context.Background(), "inspect", "--format", "{{.Config.Image}}", container)
+	if err == nil {
+		if strings.TrimSpace(existing) != image {
+			return 0, fmt.Errorf("container %s runs %s, but the dump needs %s (remove it with `docker rm -f %s`)",
+				container, strings.TrimSpace(existing), image, container)
+		}
+		if _, err := dockerOutput(context.Background(), "start", container); err != nil {
+			return 0, fmt.Errorf("failed to start container: %w", err)
+		}
+
+		// Reused containers keep the port they were created with
+		if published, err := dockerOutput(context.Background(), "port", container, "5432/tcp"); err == nil {
+			line, _, _ := strings.Cut(strings.TrimSpace(published), "\n")
+			if _, portStr, err := net.SplitHostPort(line); err == nil {
+				if p, err := strconv.Atoi(portStr); err == nil {
+					port = p
+				}
+			}
+		}
+	} else {
+		fmt.Printf("Creating container %s on 127.0.0.1:%d\n", container, port)
+		cmd := exec.Command("docker", "run", "-d",
+			"--name", container,
+			"-e", "POSTGRES_PASSWORD="+restorePassword,
+			"-p", fmt.Sprintf("127.0.0.1:%d:5432", port),
+			image,
+		)
+		// Pulling the image may take a while, so no timeout
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return 0, fmt.Errorf("failed to start container: %w\nOutput: %s", err, string(output))
+		}
+	}
+
+	deadline := time.Now().Add(restoreReadyTimeout)
+	for time.Now().Before(deadline) {
+		if _, err := dockerOutput(context.Background(), "exec", container, "pg_isready", "-U", "postgres"); err == nil {
+			return port, nil
+		}
+		time.Sleep(time.Second)
+	}
+	return 0, fmt.Errorf("container %s didn't become ready within %s", container, restoreReadyTimeout)
+}
+
+// restoreURL is the connection URL of a database restored into a local container
+func restoreURL(port int, dbName string) string {
+	return fmt.Sprintf("postgres://postgres:%s@127.0.0.1:%d/%s", restorePassword, port, dbName)
+}
+
+// loadDump pipes a compressed dump into psql inside the container, showing errors on out
+func loadDump(out io.Writer, container, dbName, dump string) error {
+	f, err := os.Open(dump)
+	if err != nil {
+		return fmt.Errorf("failed to open dump: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read dump: %w", err)
+	}
+	defer gz.Close()
+
+	// Errors of single statements (e.g. roles that already exist) are shown but don't stop the restore
+	cmd := exec.Command("docker", "exec", "-i", container, "psql", "-U", "postgres", "-d", dbName, "-q")
+	cmd.Stdin = gz
+	cmd.Stdout = io.Discard
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to load %s: %w", dump, err)
+	}
+	return nil
+}
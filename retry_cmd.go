@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// retryParams are the parameters of the "retry" control command
+type retryParams struct {
+	Forward string `json:"forward,omitempty"`
+}
+
+// retryResult is the result of the "retry" control command
+type retryResult struct {
+	Retried []string `json:"retried"`
+}
+
+// runRetryCommand retries reconnecting and failed forwards of the running instance now
+func runRetryCommand() {
+	retryFlags := flag.NewFlagSet("retry", flag.ExitOnError)
+	configPath := retryFlags.String("config", defaultConfigPath, "Path to configuration file")
+	socketPath := retryFlags.String("socket", "", "Control socket of the running instance (default: from config)")
+	forward := retryFlags.String("forward", "", "Only retry a forward (cluster/namespace/service or its ID; default: all)")
+	retryFlags.Parse(os.Args[2:])
+
+	socket := *socketPath
+	if socket == "" {
+		socket = controlSocketFromConfig(*configPath)
+	}
+
+	client, err := DialControl(socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: no running instance on %s: %v\n", socket, err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	var result retryResult
+	if err := client.Call("retry", retryParams{Forward: *forward}, &result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(result.Retried) == 0 {
+		fmt.Println("No port-forwards waiting for a retry")
+		return
+	}
+	for _, id := range result.Retried {
+		fmt.Printf("Retrying %s\n", id)
+	}
+}
+
+// handleRetry returns the control handler retrying forwards now
+func handleRetry(manager *PortForwardManager) ControlHandler {
+	return func(params json.RawMessage) (any, error) {
+		var req retryParams
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, fmt.Errorf("invalid params: %w", err)
+			}
+		}
+
+		result := retryResult{Retried: []string{}}
+		for _, pf := range manager.GetForwards() {
+			matches := req.Forward == "" || pf.ID == req.Forward || strings.HasPrefix(pf.ID, req.Forward+":")
+			if !matches {
+				continue
+			}
+
+			if !pf.Retryable() {
+				if req.Forward != "" {
+					return nil, fmt.Errorf("port-forward %s is %s, nothing to retry", pf.ID, pf.GetState())
+				}
+				continue
+			}
+
+			if err := manager.RetryForward(pf.ID); err != nil {
+				return nil, err
+			}
+			result.Retried = append(result.Retried, pf.ID)
+		}
+
+		if req.Forward != "" && len(result.Retried) == 0 {
+			return nil, fmt.Errorf("no port-forward %s", req.Forward)
+		}
+
+		return result, nil
+	}
+}
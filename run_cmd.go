@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+)
+
+// runWaitInterval is how often `run` checks whether the forwards are active
+const runWaitInterval = 200 * time.Millisecond
+
+// runRunCommand starts the configured forwards without the TUI, waits for them to be
+// active, runs a command with their endpoints in its environment and stops the forwards
+// when it exits, exiting with its exit code
+func runRunCommand() {
+	runFlags := flag.NewFlagSet("run", flag.ExitOnError)
+	configPath := runFlags.String("config", defaultConfigPath, "Path to configuration file")
+	timeout := runFlags.Duration("timeout", defaultStatusWaitTimeout, "How long to wait for the forwards to be active")
+	forward := runFlags.String("forward", "", "Only wait for a forward (cluster/namespace/service or its ID; default: all)")
+	verbose := runFlags.Bool("verbose", false, "Log everything, not just warnings, to stderr")
+	runFlags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: nanoporter run [flags] -- COMMAND [ARGS...]")
+		runFlags.PrintDefaults()
+	}
+	runFlags.Parse(os.Args[2:])
+
+	args := runFlags.Args()
+	if len(args) == 0 {
+		runFlags.Usage()
+		os.Exit(2)
+	}
+
+	// The command's output is what the caller wants to see; nanoporter only speaks up
+	// when something is wrong
+	logLevel := slog.LevelWarn
+	if *verbose {
+		logLevel = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := CheckBindAddresses(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	manager := NewPortForwardManager(config)
+	if err := manager.Initialize(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ResolvePortConflicts(config, manager, ConflictPolicy{}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Signals go to the command, which shares the terminal; nanoporter stops with it
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	manager.Start()
+
+	if err := waitForManager(manager, *forward, *timeout, signals); err != nil {
+		manager.Stop()
+		printStatusTable(filterStatuses(manager.Snapshot(), *forward))
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	env, err := endpointEnv(config, manager)
+	if err != nil {
+		manager.Stop()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	if err := cmd.Start(); err != nil {
+		manager.Stop()
+		fmt.Fprintf(os.Stderr, "Error: failed to start %s: %v\n", args[0], err)
+		os.Exit(127)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	var runErr error
+wait:
+	for {
+		select {
+		case sig := <-signals:
+			cmd.Process.Signal(sig)
+		case runErr = <-exited:
+			break wait
+		}
+	}
+
+	manager.Stop()
+
+	code := 0
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		code = exitErr.ExitCode()
+		if code < 0 {
+			// Killed by a signal
+			code = 1
+		}
+	} else if runErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", runErr)
+		code = 1
+	}
+	os.Exit(code)
+}
+
+// waitForManager waits until the forwards matching filter are active, giving up after
+// timeout, when one of them fails or on a signal
+func waitForManager(manager *PortForwardManager, filter string, timeout time.Duration, signals <-chan os.Signal) error {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(runWaitInterval)
+	defer ticker.Stop()
+
+	for {
+		statuses := filterStatuses(manager.Snapshot(), filter)
+		if len(statuses) == 0 && filter != "" {
+			return fmt.Errorf("no port-forward %s", filter)
+		}
+		pending, err := pendingForwards(statuses)
+		if err != nil {
+			return err
+		}
+		if pending == 0 {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return fmt.Errorf("%d of %d port-forwards not active after %s", pending, len(statuses), timeout)
+		case sig := <-signals:
+			return fmt.Errorf("interrupted by %s", sig)
+		}
+	}
+}
+
+// endpointEnv returns the variables of the active forwards as the env_file template renders
+// them (NAME_URL=host:port by default), for a command's environment
+func endpointEnv(config *Config, manager *PortForwardManager) ([]string, error) {
+	text := defaultEnvFileTemplate
+	if config.EnvFile != nil {
+		text = config.EnvFile.Template
+	}
+	tmpl, err := template.New("env_file").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid env_file template: %w", err)
+	}
+
+	var env []string
+	for _, pf := range manager.GetForwards() {
+		if pf.GetState() != StateActive {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, newEnvFileEntry(pf)); err != nil {
+			return nil, fmt.Errorf("failed to render env_file entry for %s: %w", pf.ID, err)
+		}
+		for _, line := range strings.Split(buf.String(), "\n") {
+			line = strings.TrimPrefix(strings.TrimSpace(line), "export ")
+			if line != "" && !strings.HasPrefix(line, "#") && strings.Contains(line, "=") {
+				env = append(env, line)
+			}
+		}
+	}
+	return env, nil
+}
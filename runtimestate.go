@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// defaultStateFile is where the statistics of the forwards are kept across restarts
+const defaultStateFile = "nanoporter-state.json"
+
+// staleStatsAge is how long the statistics of a forward that no longer runs are kept
+const staleStatsAge = 30 * 24 * time.Hour
+
+// runtimeStateContents is the JSON document written to the state file
+type runtimeStateContents struct {
+	SavedAt  time.Time                `json:"saved_at"`
+	Forwards map[string]*forwardStats `json:"forwards"` // forward ID -> statistics
+}
+
+// forwardStats are the persisted statistics of a forward
+type forwardStats struct {
+	Since         time.Time `json:"since"`          // when the statistics started
+	UptimeSeconds float64   `json:"uptime_seconds"` // total time active
+	Reconnects    int       `json:"reconnects"`
+	Pod           string    `json:"pod,omitempty"` // pod (or container) last connected to
+	PodUID        string    `json:"pod_uid,omitempty"`
+	PodSince      time.Time `json:"pod_since,omitzero"`
+	SeenAt        time.Time `json:"seen_at"` // when the forward last ran
+}
+
+// totalUptime returns how long the forward has been active since its statistics started,
+// including the current connection (caller holds pf.mu)
+func (pf *PortForward) totalUptime() time.Duration {
+	uptime := pf.uptime
+	if pf.reportedState == StateActive && !pf.ActiveSince.IsZero() {
+		uptime += time.Since(pf.ActiveSince)
+	}
+	return uptime
+}
+
+// RestoreRuntimeState continues the statistics of the forwards from the state file written
+// by the previous run. The pod each forward was connected to is shown until it connects
+// again; when it's the same pod, the time it was selected is kept too.
+func (m *PortForwardManager) RestoreRuntimeState(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	var state runtimeStateContents
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+
+	restored := 0
+	for _, pf := range m.GetForwards() {
+		stats := state.Forwards[pf.ID]
+		if stats == nil {
+			continue
+		}
+		pf.mu.Lock()
+		if !stats.Since.IsZero() {
+			pf.statsSince = stats.Since
+		}
+		pf.uptime = time.Duration(stats.UptimeSeconds * float64(time.Second))
+		pf.reconnects = stats.Reconnects
+		if pf.pod == "" {
+			pf.pod = stats.Pod
+			pf.podUID = stats.PodUID
+			pf.podSince = stats.PodSince
+		}
+		pf.mu.Unlock()
+		restored++
+	}
+
+	slog.Info("Restored forward statistics", "path", path, "forwards", restored, "saved_at", state.SavedAt)
+	return nil
+}
+
+// SaveRuntimeState writes the statistics of the forwards to the state file. Forwards that
+// aren't running now, e.g. commented out of the config, keep their entries for a month.
+func (m *PortForwardManager) SaveRuntimeState(path string) error {
+	state := runtimeStateContents{Forwards: make(map[string]*forwardStats)}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &state); err != nil || state.Forwards == nil {
+			state.Forwards = make(map[string]*forwardStats)
+		}
+		for id, stats := range state.Forwards {
+			if time.Since(stats.SeenAt) > staleStatsAge {
+				delete(state.Forwards, id)
+			}
+		}
+	}
+	state.SavedAt = time.Now()
+
+	for _, pf := range m.GetForwards() {
+		pf.mu.RLock()
+		state.Forwards[pf.ID] = &forwardStats{
+			Since:         pf.statsSince,
+			UptimeSeconds: pf.totalUptime().Seconds(),
+			Reconnects:    pf.reconnects,
+			Pod:           pf.pod,
+			PodUID:        pf.podUID,
+			PodSince:      pf.podSince,
+			SeenAt:        state.SavedAt,
+		}
+		pf.mu.RUnlock()
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
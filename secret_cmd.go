@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// runSecretCommand stores or removes secrets of the OS keyring referenced from the config
+// as keyring:service/account
+func runSecretCommand() {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: nanoporter secret set|delete [service/]account")
+		fmt.Fprintln(os.Stderr, "\nThe secret is referenced from the config as keyring:service/account;")
+		fmt.Fprintln(os.Stderr, "the service defaults to nanoporter. set reads it from the terminal or stdin.")
+	}
+	if len(os.Args) != 4 {
+		usage()
+		os.Exit(2)
+	}
+
+	service, account, err := parseKeyringReference(os.Args[3])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	ref := keyringPrefix + service + "/" + account
+
+	switch os.Args[2] {
+	case "set":
+		secret, err := readSecret(fmt.Sprintf("Secret for %s: ", ref))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := keyringSet(service, account, secret); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to store %s in the OS keyring: %v\n", ref, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Stored %s; use it in the config as\n  password: %s\n", ref, ref)
+	case "delete":
+		err := keyringDelete(service, account)
+		if errors.Is(err, errKeyringNotFound) {
+			fmt.Fprintf(os.Stderr, "Error: no secret %s in the OS keyring\n", ref)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to delete %s from the OS keyring: %v\n", ref, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted %s\n", ref)
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// readSecret reads a secret from the terminal without echoing it, or from stdin when it's
+// piped in; a trailing newline is dropped
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		secret, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		if len(secret) == 0 {
+			return "", fmt.Errorf("empty secret")
+		}
+		return string(secret), nil
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	secret := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if secret == "" {
+		return "", fmt.Errorf("empty secret")
+	}
+	return secret, nil
+}
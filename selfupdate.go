@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version is the version of the build, set with -ldflags "-X main.version=v1.2.3" by make
+var version = "dev"
+
+const (
+	// latestReleaseURL is the GitHub API endpoint of the latest release
+	latestReleaseURL = "https://api.github.com/repos/itegmark/nanoporter/releases/latest"
+	// checksumsAsset is the release asset with the SHA-256 checksums of the binaries
+	checksumsAsset = "nanoporter-checksums.txt"
+	// releaseCheckTimeout bounds looking up the latest release
+	releaseCheckTimeout = 10 * time.Second
+	// releaseDownloadTimeout bounds downloading a binary
+	releaseDownloadTimeout = 5 * time.Minute
+)
+
+// githubRelease is the part of a GitHub release used for updates
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	HTMLURL string        `json:"html_url"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+// githubAsset is a file attached to a GitHub release
+type githubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// currentVersion returns the version of the running binary: the one set at build time, or
+// the module version of `go install ...@version` builds
+func currentVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// runVersionCommand prints the version of the binary
+func runVersionCommand() {
+	fmt.Printf("nanoporter %s (%s/%s, %s)\n", currentVersion(), runtime.GOOS, runtime.GOARCH, runtime.Version())
+}
+
+// runSelfUpdateCommand replaces the binary with the one of the latest GitHub release, if
+// that's newer than the running version
+func runSelfUpdateCommand() {
+	updateFlags := flag.NewFlagSet("self-update", flag.ExitOnError)
+	check := updateFlags.Bool("check", false, "Only report whether a newer release is available (exit 1 if so)")
+	force := updateFlags.Bool("force", false, "Install the latest release even if it isn't newer, e.g. over a development build")
+	updateFlags.Parse(os.Args[2:])
+
+	current := currentVersion()
+	release, err := latestRelease()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	newer, comparable := isNewerVersion(release.TagName, current)
+	switch {
+	case !comparable && !*force:
+		fmt.Printf("Running a development build (%s); the latest release is %s. Use --force to install it.\n", current, release.TagName)
+		return
+	case !newer && !*force:
+		fmt.Printf("nanoporter %s is up to date\n", current)
+		return
+	case *check:
+		fmt.Printf("nanoporter %s is available (running %s): %s\n", release.TagName, current, release.HTMLURL)
+		os.Exit(1)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to find the running binary: %v\n", err)
+		os.Exit(1)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	fmt.Printf("Downloading nanoporter %s...\n", release.TagName)
+	binary, err := downloadRelease(release)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := replaceExecutable(executable, binary); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if os.IsPermission(err) {
+			fmt.Fprintf(os.Stderr, "Run the update as a user that can write %s (e.g. with sudo)\n", executable)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("Updated %s from %s to %s; restart running instances to use it\n", executable, current, release.TagName)
+}
+
+// updateNotice returns a line announcing a newer release, "" when there is none or the
+// check failed
+func updateNotice() string {
+	release, err := latestRelease()
+	if err != nil {
+		return ""
+	}
+	current := currentVersion()
+	if newer, _ := isNewerVersion(release.TagName, current); !newer {
+		return ""
+	}
+	return fmt.Sprintf("nanoporter %s is available (running %s), run `nanoporter self-update`", release.TagName, current)
+}
+
+// latestRelease looks up the latest GitHub release. $GITHUB_TOKEN is sent when set, for
+// the higher rate limit.
+func latestRelease() (*githubRelease, error) {
+	req, err := http.NewRequest(http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := (&http.Client{Timeout: releaseCheckTimeout}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to look up the latest release: %s", resp.Status)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse the latest release: %w", err)
+	}
+	return &release, nil
+}
+
+// downloadRelease downloads the binary for this platform from a release and checks it
+// against the published checksums
+func downloadRelease(release *githubRelease) ([]byte, error) {
+	name := fmt.Sprintf("nanoporter-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	var binaryURL, checksumsURL string
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case name:
+			binaryURL = asset.URL
+		case checksumsAsset:
+			checksumsURL = asset.URL
+		}
+	}
+	if binaryURL == "" {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s (%s)", release.TagName, runtime.GOOS, runtime.GOARCH, name)
+	}
+	if checksumsURL == "" {
+		return nil, fmt.Errorf("release %s has no %s to verify the download", release.TagName, checksumsAsset)
+	}
+
+	checksums, err := download(checksumsURL)
+	if err != nil {
+		return nil, err
+	}
+	want := ""
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		// sha256sum format: checksum, two spaces (or " *" in binary mode), file name
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			want = strings.ToLower(fields[0])
+		}
+	}
+	if want == "" {
+		return nil, fmt.Errorf("%s of release %s lists no checksum for %s", checksumsAsset, release.TagName, name)
+	}
+
+	binary, err := download(binaryURL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(binary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, want, got)
+	}
+	return binary, nil
+}
+
+// download fetches a release asset
+func download(url string) ([]byte, error) {
+	resp, err := (&http.Client{Timeout: releaseDownloadTimeout}).Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return data, nil
+}
+
+// replaceExecutable writes a new binary next to the running one and moves it into place.
+// Windows can't replace a running executable, but can rename it, so it's moved aside first.
+func replaceExecutable(path string, binary []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".nanoporter-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the new binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// isNewerVersion reports whether latest is a newer version than current, both as
+// v1.2.3[-pre]. comparable is false when current isn't such a version, i.e. for
+// development builds.
+func isNewerVersion(latest, current string) (newer, comparable bool) {
+	l, lPre, ok := parseVersion(latest)
+	if !ok {
+		return false, false
+	}
+	c, cPre, ok := parseVersion(current)
+	if !ok || pseudoVersionSuffix.MatchString(cPre) {
+		return false, false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i], true
+		}
+	}
+	if gitDescribeSuffix.MatchString(cPre) {
+		// Built from commits after the release, or from a modified tree
+		return false, true
+	}
+	// A release is newer than its pre-releases
+	if lPre == "" || cPre == "" {
+		return lPre == "" && cPre != "", true
+	}
+	return lPre > cPre, true
+}
+
+// gitDescribeSuffix matches what `git describe --tags --dirty` appends to the tag of a build
+// from later commits or a modified tree, e.g. 4-gabcdef0-dirty of v1.2.3-4-gabcdef0-dirty
+var gitDescribeSuffix = regexp.MustCompile(`^(\d+-g[0-9a-f]+)?(-?dirty)?$`)
+
+// pseudoVersionSuffix matches the pre-release of Go pseudo-versions, which builds from a
+// checkout get instead of a release version, e.g. v0.0.0-20260102150405-abcdef012345
+var pseudoVersionSuffix = regexp.MustCompile(`(^|\.)\d{14}-[0-9a-f]{12}$`)
+
+// parseVersion splits v1.2.3[-pre][+build] into its numbers and pre-release
+func parseVersion(v string) (numbers [3]int, pre string, ok bool) {
+	v, _, _ = strings.Cut(strings.TrimPrefix(v, "v"), "+")
+	v, pre, _ = strings.Cut(v, "-")
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return numbers, "", false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return numbers, "", false
+		}
+		numbers[i] = n
+	}
+	return numbers, pre, true
+}
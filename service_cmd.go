@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Names of the generated systemd units
+const (
+	serviceUnitName = "nanoporter.service"
+	socketUnitName  = "nanoporter.socket"
+)
+
+// runServiceCommand installs, removes or prints systemd units running nanoporter as a
+// service, with its control socket opened by systemd
+func runServiceCommand() {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: nanoporter service install|uninstall|print [flags]")
+	}
+	if len(os.Args) < 3 {
+		usage()
+		os.Exit(2)
+	}
+	action := os.Args[2]
+
+	serviceFlags := flag.NewFlagSet("service "+action, flag.ExitOnError)
+	configPath := serviceFlags.String("config", defaultConfigPath, "Path to configuration file")
+	system := serviceFlags.Bool("system", false, "Install a system service (in /etc/systemd/system, needs root) instead of a user service")
+	serviceFlags.Parse(os.Args[3:])
+
+	switch action {
+	case "install", "uninstall", "print":
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if action != "print" && runtime.GOOS != "linux" {
+		fmt.Fprintln(os.Stderr, "Error: services are installed as systemd units, which only exist on Linux; use `nanoporter service print` as a template")
+		os.Exit(1)
+	}
+
+	unitDir, err := systemdUnitDir(*system)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if action == "uninstall" {
+		systemctl(*system, "disable", "--now", serviceUnitName, socketUnitName)
+		for _, name := range []string{serviceUnitName, socketUnitName} {
+			path := filepath.Join(unitDir, name)
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "Error: failed to remove %s: %v\n", path, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Removed %s\n", path)
+		}
+		systemctl(*system, "daemon-reload")
+		return
+	}
+
+	units, err := serviceUnits(*configPath, *system)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if action == "print" {
+		for _, name := range []string{socketUnitName, serviceUnitName} {
+			fmt.Printf("# %s\n%s\n", filepath.Join(unitDir, name), units[name])
+		}
+		return
+	}
+
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create %s: %v\n", unitDir, err)
+		os.Exit(1)
+	}
+	for _, name := range []string{socketUnitName, serviceUnitName} {
+		path := filepath.Join(unitDir, name)
+		if err := os.WriteFile(path, []byte(units[name]), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+
+	if err := systemctl(*system, "daemon-reload"); err != nil {
+		os.Exit(1)
+	}
+	if err := systemctl(*system, "enable", "--now", socketUnitName, serviceUnitName); err != nil {
+		fmt.Fprintln(os.Stderr, "Stop nanoporter instances using the control socket and run the command again")
+		os.Exit(1)
+	}
+
+	scope := ""
+	if !*system {
+		scope = "--user "
+		fmt.Println("\nUser services stop when you log out; to keep the forwards running, run")
+		fmt.Println("  loginctl enable-linger $USER")
+	}
+	fmt.Printf("\nnanoporter is running as %s; see its output with\n  journalctl %s-u %s -f\n", serviceUnitName, scope, serviceUnitName)
+}
+
+// serviceUnits returns the socket and service units running nanoporter with a config
+func serviceUnits(configPath string, system bool) (map[string]string, error) {
+	configPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", configPath, err)
+	}
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if system && config.ControlSocket == defaultControlSocketPath() {
+		return nil, fmt.Errorf("set control_socket in the config, so clients of other users find the system service, e.g. control_socket: /run/nanoporter.sock")
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the nanoporter binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(binary); err == nil {
+		binary = resolved
+	}
+
+	socketMode := "0600"
+	if len(config.ControlUsers) > 0 || len(config.ControlGroups) > 0 {
+		// Clients are checked by peer credentials
+		socketMode = "0666"
+	}
+	wantedBy := "default.target"
+	if system {
+		wantedBy = "multi-user.target"
+	}
+
+	socket := fmt.Sprintf(`[Unit]
+Description=nanoporter control socket
+
+[Socket]
+ListenStream=%s
+SocketMode=%s
+RemoveOnStop=true
+
+[Install]
+WantedBy=sockets.target
+`, systemdEscape(config.ControlSocket), socketMode)
+
+	service := fmt.Sprintf(`[Unit]
+Description=nanoporter port-forward manager
+Requires=%s
+After=%s network-online.target
+Wants=network-online.target
+
+[Service]
+ExecStart=%s -service -config %s
+WorkingDirectory=%s
+Restart=on-failure
+RestartSec=5s
+
+[Install]
+WantedBy=%s
+`, socketUnitName, socketUnitName, systemdQuote(binary), systemdQuote(configPath),
+		systemdEscape(filepath.Dir(configPath)), wantedBy)
+
+	return map[string]string{socketUnitName: socket, serviceUnitName: service}, nil
+}
+
+// systemdUnitDir returns where units of the user's or the system's systemd are installed
+func systemdUnitDir(system bool) (string, error) {
+	if system {
+		return "/etc/systemd/system", nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the config directory: %w", err)
+	}
+	return filepath.Join(dir, "systemd", "user"), nil
+}
+
+// systemctl runs systemctl for the user's or the system's systemd, showing its output
+func systemctl(system bool, args ...string) error {
+	if !system {
+		args = append([]string{"--user"}, args...)
+	}
+	fmt.Printf("$ systemctl %s\n", strings.Join(args, " "))
+	cmd := exec.Command("systemctl", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: systemctl %s failed: %v\n", strings.Join(args, " "), err)
+		return err
+	}
+	return nil
+}
+
+// systemdEscape escapes the specifiers of systemd in a unit setting
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// systemdQuote quotes an argument of a unit's command line where needed
+func systemdQuote(s string) string {
+	s = systemdEscape(s)
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
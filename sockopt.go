@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"net"
+)
+
+// socketOptions returns the listener options of a forward: its own, or the global ones
+func (m *PortForwardManager) socketOptions(pf *PortForward) *SocketOptions {
+	if pf.Config.SocketOptions != nil {
+		return pf.Config.SocketOptions
+	}
+	return m.config.SocketOptions
+}
+
+// proxied reports whether a Kubernetes forward listens through nanoporter's proxy, which
+// applies the socket options, client allowlist, connection limit, local TLS, captures and
+// access log that client-go's listener can't
+func (m *PortForwardManager) proxied(pf *PortForward) bool {
+	return pf.handed != nil || m.socketOptions(pf) != nil || len(pf.Config.AllowedCIDRs) > 0 || pf.Config.MaxConnections > 0 ||
+		pf.Config.LocalTLS != nil || pf.activeCapture() != nil || pf.Config.AccessLog != ""
+}
+
+// listenLocal opens the local listener of a forward with its socket options
+func listenLocal(address string, options *SocketOptions) (net.Listener, error) {
+	if options == nil {
+		return net.Listen("tcp", address)
+	}
+
+	lc := net.ListenConfig{
+		KeepAlive: options.KeepAlive,
+		Control:   options.control,
+	}
+	return lc.Listen(context.Background(), "tcp", address)
+}
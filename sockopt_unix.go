@@ -0,0 +1,36 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// control sets the socket options on a listening socket before it is bound
+func (o *SocketOptions) control(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		if o.ReuseAddr != nil {
+			value := 0
+			if *o.ReuseAddr {
+				value = 1
+			}
+			if err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, value); err != nil {
+				sockErr = fmt.Errorf("failed to set SO_REUSEADDR: %w", err)
+				return
+			}
+		}
+		if o.ReusePort {
+			if err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); err != nil {
+				sockErr = fmt.Errorf("failed to set SO_REUSEPORT: %w", err)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
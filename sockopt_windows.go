@@ -0,0 +1,33 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// control sets the socket options on a listening socket before it is bound. SO_REUSEPORT
+// doesn't exist on Windows and is rejected with the config.
+func (o *SocketOptions) control(network, address string, c syscall.RawConn) error {
+	if o.ReuseAddr == nil {
+		return nil
+	}
+
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		value := 0
+		if *o.ReuseAddr {
+			value = 1
+		}
+		if err := windows.SetsockoptInt(windows.Handle(fd), windows.SOL_SOCKET, windows.SO_REUSEADDR, value); err != nil {
+			sockErr = fmt.Errorf("failed to set SO_REUSEADDR: %w", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
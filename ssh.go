@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// sshDialTimeout bounds connecting and authenticating to an SSH server
+	sshDialTimeout = 15 * time.Second
+	// sshKeepaliveInterval is how often SSH connections are checked for liveness
+	sshKeepaliveInterval = 30 * time.Second
+)
+
+// establishSSHForward tunnels the forward's local port through an SSH server to
+// service:remote_port, like `ssh -L`
+func (m *PortForwardManager) establishSSHForward(pf *PortForward, release func()) error {
+	client, err := dialSSH(pf.Config.SSH)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	broken := make(chan error, 1)
+	go func() {
+		broken <- fmt.Errorf("ssh connection closed: %w", client.Wait())
+	}()
+	go sshKeepalive(client, pf.ctx.Done())
+
+	target := net.JoinHostPort(pf.Config.Service, strconv.Itoa(pf.Config.RemotePort))
+	dial := func() (net.Conn, error) {
+		return client.Dial("tcp", target)
+	}
+
+	return m.relayForward(pf, release, dial, broken)
+}
+
+// dialSSH connects and authenticates to an SSH server
+func dialSSH(config *SSHConfig) (*ssh.Client, error) {
+	address := config.Host
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+
+	username := config.User
+	if username == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the SSH user: %w", err)
+		}
+		username = current.Username
+	}
+
+	auth, err := sshAuthMethods(config)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback, err := sshHostKeyCallback(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ssh.Dial("tcp", address, &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH server %s: %w", address, err)
+	}
+
+	return client, nil
+}
+
+// sshAuthMethods returns the configured key, or the keys of the running SSH agent
+func sshAuthMethods(config *SSHConfig) ([]ssh.AuthMethod, error) {
+	if config.KeyFile != "" {
+		key, err := os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH key %s: %w", config.KeyFile, err)
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+	}
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, fmt.Errorf("no ssh.key_file configured and no SSH agent running (SSH_AUTH_SOCK is not set)")
+	}
+
+	// A connection per authentication, so a restarted agent is picked up
+	return []ssh.AuthMethod{ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to SSH agent: %w", err)
+		}
+		defer conn.Close()
+		return agent.NewClient(conn).Signers()
+	})}, nil
+}
+
+// sshHostKeyCallback verifies host keys against the known_hosts file
+func sshHostKeyCallback(config *SSHConfig) (ssh.HostKeyCallback, error) {
+	if config.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	path := config.KnownHostsFile
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find known_hosts: %w", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// sshKeepalive closes the client when the server stops answering keepalive requests, so
+// a dead connection is noticed before the next connection through the tunnel fails
+func sshKeepalive(client *ssh.Client, stop <-chan struct{}) {
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				client.Close()
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runStatusCommand prints the forwards of the running instance
+func runStatusCommand() {
+	statusFlags := flag.NewFlagSet("status", flag.ExitOnError)
+	configPath := statusFlags.String("config", defaultConfigPath, "Path to configuration file")
+	socketPath := statusFlags.String("socket", "", "Control socket of the running instance (default: from config)")
+	jsonOutput := statusFlags.Bool("json", false, "Print the status as JSON")
+	wait := statusFlags.Bool("wait", false, "Wait until the forwards are active; exit non-zero if they aren't within the timeout")
+	timeout := statusFlags.Duration("timeout", defaultStatusWaitTimeout, "How long --wait waits")
+	forward := statusFlags.String("forward", "", "Only wait for a forward (cluster/namespace/service or its ID; default: all)")
+	statusFlags.Parse(os.Args[2:])
+
+	socket := *socketPath
+	if socket == "" {
+		socket = controlSocketFromConfig(*configPath)
+	}
+
+	var statuses []ForwardStatus
+	var waitErr error
+	if *wait {
+		statuses, waitErr = waitForActive(socket, *forward, *timeout)
+		if statuses == nil && waitErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", waitErr)
+			os.Exit(1)
+		}
+	} else {
+		client, err := DialControl(socket)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: no running instance on %s: %v\n", socket, err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		if err := client.Call("status", nil, &statuses); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(statuses)
+	} else {
+		printStatusTable(statuses)
+	}
+
+	if waitErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", waitErr)
+		os.Exit(1)
+	}
+}
+
+// defaultStatusWaitTimeout is how long `status --wait` waits by default
+const defaultStatusWaitTimeout = 60 * time.Second
+
+// statusWaitInterval is how often `status --wait` polls the running instance
+const statusWaitInterval = 500 * time.Millisecond
+
+// waitForActive polls the running instance until the forwards matching filter (all when
+// empty) are active. An instance that isn't running yet is waited for too, so scripts can
+// start nanoporter in the background and wait right away. Fails early when a forward has
+// failed for good. Returns the last statuses seen, nil when the instance never answered or
+// has no such forward.
+func waitForActive(socket, filter string, timeout time.Duration) ([]ForwardStatus, error) {
+	deadline := time.Now().Add(timeout)
+	var statuses []ForwardStatus
+	var lastErr error
+
+	for {
+		var current []ForwardStatus
+		client, err := DialControl(socket)
+		if err == nil {
+			err = client.Call("status", nil, &current)
+			client.Close()
+		}
+
+		if err != nil {
+			lastErr = fmt.Errorf("no running instance on %s: %w", socket, err)
+		} else {
+			statuses = filterStatuses(current, filter)
+			lastErr = nil
+			if len(statuses) == 0 {
+				if filter != "" {
+					return nil, fmt.Errorf("no port-forward %s", filter)
+				}
+				return statuses, nil
+			}
+
+			pending, err := pendingForwards(statuses)
+			if err != nil {
+				return statuses, err
+			}
+			if pending == 0 {
+				return statuses, nil
+			}
+			lastErr = fmt.Errorf("%d of %d port-forwards not active after %s", pending, len(statuses), timeout)
+		}
+
+		if time.Now().After(deadline) {
+			return statuses, lastErr
+		}
+		time.Sleep(statusWaitInterval)
+	}
+}
+
+// pendingForwards counts the forwards that aren't active yet; a failed one is an error, since
+// it isn't retried by itself. Disabled and paused forwards are settled, they don't start
+// until someone enables or resumes them.
+func pendingForwards(statuses []ForwardStatus) (int, error) {
+	pending := 0
+	for _, s := range statuses {
+		switch s.State {
+		case StateActive, StateDisabled, StatePaused:
+		case StateFailed:
+			return 0, fmt.Errorf("port-forward %s failed: %s", s.ID, s.Error)
+		default:
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// filterStatuses returns the statuses of the forwards a filter (cluster/namespace/service or
+// an ID) names, all of them when it's empty
+func filterStatuses(statuses []ForwardStatus, filter string) []ForwardStatus {
+	if filter == "" {
+		return statuses
+	}
+	var matched []ForwardStatus
+	for _, s := range statuses {
+		if s.ID == filter || strings.HasPrefix(s.ID, filter+":") {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// printStatusTable prints forward statuses as an aligned table
+func printStatusTable(statuses []ForwardStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tNAMESPACE\tSERVICE\tPORTS\tSTATUS\tPOD\tBACKUP\tERROR")
+
+	for _, s := range statuses {
+		backup := "-"
+		if s.HasBackup {
+			backup = string(s.BackupState)
+			if backup == "" {
+				backup = "waiting"
+			}
+		}
+
+		errorMsg := s.Error
+		if errorMsg == "" {
+			errorMsg = s.BackupError
+		}
+		if errorMsg == "" && s.ConfiguredPort != 0 {
+			errorMsg = fmt.Sprintf("local port %d taken, reassigned", s.ConfiguredPort)
+		}
+
+		pod := s.Pod
+		if pod == "" {
+			pod = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s:%d:%s\t%s\t%s\t%s\t%s\n",
+			s.Cluster, s.Namespace, s.Service,
+			s.LocalAddress, s.LocalPort, s.RemotePortLabel(),
+			s.State, pod, backup, errorMsg)
+	}
+
+	w.Flush()
+}
+
+// controlSocketFromConfig returns the control socket configured in a config file, falling
+// back to the default path when the file can't be read
+func controlSocketFromConfig(path string) string {
+	config, err := LoadConfig(path)
+	if err != nil || config.ControlSocket == "" {
+		return defaultControlSocketPath()
+	}
+	return config.ControlSocket
+}
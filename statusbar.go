@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// statusBarStyle sets the status bar apart from the table
+var statusBarStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("252")).
+	Background(lipgloss.Color("236"))
+
+// statusBarStates are the forward states counted in the status bar, in display order
+var statusBarStates = []ForwardState{
+	StateActive, StateStarting, StateReconnecting, StateAuthExpired, StateFailed, StateStopped, StateDisabled, StatePaused,
+}
+
+// statusBar summarizes the forwards, clusters, backups and the latest event in one line
+func (m model) statusBar() string {
+	counts := make(map[ForwardState]int)
+	var pending, running int
+	for _, fs := range m.forwards {
+		counts[fs.State]++
+		switch fs.BackupState {
+		case BackupPending:
+			pending++
+		case BackupRunning:
+			running++
+		}
+	}
+
+	states := make([]string, 0, len(statusBarStates))
+	for _, state := range statusBarStates {
+		if counts[state] > 0 {
+			states = append(states, fmt.Sprintf("%d %s", counts[state], state))
+		}
+	}
+	parts := []string{fmt.Sprintf("%d forwards", len(m.forwards))}
+	if len(states) > 0 {
+		parts[0] += ": " + strings.Join(states, ", ")
+	}
+
+	var connected, kubernetes int
+	for _, cs := range m.clusters {
+		if cs.Kubernetes && !cs.Disabled {
+			kubernetes++
+			if cs.Reachable && !cs.AuthExpired {
+				connected++
+			}
+		}
+	}
+	if kubernetes > 0 {
+		parts = append(parts, fmt.Sprintf("clusters %d/%d connected", connected, kubernetes))
+	}
+
+	if pending > 0 || running > 0 {
+		parts = append(parts, fmt.Sprintf("backups %d running, %d pending", running, pending))
+	}
+
+	if m.lastEvent != nil {
+		parts = append(parts, "last: "+m.lastEvent.Time.Local().Format("15:04:05")+" "+describeEvent(*m.lastEvent))
+	}
+
+	line := " " + strings.Join(parts, " │ ")
+	now := time.Now().Format("15:04:05") + " "
+
+	if m.width <= 0 {
+		return statusBarStyle.Render(line + " │ " + now)
+	}
+
+	// The clock sticks to the right edge; the summary is cut to leave room for it
+	room := m.width - lipgloss.Width(now) - 1
+	if runes := []rune(line); len(runes) > room {
+		line = string(runes[:max(room-1, 0)]) + "…"
+	}
+	gap := max(m.width-lipgloss.Width(line)-lipgloss.Width(now), 1)
+	return statusBarStyle.Render(line + strings.Repeat(" ", gap) + now)
+}
+
+// describeEvent describes an event in a few words for the status bar
+func describeEvent(e Event) string {
+	fs := e.Forward
+	target := fs.Name
+	if target == "" {
+		target = fs.Cluster + "/" + fs.Service
+	}
+
+	switch e.Type {
+	case EventStateChanged:
+		if e.PreviousState != "" && e.PreviousState != fs.State {
+			return fmt.Sprintf("%s %s → %s", target, e.PreviousState, fs.State)
+		}
+		return fmt.Sprintf("%s %s", target, fs.State)
+	case EventHealthCheckFailed:
+		return target + " health check failed"
+	case EventBackupProgress:
+		return fmt.Sprintf("%s backup %s", target, fs.BackupState)
+	case EventPodSwitched:
+		return fmt.Sprintf("%s switched to %s", target, fs.Pod)
+	case EventForwardAdded:
+		return target + " added"
+	case EventForwardRemoved:
+		return target + " removed"
+	}
+	return fmt.Sprintf("%s %s", target, e.Type)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// statusFileContents is the JSON document written to status_file
+type statusFileContents struct {
+	PID       int             `json:"pid"`
+	StartedAt time.Time       `json:"started_at"`
+	Forwards  []ForwardStatus `json:"forwards"`
+}
+
+// StatusFileWriter keeps a JSON file with the status of all forwards up to date, for
+// status lines and scripts that don't want to talk to the control socket
+type StatusFileWriter struct {
+	path      string
+	manager   *PortForwardManager
+	startedAt time.Time
+	mu        sync.Mutex
+	last      []byte
+}
+
+// NewStatusFileWriter creates a writer and registers it for manager updates
+func NewStatusFileWriter(path string, manager *PortForwardManager) *StatusFileWriter {
+	w := &StatusFileWriter{
+		path:      path,
+		manager:   manager,
+		startedAt: time.Now(),
+	}
+	manager.OnEvent(func(Event) { w.Write() })
+
+	return w
+}
+
+// Write renders the file from the current forward states, skipping the write if nothing changed
+func (w *StatusFileWriter) Write() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := json.MarshalIndent(statusFileContents{
+		PID:       os.Getpid(),
+		StartedAt: w.startedAt,
+		Forwards:  w.manager.Snapshot(),
+	}, "", "  ")
+	if err != nil {
+		slog.Warn("Failed to render status_file", "error", err)
+		return
+	}
+	data = append(data, '\n')
+
+	if bytes.Equal(data, w.last) {
+		return
+	}
+
+	if err := writeFileAtomic(w.path, data, 0644); err != nil {
+		slog.Warn("Failed to write status_file", "path", w.path, "error", err)
+		return
+	}
+	w.last = data
+
+	slog.Debug("Updated status_file", "path", w.path)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -34,84 +35,451 @@ var (
 			MarginTop(1)
 )
 
-// updateMsg is sent when a port-forward status changes
-type updateMsg struct {
-	forward *PortForward
+// eventMsg is sent when a port-forward event is received
+type eventMsg struct {
+	event Event
 }
 
+// noticeMsg is sent when a background action finishes
+type noticeMsg string
+
 // tickMsg is sent on each tick for refresh
 type tickMsg time.Time
 
 // model represents the TUI state
 type model struct {
-	manager  *PortForwardManager
-	forwards []*PortForward
-	width    int
-	height   int
-	quitting bool
+	manager     *PortForwardManager
+	backups     *BackupManager
+	configPath  string
+	events      <-chan Event
+	forwards    []ForwardStatus
+	clusters    []ClusterStatus
+	columns     []tuiColumn
+	mode        string // modeAuto, modeWide or modeCompact
+	keys        keymap
+	pending     string          // keys typed so far of a key sequence
+	cursor      int             // row under the cursor
+	visualFrom  int             // row visual mode started at, -1 outside of visual mode
+	marked      map[string]bool // forward IDs of marked rows
+	wizard      *addWizard
+	history     *backupHistory
+	showHelp    bool
+	quitDialog  bool   // asking what to do with running backups before quitting
+	quitWaiting string // what quitting waits for, once chosen in the quit dialog
+	notice      string
+	lastEvent   *Event // latest port-forward event, for the status bar
+	width       int
+	height      int
+	quitting    bool
 }
 
-// NewTUIModel creates a new TUI model
-func NewTUIModel(manager *PortForwardManager) model {
-	return model{
-		manager:  manager,
-		forwards: manager.GetForwards(),
+// NewTUIModel creates a new TUI model. backups is nil when no backups are configured.
+func NewTUIModel(manager *PortForwardManager, backups *BackupManager, configPath string) model {
+	events, _ := manager.Subscribe(100)
+
+	// The keymap has been checked with the config
+	var overrides map[string][]string
+	mode := modeAuto
+	if tui := manager.config.TUI; tui != nil {
+		overrides = tui.Keymap
+		if tui.Mode != "" {
+			mode = tui.Mode
+		}
 	}
+	keys, _ := newKeymap(overrides)
+
+	m := model{
+		manager:    manager,
+		backups:    backups,
+		configPath: configPath,
+		events:     events,
+		columns:    resolveTUIColumns(manager.config),
+		keys:       keys,
+		mode:       mode,
+		visualFrom: -1,
+		marked:     make(map[string]bool),
+	}
+	m.refresh()
+	return m
 }
 
 // Init initializes the TUI
 func (m model) Init() tea.Cmd {
 	return tea.Batch(
-		waitForUpdate(m.manager),
+		waitForEvent(m.events),
 		tickCmd(),
 	)
 }
 
 // Update handles messages
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// The add forward wizard takes keyboard input while open
+	if m.wizard != nil {
+		switch msg.(type) {
+		case tea.KeyMsg, wizardOptionsMsg:
+			closed, cmd := m.wizard.Update(msg)
+			if closed {
+				m.notice = m.wizard.notice
+				m.wizard = nil
+				m.refresh()
+			}
+			return m, cmd
+		}
+	}
+
+	// So does the backup history screen
+	if m.history != nil {
+		switch msg.(type) {
+		case tea.KeyMsg, historyVerifiedMsg, historyNoticeMsg:
+			closed, cmd := m.history.Update(msg)
+			if closed {
+				m.notice = m.history.notice
+				m.history = nil
+			}
+			return m, cmd
+		}
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c", "esc":
-			m.quitting = true
-			m.manager.Stop()
-			return m, tea.Quit
+		if m.quitDialog {
+			return m.updateQuitDialog(msg)
 		}
+		if msg.String() == "ctrl+c" {
+			// Always quits, whatever the keymap says
+			return m.requestQuit()
+		}
+		if m.showHelp {
+			m.showHelp = false
+			return m, nil
+		}
+		var action string
+		action, m.pending = m.keys.lookup(m.pending, msg.String())
+		return m.runAction(action)
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 
-	case updateMsg:
+	case eventMsg:
 		// Refresh forwards list
-		m.forwards = m.manager.GetForwards()
-		return m, waitForUpdate(m.manager)
+		event := msg.event
+		m.lastEvent = &event
+		m.refresh()
+		return m, waitForEvent(m.events)
+
+	case noticeMsg:
+		m.notice = string(msg)
+		m.refresh()
+
+	case historyNoticeMsg:
+		// A delete or restore finished after the history screen was closed
+		m.notice = string(msg)
+
+	case backupsDoneMsg:
+		return m.quit()
 
 	case tickMsg:
 		// Periodic refresh
-		m.forwards = m.manager.GetForwards()
+		m.refresh()
+		if m.quitDialog && m.quitWaiting == "" && len(m.runningBackups()) == 0 {
+			// The backups finished while the dialog was open
+			return m.quit()
+		}
 		return m, tickCmd()
 	}
 
 	return m, nil
 }
 
+// runAction runs an action of the forward list
+func (m model) runAction(action string) (tea.Model, tea.Cmd) {
+	switch action {
+	case actionQuit:
+		if m.visualFrom >= 0 || len(m.marked) > 0 {
+			// Leave the selection first
+			m.clearSelection()
+			return m, nil
+		}
+		return m.requestQuit()
+	case actionHelp:
+		m.showHelp = true
+	case actionToggleMode:
+		if m.compact() {
+			m.mode = modeWide
+		} else {
+			m.mode = modeCompact
+		}
+	case actionAdd:
+		m.notice = ""
+		m.wizard = newAddWizard(m.manager, m.configPath)
+	case actionBackupHistory:
+		m.notice = ""
+		history, cmd := newBackupHistory(m.manager, defaultBackupDir)
+		m.history = history
+		return m, cmd
+	case actionUp:
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case actionDown:
+		if m.cursor < len(m.forwards)-1 {
+			m.cursor++
+		}
+	case actionTop:
+		m.cursor = 0
+	case actionBottom:
+		m.cursor = max(len(m.forwards)-1, 0)
+	case actionMark:
+		if m.cursor < len(m.forwards) {
+			id := m.forwards[m.cursor].ID
+			if m.marked[id] {
+				delete(m.marked, id)
+			} else {
+				m.marked[id] = true
+			}
+		}
+	case actionVisual:
+		if m.visualFrom < 0 {
+			m.visualFrom = m.cursor
+		} else {
+			// Keep the rows of the visual range marked
+			for _, fs := range m.selected() {
+				m.marked[fs.ID] = true
+			}
+			m.visualFrom = -1
+		}
+	case actionOpen:
+		var opened, failed []string
+		for _, fs := range m.selected() {
+			url := endpointURL(fs)
+			if err := openBrowser(url); err != nil {
+				failed = append(failed, fmt.Sprintf("Can't open %s: %v", url, err))
+			} else {
+				opened = append(opened, url)
+			}
+		}
+		if len(failed) > 0 {
+			m.notice = strings.Join(failed, "\n")
+		} else if len(opened) > 0 {
+			m.notice = "Opened " + strings.Join(opened, ", ")
+		}
+		m.clearSelection()
+	case actionRetry:
+		selected := m.selected()
+		var lines []string
+		for _, fs := range selected {
+			err := m.manager.RetryForward(fs.ID)
+			audit("retry", auditSourceTUI, err, "forward", fs.ID)
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("Can't retry %s/%s/%s: %v", fs.Cluster, fs.Namespace, fs.Service, err))
+			}
+		}
+		if retried := len(selected) - len(lines); retried == 1 && len(selected) == 1 {
+			fs := selected[0]
+			lines = append(lines, fmt.Sprintf("Retrying %s/%s/%s now", fs.Cluster, fs.Namespace, fs.Service))
+		} else if retried > 0 {
+			lines = append([]string{fmt.Sprintf("Retrying %d port-forward(s) now", retried)}, lines...)
+		}
+		m.notice = strings.Join(lines, "\n")
+		m.clearSelection()
+		m.refresh()
+	case actionDisable:
+		var cmds []tea.Cmd
+		toggled := make(map[string]bool)
+		for _, fs := range m.selected() {
+			if !toggled[fs.Cluster] {
+				toggled[fs.Cluster] = true
+				cmds = append(cmds, m.toggleCluster(fs.Cluster))
+			}
+		}
+		m.clearSelection()
+		return m, tea.Batch(cmds...)
+	case actionPauseAll:
+		return m, m.togglePause()
+	case actionCapture:
+		var lines []string
+		for _, fs := range m.selected() {
+			pf := m.manager.GetForward(fs.ID)
+			if pf == nil {
+				continue
+			}
+			if fs.Capture != "" {
+				path, err := m.manager.StopCapture(pf)
+				audit("capture_stop", auditSourceTUI, err, "forward", fs.ID)
+				if err == nil {
+					lines = append(lines, fmt.Sprintf("Stopped capturing %s/%s/%s to %s", fs.Cluster, fs.Namespace, fs.Service, path))
+				}
+				continue
+			}
+			path, err := m.manager.StartCapture(pf)
+			audit("capture_start", auditSourceTUI, err, "forward", fs.ID)
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("Can't capture %s/%s/%s: %v", fs.Cluster, fs.Namespace, fs.Service, err))
+			} else {
+				lines = append(lines, fmt.Sprintf("Capturing %s/%s/%s to %s", fs.Cluster, fs.Namespace, fs.Service, path))
+			}
+		}
+		m.notice = strings.Join(lines, "\n")
+		m.clearSelection()
+		m.refresh()
+	case actionRetryAll:
+		retried := m.manager.RetryForwards()
+		audit("retry_all", auditSourceTUI, nil, "retried", retried)
+		if retried > 0 {
+			m.notice = fmt.Sprintf("Retrying %d port-forward(s) now", retried)
+		} else {
+			m.notice = "No port-forwards waiting for a retry"
+		}
+		m.refresh()
+	}
+
+	return m, nil
+}
+
+// quit stops all forwards and exits
+func (m model) quit() (tea.Model, tea.Cmd) {
+	m.quitting = true
+	audit("shutdown", auditSourceTUI, nil)
+	m.manager.Stop()
+	return m, tea.Quit
+}
+
+// selected returns the rows an action applies to: the visual range and marked rows, or
+// the row under the cursor when nothing is selected
+func (m model) selected() []ForwardStatus {
+	var rows []ForwardStatus
+	for i, fs := range m.forwards {
+		if m.inSelection(i) {
+			rows = append(rows, fs)
+		}
+	}
+	if len(rows) == 0 && m.cursor < len(m.forwards) {
+		rows = append(rows, m.forwards[m.cursor])
+	}
+	return rows
+}
+
+// inSelection reports whether the row at index is marked or in the visual range
+func (m model) inSelection(index int) bool {
+	if m.visualFrom >= 0 && index >= min(m.visualFrom, m.cursor) && index <= max(m.visualFrom, m.cursor) {
+		return true
+	}
+	return m.marked[m.forwards[index].ID]
+}
+
+// clearSelection leaves visual mode and unmarks all rows
+func (m *model) clearSelection() {
+	m.visualFrom = -1
+	clear(m.marked)
+}
+
+// toggleCluster disables or enables a cluster in the background, since stopping its
+// forwards waits for their pre_stop hooks
+func (m *model) toggleCluster(name string) tea.Cmd {
+	manager := m.manager
+	if manager.ClusterDisabled(name) {
+		m.notice = fmt.Sprintf("Enabling cluster %s...", name)
+		return func() tea.Msg {
+			err := manager.EnableCluster(name)
+			audit("enable", auditSourceTUI, err, "cluster", name)
+			if err != nil {
+				return noticeMsg(fmt.Sprintf("Can't enable cluster %s: %v", name, err))
+			}
+			return noticeMsg(fmt.Sprintf("Cluster %s enabled", name))
+		}
+	}
+
+	m.notice = fmt.Sprintf("Disabling cluster %s...", name)
+	return func() tea.Msg {
+		err := manager.DisableCluster(name)
+		audit("disable", auditSourceTUI, err, "cluster", name)
+		if err != nil {
+			return noticeMsg(fmt.Sprintf("Can't disable cluster %s: %v", name, err))
+		}
+		return noticeMsg(fmt.Sprintf("Cluster %s disabled", name))
+	}
+}
+
+// togglePause pauses or resumes all forwards in the background, since stopping them waits
+// for their pre_stop hooks
+func (m *model) togglePause() tea.Cmd {
+	manager := m.manager
+	if manager.Paused() {
+		m.notice = "Resuming all port-forwards..."
+		return func() tea.Msg {
+			err := manager.ResumeAll()
+			audit("resume", auditSourceTUI, err)
+			if err != nil {
+				return noticeMsg(fmt.Sprintf("Can't resume: %v", err))
+			}
+			return noticeMsg("All port-forwards resumed")
+		}
+	}
+
+	m.notice = "Pausing all port-forwards..."
+	return func() tea.Msg {
+		err := manager.PauseAll()
+		audit("pause", auditSourceTUI, err)
+		if err != nil {
+			return noticeMsg(fmt.Sprintf("Can't pause: %v", err))
+		}
+		return noticeMsg("All port-forwards paused")
+	}
+}
+
+// refresh reloads the forwards and keeps the cursor on a row
+func (m *model) refresh() {
+	m.forwards = m.manager.Snapshot()
+	m.clusters = m.manager.ClusterStatuses()
+
+	// Forwards added at runtime are listed under their cluster's header
+	order := make(map[string]int, len(m.clusters))
+	for i, cs := range m.clusters {
+		order[cs.Name] = i
+	}
+	sort.SliceStable(m.forwards, func(i, j int) bool {
+		return order[m.forwards[i].Cluster] < order[m.forwards[j].Cluster]
+	})
+	if m.cursor >= len(m.forwards) {
+		m.cursor = max(len(m.forwards)-1, 0)
+	}
+	if m.visualFrom >= len(m.forwards) {
+		m.visualFrom = m.cursor
+	}
+}
+
 // View renders the TUI
 func (m model) View() string {
 	if m.quitting {
 		return "Shutting down port-forwards...\n"
 	}
 
+	if m.wizard != nil {
+		return m.wizard.View()
+	}
+	if m.history != nil {
+		return m.history.View()
+	}
+	if m.showHelp {
+		return m.helpView()
+	}
+	if m.compact() {
+		return m.compactView()
+	}
+
 	var b strings.Builder
 
 	// Title
 	b.WriteString(titleStyle.Render("nanoporter - Kubernetes Port-Forward Manager"))
 	b.WriteString("\n\n")
 
-	// Table header - wider columns to accommodate full names
-	header := fmt.Sprintf("%-20s %-18s %-35s %-12s %-14s %-16s %s",
-		"Cluster", "Namespace", "Service", "Ports", "Status", "Backup", "Info")
-	b.WriteString(headerStyle.Render(header))
+	// Table header
+	titles := make(map[string]string, len(m.columns))
+	for _, col := range m.columns {
+		titles[col.name] = col.title
+	}
+	b.WriteString(headerStyle.Render(m.formatRow(titles)))
 	b.WriteString("\n")
 	b.WriteString(strings.Repeat("─", 150))
 	b.WriteString("\n")
@@ -121,118 +489,448 @@ func (m model) View() string {
 		b.WriteString("No port-forwards configured.\n")
 	}
 
-	for _, pf := range m.forwards {
-		pf.mu.RLock()
-		cluster := pf.ClusterName
-		namespace := pf.Config.Namespace
-		service := pf.Config.Service
-		ports := fmt.Sprintf("%d:%d", pf.Config.LocalPort, pf.Config.RemotePort)
-		state := pf.State
-		errorMsg := pf.Error
-		retryCount := pf.RetryCount
-		reconnectAt := pf.ReconnectAt
-		lastCheck := pf.LastCheck
-		backupState := pf.BackupState
-		backupError := pf.BackupError
-		backupTime := pf.BackupTime
-		backupSizeMB := pf.BackupSizeMB
-		hasBackup := pf.Config.DBBackup != nil
-		pf.mu.RUnlock()
-
-		// Format status with color
-		var statusText, info string
-		var statusStyle lipgloss.Style
-
-		switch state {
-		case StateActive:
-			statusText = "🟢 Active"
-			statusStyle = activeStyle
-			if !lastCheck.IsZero() {
-				info = fmt.Sprintf("checked %s ago", formatDuration(time.Since(lastCheck)))
+	clusters := make(map[string]ClusterStatus, len(m.clusters))
+	for _, cs := range m.clusters {
+		clusters[cs.Name] = cs
+	}
+
+	for i, fs := range m.forwards {
+		if i == 0 || m.forwards[i-1].Cluster != fs.Cluster {
+			b.WriteString(clusterHeader(clusters[fs.Cluster]))
+			b.WriteString("\n")
+		}
+
+		row := forwardRow(fs)
+		statusStyle := row.style
+		line := m.formatRow(row.cells)
+		if i == m.cursor {
+			statusStyle = statusStyle.Reverse(true)
+		} else if m.inSelection(i) {
+			statusStyle = statusStyle.Background(lipgloss.Color("237"))
+		}
+		b.WriteString(statusStyle.Render(line))
+		b.WriteString("\n")
+
+		// Show error details on separate line if present and state is failed
+		if fs.State == StateFailed && len(fs.Error) > 40 {
+			b.WriteString(failedStyle.Render(fmt.Sprintf("  Error: %s", fs.Error)))
+			b.WriteString("\n")
+		}
+	}
+
+	// Details of the row under the cursor
+	if m.cursor < len(m.forwards) {
+		fs := m.forwards[m.cursor]
+		var details []string
+		if fs.Pod != "" {
+			details = append(details, podDetails(fs))
+		}
+		if stats := statsDetails(fs); stats != "" {
+			details = append(details, stats)
+		}
+		details = append(details, connectionDetails(fs)...)
+		if len(details) > 0 {
+			b.WriteString("\n")
+			b.WriteString(helpStyle.UnsetMarginTop().Render(strings.Join(details, "\n")))
+			b.WriteString("\n")
+		}
+	}
+
+	if m.notice != "" {
+		b.WriteString("\n")
+		b.WriteString(m.notice)
+		b.WriteString("\n")
+	}
+
+	if m.quitDialog {
+		b.WriteString("\n")
+		b.WriteString(m.quitDialogView())
+		return b.String()
+	}
+
+	// Help text
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render(m.helpText()))
+	b.WriteString("\n")
+	b.WriteString(m.statusBar())
+
+	return b.String()
+}
+
+// tableRow is a forward's row in the wide table: the text of each column and its style
+type tableRow struct {
+	cells map[string]string
+	style lipgloss.Style
+}
+
+// forwardRow formats the columns of a forward's row
+func forwardRow(fs ForwardStatus) tableRow {
+	cluster := fs.Cluster
+	namespace := fs.Namespace
+	service := fs.Service
+	ports := fmt.Sprintf("%d:%s", fs.LocalPort, fs.RemotePortLabel())
+	state := fs.State
+	errorMsg := fs.Error
+	retryCount := fs.RetryCount
+	reconnectAt := fs.ReconnectAt
+	lastCheck := fs.LastCheck
+	backupState := fs.BackupState
+	backupError := fs.BackupError
+	backupTime := fs.BackupTime
+	backupSizeMB := fs.BackupSizeMB
+	hasBackup := fs.HasBackup
+
+	// Format status with color
+	var statusText, info string
+	var statusStyle lipgloss.Style
+
+	switch state {
+	case StateActive:
+		statusText = "🟢 Active"
+		statusStyle = activeStyle
+		if !lastCheck.IsZero() {
+			info = fmt.Sprintf("checked %s ago", formatDuration(time.Since(lastCheck)))
+		}
+		if fs.LatencyMS > 0 {
+			info += " · " + formatLatency(fs.LatencyMS)
+		}
+	case StateReconnecting:
+		statusText = "🟡 Reconnecting"
+		statusStyle = reconnectingStyle
+		if !reconnectAt.IsZero() {
+			until := time.Until(reconnectAt)
+			if until > 0 {
+				info = fmt.Sprintf("retry in %s (attempt %d)", formatDuration(until), retryCount)
+			} else {
+				info = fmt.Sprintf("retrying... (attempt %d)", retryCount)
 			}
-		case StateReconnecting:
-			statusText = "🟡 Reconnecting"
-			statusStyle = reconnectingStyle
-			if !reconnectAt.IsZero() {
-				until := time.Until(reconnectAt)
-				if until > 0 {
-					info = fmt.Sprintf("retry in %s (attempt %d)", formatDuration(until), retryCount)
-				} else {
-					info = fmt.Sprintf("retrying... (attempt %d)", retryCount)
-				}
+		} else if retryCount > 0 {
+			info = fmt.Sprintf("waiting for API server (attempt %d)", retryCount)
+		}
+	case StateAuthExpired:
+		statusText = "🔑 Auth expired"
+		statusStyle = reconnectingStyle
+		info = fmt.Sprintf("waiting for credentials (attempt %d)", retryCount)
+	case StateFailed:
+		statusText = "🔴 Failed"
+		statusStyle = failedStyle
+		if errorMsg != "" {
+			info = truncate(errorMsg, 40)
+		}
+	case StateStarting:
+		statusText = "⚪ Starting"
+		statusStyle = lipgloss.NewStyle()
+		info = "initializing..."
+	case StateStopped:
+		statusText = "⚫ Stopped"
+		statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	case StateDisabled:
+		statusText = "⏸ Disabled"
+		statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+		info = "cluster disabled"
+	case StatePaused:
+		statusText = "⏸ Paused"
+		statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	}
+
+	// Format backup status
+	var backupText string
+	if !hasBackup {
+		backupText = "-"
+	} else {
+		switch backupState {
+		case BackupPending:
+			backupText = "⏳ Pending"
+		case BackupRunning:
+			backupText = "🔄 Running"
+		case BackupCompleted:
+			if !backupTime.IsZero() {
+				backupText = "✓ " + formatSize(backupSizeMB)
+			} else {
+				backupText = "✓ Done"
 			}
-		case StateFailed:
-			statusText = "🔴 Failed"
-			statusStyle = failedStyle
-			if errorMsg != "" {
-				info = truncate(errorMsg, 40)
+		case BackupFailed:
+			backupText = "✗ Failed"
+			if backupError != "" && info == "" {
+				info = truncate(backupError, 40)
 			}
-		case StateStarting:
-			statusText = "⚪ Starting"
-			statusStyle = lipgloss.NewStyle()
-			info = "initializing..."
-		case StateStopped:
-			statusText = "⚫ Stopped"
-			statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-		}
-
-		// Format backup status
-		var backupText string
-		if !hasBackup {
-			backupText = "-"
+		default:
+			backupText = "⏸ Waiting"
+		}
+	}
+
+	uptime := ""
+	if state == StateActive && !fs.ActiveSince.IsZero() {
+		uptime = formatDuration(time.Since(fs.ActiveSince))
+	}
+
+	// Forwards moved off a taken local port stand out
+	if fs.ConfiguredPort != 0 {
+		ports = fmt.Sprintf("%d*:%s", fs.LocalPort, fs.RemotePortLabel())
+		note := fmt.Sprintf("port %d taken", fs.ConfiguredPort)
+		if info != "" {
+			note += " · " + info
+		}
+		info = note
+	}
+	if fs.Rejected > 0 {
+		info = strings.TrimPrefix(info+fmt.Sprintf(" · %d rejected", fs.Rejected), " · ")
+	}
+	if fs.Refused > 0 {
+		info = strings.TrimPrefix(info+fmt.Sprintf(" · %d refused", fs.Refused), " · ")
+	}
+	if fs.Capture != "" {
+		info = strings.TrimSuffix("● capturing · "+info, " · ")
+	}
+
+	latency := ""
+	if state == StateActive && fs.LatencyMS > 0 {
+		latency = fmt.Sprintf("%s (avg %s)", formatLatency(fs.LatencyMS), formatLatency(fs.LatencyAvgMS))
+	}
+
+	return tableRow{
+		style: statusStyle,
+		cells: map[string]string{
+			"cluster":   cluster,
+			"namespace": namespace,
+			"service":   service,
+			"ports":     ports,
+			"status":    statusText,
+			"backup":    backupText,
+			"pod":       fs.Pod,
+			"uptime":    uptime,
+			"latency":   latency,
+			"info":      info,
+		},
+	}
+}
+
+// formatRow lays out the cells of a row in the configured columns. Text is cut to the
+// column width, except in the last column and the status and backup cells, which start
+// with an emoji.
+func (m model) formatRow(cells map[string]string) string {
+	var b strings.Builder
+	for i, col := range m.columns {
+		cell := cells[col.name]
+		if i == len(m.columns)-1 {
+			// The last column runs to the end of the line
+			b.WriteString(cell)
+			break
+		}
+		if col.name != "status" && col.name != "backup" {
+			cell = truncate(cell, col.width)
+		}
+		fmt.Fprintf(&b, "%-*s ", col.width, cell)
+	}
+	return b.String()
+}
+
+// clusterHeader renders the line above a cluster's forwards: its context, whether its API
+// server answers, whether its credentials work, and how many of its forwards are up
+func clusterHeader(cs ClusterStatus) string {
+	parts := []string{cs.Name}
+	if cs.Context != "" && cs.Context != cs.Name {
+		parts = append(parts, "context "+cs.Context)
+	}
+
+	style := activeStyle
+	switch {
+	case cs.Disabled:
+		parts = append(parts, "disabled")
+		style = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	case !cs.Kubernetes:
+		// No API server to talk to
+	case cs.Probed.IsZero():
+		parts = append(parts, "API checking...")
+	case cs.Reachable:
+		parts = append(parts, "API reachable")
+		if cs.LatencyMS > 0 {
+			parts[len(parts)-1] += " (" + formatLatency(cs.LatencyMS) + ")"
+		}
+	default:
+		parts = append(parts, "API unreachable: "+truncate(cs.Error, 60))
+		style = failedStyle
+	}
+	if cs.Kubernetes && !cs.Disabled {
+		if cs.AuthExpired {
+			parts = append(parts, "auth expired")
+			style = failedStyle
+		} else if cs.Reachable {
+			parts = append(parts, "auth ok")
+		}
+	}
+
+	counts := fmt.Sprintf("%d/%d active", cs.Active, cs.Forwards)
+	if cs.Down > 0 {
+		counts += fmt.Sprintf(", %d down", cs.Down)
+		if style.GetForeground() == activeStyle.GetForeground() {
+			style = reconnectingStyle
+		}
+	}
+	parts = append(parts, counts)
+
+	return style.Bold(true).Render("▾ " + strings.Join(parts, " · "))
+}
+
+// podDetails describes the pod (or container) a forward is connected to
+func podDetails(fs ForwardStatus) string {
+	// Short forms, like kubectl and docker show them
+	label, id := "Pod", fs.PodUID
+	if fs.Type == "docker" {
+		label, id = "Container", id[:min(len(id), 12)]
+	} else if before, _, ok := strings.Cut(id, "-"); ok {
+		id = before
+	}
+
+	details := fmt.Sprintf("%s: %s", label, fs.Pod)
+	if id != "" {
+		details += fmt.Sprintf(" (%s)", id)
+	}
+	if !fs.PodSince.IsZero() {
+		details += fmt.Sprintf(", selected %s ago", formatDuration(time.Since(fs.PodSince)))
+	}
+	return details
+}
+
+// statsDetails sums up how a forward has fared since its statistics started, which may be
+// in an earlier run
+func statsDetails(fs ForwardStatus) string {
+	if fs.StatsSince.IsZero() {
+		return ""
+	}
+	tracked := time.Since(fs.StatsSince)
+	uptime := time.Duration(fs.UptimeSeconds * float64(time.Second))
+
+	details := fmt.Sprintf("Since %s: ", fs.StatsSince.Local().Format("Jan 2 15:04"))
+	if uptime < time.Second {
+		details += "not active yet"
+	} else {
+		details += fmt.Sprintf("active %s (%.0f%%)", formatDuration(uptime), 100*min(uptime.Seconds()/tracked.Seconds(), 1))
+	}
+	reconnects := "reconnects"
+	if fs.Reconnects == 1 {
+		reconnects = "reconnect"
+	}
+	return details + fmt.Sprintf(", %d %s", fs.Reconnects, reconnects)
+}
+
+// shownConnections is how many recent connections the detail view lists
+const shownConnections = 5
+
+// connectionDetails lists the latest connections of a forward under the table: who
+// connected, to which pod, when, for how long and how much was sent
+func connectionDetails(fs ForwardStatus) []string {
+	if len(fs.Connections) == 0 {
+		return nil
+	}
+
+	open := fmt.Sprintf("%d", fs.OpenConnections)
+	if fs.MaxConnections > 0 {
+		open += fmt.Sprintf("/%d", fs.MaxConnections)
+	}
+	lines := []string{fmt.Sprintf("Connections: %s open, latest %d:", open, min(len(fs.Connections), shownConnections))}
+
+	for _, c := range fs.Connections[:min(len(fs.Connections), shownConnections)] {
+		line := fmt.Sprintf("  %-22s %s", c.Client, c.Start.Local().Format("15:04:05"))
+		if c.End.IsZero() {
+			line += fmt.Sprintf(" open for %s", formatDuration(time.Since(c.Start)))
+		} else if d := c.End.Sub(c.Start); d < time.Second {
+			line += " for <1s"
 		} else {
-			switch backupState {
-			case BackupPending:
-				backupText = "⏳ Pending"
-			case BackupRunning:
-				backupText = "🔄 Running"
-			case BackupCompleted:
-				if !backupTime.IsZero() {
-					// Show KB if less than 1 MB, otherwise MB
-					if backupSizeMB < 1.0 {
-						backupText = fmt.Sprintf("✓ %.0fKB", backupSizeMB*1024)
-					} else {
-						backupText = fmt.Sprintf("✓ %.1fMB", backupSizeMB)
-					}
-				} else {
-					backupText = "✓ Done"
-				}
-			case BackupFailed:
-				backupText = "✗ Failed"
-				if backupError != "" && info == "" {
-					info = truncate(backupError, 40)
-				}
-			default:
-				backupText = "⏸ Waiting"
-			}
+			line += fmt.Sprintf(" for %s", formatDuration(d))
 		}
+		line += fmt.Sprintf(", in %s, out %s", formatBytes(c.BytesIn), formatBytes(c.BytesOut))
+		if c.Pod != "" {
+			line += " → " + c.Pod
+		}
+		if c.Error != "" {
+			line += " (" + c.Error + ")"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
 
-		row := fmt.Sprintf("%-20s %-18s %-35s %-12s %-14s %-16s %s",
-			truncate(cluster, 20), truncate(namespace, 18), truncate(service, 35),
-			ports, statusText, backupText, info)
+// formatBytes formats a byte count of a connection
+func formatBytes(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	return formatSize(float64(n) / 1024 / 1024)
+}
 
-		b.WriteString(statusStyle.Render(row))
-		b.WriteString("\n")
+// helpText describes the main keys of the forward list; the help overlay lists all of them
+func (m model) helpText() string {
+	type hint struct{ action, label string }
+	hints := []hint{{actionUp, "up"}, {actionDown, "down"}, {actionAdd, "add"}, {actionOpen, "open"},
+		{actionRetry, "retry"}, {actionDisable, "disable/enable cluster"}, {actionToggleMode, "compact/wide"}, {actionHelp, "all keys"},
+		{actionQuit, "quit"}}
+	prefix := ""
+	if m.visualFrom >= 0 {
+		prefix = "-- VISUAL -- "
+		hints = []hint{{actionUp, "up"}, {actionDown, "down"}, {actionVisual, "keep selection"}, {actionOpen, "open"},
+			{actionRetry, "retry"}, {actionDisable, "disable/enable clusters"}, {actionQuit, "cancel"}}
+	}
 
-		// Show error details on separate line if present and state is failed
-		if state == StateFailed && errorMsg != "" && len(errorMsg) > 40 {
-			b.WriteString(failedStyle.Render(fmt.Sprintf("  Error: %s", errorMsg)))
-			b.WriteString("\n")
+	var parts []string
+	for _, h := range hints {
+		if key := m.keys.help(h.action); key != "" {
+			parts = append(parts, fmt.Sprintf("%s %s", key, h.label))
 		}
 	}
+	return prefix + strings.Join(parts, " • ")
+}
+
+// helpView lists all key bindings of the forward list
+func (m model) helpView() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("Keyboard shortcuts"))
+	b.WriteString("\n\n")
+
+	for _, h := range keymapHelp {
+		var names []string
+		for _, seq := range m.keys.keys[h.action] {
+			names = append(names, keyName(seq))
+		}
+		keys := strings.Join(names, ", ")
+		if keys == "" {
+			keys = "(unbound)"
+		}
+		b.WriteString(fmt.Sprintf("  %-16s %s\n", keys, h.description))
+	}
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "ctrl+c", "Quit and stop all port-forwards"))
 
-	// Help text
 	b.WriteString("\n")
-	b.WriteString(helpStyle.Render("Press 'q' or Ctrl+C to quit"))
+	b.WriteString(headerStyle.Render("Add forward wizard"))
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "↑/↓, k/j", "Select"))
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "enter", "Continue, or start the forward on the last step"))
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "s", "Start the forward and save it to the config file (last step)"))
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "backspace", "Go back"))
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "esc", "Cancel"))
+
+	b.WriteString("\n")
+	b.WriteString(headerStyle.Render("Backup history"))
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "↑/↓, k/j", "Select"))
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "enter", "Restore into a local Docker container"))
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "x, delete", "Delete the backup"))
+	b.WriteString(fmt.Sprintf("  %-16s %s\n", "esc, q, B", "Back"))
+
+	b.WriteString(helpStyle.Render("Key bindings can be changed in the tui.keymap section of the config • any key closes this help"))
 
 	return b.String()
 }
 
-// waitForUpdate waits for port-forward updates
-func waitForUpdate(manager *PortForwardManager) tea.Cmd {
+// waitForEvent waits for the next port-forward event
+func waitForEvent(events <-chan Event) tea.Cmd {
 	return func() tea.Msg {
-		forward := <-manager.GetUpdateChannel()
-		return updateMsg{forward: forward}
+		event, ok := <-events
+		if !ok {
+			return nil
+		}
+		return eventMsg{event: event}
 	}
 }
 
@@ -257,6 +955,14 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
 }
 
+// formatSize formats a size in MB, showing KB if less than 1 MB
+func formatSize(sizeMB float64) string {
+	if sizeMB < 1.0 {
+		return fmt.Sprintf("%.0fKB", sizeMB*1024)
+	}
+	return fmt.Sprintf("%.1fMB", sizeMB)
+}
+
 // truncate truncates a string to the specified length
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
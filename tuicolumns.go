@@ -0,0 +1,80 @@
+package main
+
+// tuiColumn is a column of the forward list
+type tuiColumn struct {
+	name  string
+	title string
+	width int
+}
+
+// tuiColumnNames lists the columns the forward list can show
+var tuiColumnNames = []string{"cluster", "namespace", "service", "ports", "status", "backup", "pod", "uptime", "latency", "info"}
+
+// tuiColumnWidths are the default widths of the columns
+var tuiColumnWidths = map[string]int{
+	"cluster":   20,
+	"namespace": 18,
+	"service":   35,
+	"ports":     12,
+	"status":    14,
+	"backup":    16,
+	"pod":       30,
+	"uptime":    10,
+	"latency":   20,
+	"info":      40,
+}
+
+// tuiColumnTitles are the header titles of the columns
+var tuiColumnTitles = map[string]string{
+	"cluster":   "Cluster",
+	"namespace": "Namespace",
+	"service":   "Service",
+	"ports":     "Ports",
+	"status":    "Status",
+	"backup":    "Backup",
+	"pod":       "Pod",
+	"uptime":    "Uptime",
+	"latency":   "Latency",
+	"info":      "Info",
+}
+
+// resolveTUIColumns returns the columns to show. Without configured columns, the Backup
+// column is only shown when a forward has backups configured.
+func resolveTUIColumns(config *Config) []tuiColumn {
+	var names []string
+	var widths map[string]int
+	if config.TUI != nil {
+		names = config.TUI.Columns
+		widths = config.TUI.ColumnWidths
+	}
+
+	if len(names) == 0 {
+		names = []string{"cluster", "namespace", "service", "ports", "status"}
+		if backupsConfigured(config) {
+			names = append(names, "backup")
+		}
+		names = append(names, "info")
+	}
+
+	columns := make([]tuiColumn, 0, len(names))
+	for _, name := range names {
+		width := tuiColumnWidths[name]
+		if w, ok := widths[name]; ok {
+			width = w
+		}
+		columns = append(columns, tuiColumn{name: name, title: tuiColumnTitles[name], width: width})
+	}
+	return columns
+}
+
+// backupsConfigured reports whether any forward has database backups configured
+func backupsConfigured(config *Config) bool {
+	for _, cluster := range config.Clusters {
+		for _, forward := range cluster.Forwards {
+			if forward.DBBackup != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
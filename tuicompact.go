@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Display modes of the forward list
+const (
+	modeAuto    = "auto"    // compact when the wide table doesn't fit the terminal
+	modeWide    = "wide"    // the table with all configured columns
+	modeCompact = "compact" // one dense line per forward, without emoji
+)
+
+// compactTargetWidth is the width of the forward column in compact mode
+const compactTargetWidth = 32
+
+// compact reports whether the forward list is shown in compact mode
+func (m model) compact() bool {
+	switch m.mode {
+	case modeWide:
+		return false
+	case modeCompact:
+		return true
+	}
+
+	// Before the first WindowSizeMsg the terminal size isn't known
+	if m.width == 0 || m.height == 0 {
+		return false
+	}
+	// Title, table header, details, help and status bar lines around the rows
+	wideHeight := len(m.forwards) + len(m.clusters) + 10
+	if m.cursor < len(m.forwards) {
+		wideHeight += len(connectionDetails(m.forwards[m.cursor])) + 1 // and the statistics
+	}
+	return m.width < tableWidth(m.columns) || m.height < wideHeight
+}
+
+// tableWidth returns the width the wide table needs
+func tableWidth(columns []tuiColumn) int {
+	width := 0
+	for _, col := range columns {
+		width += col.width + 1
+	}
+	return width
+}
+
+// compactView renders the forward list with one line per forward
+func (m model) compactView() string {
+	var b strings.Builder
+
+	active := 0
+	for _, fs := range m.forwards {
+		if fs.State == StateActive {
+			active++
+		}
+	}
+	b.WriteString(titleStyle.UnsetMarginBottom().Render("nanoporter"))
+	b.WriteString(fmt.Sprintf(" %d/%d active\n", active, len(m.forwards)))
+
+	b.WriteString(headerStyle.Render(m.fitWidth(fmt.Sprintf("%-*s %5s %-12s %s",
+		compactTargetWidth, "FORWARD", "PORT", "STATE", "INFO"))))
+	b.WriteString("\n")
+
+	if len(m.forwards) == 0 {
+		b.WriteString("No port-forwards configured.\n")
+	}
+
+	// Only as many rows as fit, scrolled to keep the cursor visible
+	footer := 2 + strings.Count(m.notice, "\n")
+	if m.notice != "" {
+		footer++
+	}
+	rows := len(m.forwards)
+	if m.height > 0 {
+		rows = max(m.height-2-footer, 1)
+	}
+	start := max(m.cursor-rows+1, 0)
+
+	for i := start; i < len(m.forwards) && i < start+rows; i++ {
+		line, style := compactRow(m.forwards[i])
+		if i == m.cursor {
+			style = style.Reverse(true)
+		} else if m.inSelection(i) {
+			style = style.Background(lipgloss.Color("237"))
+		}
+		b.WriteString(style.Render(m.fitWidth(line)))
+		b.WriteString("\n")
+	}
+
+	if m.notice != "" {
+		b.WriteString(m.notice)
+		b.WriteString("\n")
+	}
+
+	if m.quitDialog {
+		b.WriteString(m.quitDialogView())
+		return b.String()
+	}
+
+	b.WriteString(helpStyle.UnsetMarginTop().Render(m.fitWidth(m.helpText())))
+	b.WriteString("\n")
+	b.WriteString(m.statusBar())
+
+	return b.String()
+}
+
+// compactRow formats a forward as a single line without emoji
+func compactRow(fs ForwardStatus) (string, lipgloss.Style) {
+	target := fs.Name
+	if target == "" {
+		target = fs.Cluster + "/" + fs.Service
+		if fs.Namespace != "" {
+			target = fs.Cluster + "/" + fs.Namespace + "/" + fs.Service
+		}
+	}
+
+	var info []string
+	if fs.Capture != "" {
+		info = append(info, "capturing")
+	}
+	if fs.ConfiguredPort != 0 {
+		info = append(info, fmt.Sprintf("port %d taken", fs.ConfiguredPort))
+	}
+	if fs.Rejected > 0 {
+		info = append(info, fmt.Sprintf("%d rejected", fs.Rejected))
+	}
+	if fs.Refused > 0 {
+		info = append(info, fmt.Sprintf("%d refused", fs.Refused))
+	}
+	style := lipgloss.NewStyle()
+	switch fs.State {
+	case StateActive:
+		style = activeStyle
+		if !fs.ActiveSince.IsZero() {
+			info = append(info, "up "+formatDuration(time.Since(fs.ActiveSince)))
+		}
+		if fs.LatencyMS > 0 {
+			info = append(info, formatLatency(fs.LatencyMS))
+		}
+	case StateReconnecting:
+		style = reconnectingStyle
+		if until := time.Until(fs.ReconnectAt); until >= time.Second {
+			info = append(info, fmt.Sprintf("retry in %s (#%d)", formatDuration(until), fs.RetryCount))
+		} else if fs.ReconnectAt.IsZero() && fs.RetryCount > 0 {
+			info = append(info, fmt.Sprintf("waiting for API server (#%d)", fs.RetryCount))
+		} else {
+			info = append(info, fmt.Sprintf("retrying (#%d)", fs.RetryCount))
+		}
+	case StateAuthExpired:
+		style = reconnectingStyle
+	case StateFailed:
+		style = failedStyle
+		info = append(info, fs.Error)
+	case StateStopped, StateDisabled, StatePaused:
+		style = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	}
+
+	if fs.HasBackup {
+		switch fs.BackupState {
+		case BackupCompleted:
+			info = append(info, "backup "+formatSize(fs.BackupSizeMB))
+		case BackupFailed:
+			info = append(info, "backup failed: "+fs.BackupError)
+		case BackupNone:
+		default:
+			info = append(info, "backup "+string(fs.BackupState))
+		}
+	}
+
+	line := fmt.Sprintf("%-*s %5d %-12s %s", compactTargetWidth, truncate(target, compactTargetWidth),
+		fs.LocalPort, fs.State, strings.ReplaceAll(strings.Join(info, " · "), "\n", " "))
+	return line, style
+}
+
+// fitWidth cuts a line to the terminal width
+func (m model) fitWidth(line string) string {
+	if m.width <= 0 {
+		return line
+	}
+	runes := []rune(line)
+	if len(runes) <= m.width {
+		return line
+	}
+	return string(runes[:m.width-1]) + "…"
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// vaultRequestTimeout bounds a single request to Vault
+	vaultRequestTimeout = 10 * time.Second
+	// defaultVaultJWTFile is the service account token used for Kubernetes auth
+	defaultVaultJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// vaultClient is the HTTP client used for Vault requests
+var vaultClient = &http.Client{Timeout: vaultRequestTimeout}
+
+// readVaultSecret logs in to Vault and reads the secret at the configured path. KV v2
+// secrets are unwrapped, so the result holds the secret's fields in every case.
+func readVaultSecret(config *VaultConfig) (map[string]string, error) {
+	address := config.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("no vault.address configured and VAULT_ADDR is not set")
+	}
+	address = strings.TrimSuffix(address, "/")
+
+	token, err := vaultLogin(address, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in to Vault: %w", err)
+	}
+
+	var response struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := vaultRequest(http.MethodGet, address, config.Path, token, config.Namespace, nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to read Vault secret %s: %w", config.Path, err)
+	}
+
+	fields := response.Data
+	if nested, ok := fields["data"].(map[string]any); ok && fields["metadata"] != nil {
+		// KV v2
+		fields = nested
+	}
+
+	data := make(map[string]string, len(fields))
+	for key, val := range fields {
+		if s, ok := val.(string); ok {
+			data[key] = s
+		} else if val != nil {
+			data[key] = fmt.Sprint(val)
+		}
+	}
+	return data, nil
+}
+
+// vaultLogin returns a Vault token for the configured auth method
+func vaultLogin(address string, config *VaultConfig) (string, error) {
+	switch config.Auth {
+	case "", "token":
+		if config.Token != "" {
+			return config.Token, nil
+		}
+		if token := os.Getenv("VAULT_TOKEN"); token != "" {
+			return token, nil
+		}
+		// Written by `vault login`
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("no Vault token found: %w", err)
+		}
+		token, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+		if err != nil {
+			return "", fmt.Errorf("no Vault token found (set vault.token or VAULT_TOKEN, or run `vault login`)")
+		}
+		return strings.TrimSpace(string(token)), nil
+
+	case "approle":
+		secretID := config.SecretID
+		if secretID == "" {
+			secretID = os.Getenv("VAULT_SECRET_ID")
+		}
+		return vaultAuthLogin(address, config, "approle", map[string]string{
+			"role_id":   config.RoleID,
+			"secret_id": secretID,
+		})
+
+	case "kubernetes":
+		jwtFile := config.JWTFile
+		if jwtFile == "" {
+			jwtFile = defaultVaultJWTFile
+		}
+		jwt, err := os.ReadFile(jwtFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account token: %w", err)
+		}
+		return vaultAuthLogin(address, config, "kubernetes", map[string]string{
+			"role": config.Role,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		})
+
+	default:
+		return "", fmt.Errorf("unknown auth method '%s'", config.Auth)
+	}
+}
+
+// vaultAuthLogin logs in with an auth method mounted at vault.mount (default: its name)
+func vaultAuthLogin(address string, config *VaultConfig, method string, body map[string]string) (string, error) {
+	mount := config.Mount
+	if mount == "" {
+		mount = method
+	}
+
+	var response struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := vaultRequest(http.MethodPost, address, "auth/"+mount+"/login", "", config.Namespace, body, &response); err != nil {
+		return "", err
+	}
+	if response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("no token in %s login response", method)
+	}
+	return response.Auth.ClientToken, nil
+}
+
+// vaultRequest sends a request to the Vault HTTP API and decodes the JSON response
+func vaultRequest(method, address, path, token, namespace string, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, address+"/v1/"+strings.TrimPrefix(path, "/"), reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := vaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResponse struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResponse)
+		if len(errResponse.Errors) > 0 {
+			return fmt.Errorf("%s: %s", resp.Status, strings.Join(errResponse.Errors, "; "))
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// wizardStep is a step of the add forward wizard
+type wizardStep int
+
+const (
+	stepCluster wizardStep = iota
+	stepNamespace
+	stepService
+	stepPort
+	stepLocalPort
+)
+
+// wizardOption is an entry of a wizard list
+type wizardOption struct {
+	label string
+	value string
+	port  int // remote port, for the port step
+}
+
+// wizardOptionsMsg carries options loaded from the cluster for a step
+type wizardOptionsMsg struct {
+	step     wizardStep
+	options  []wizardOption
+	services []corev1.Service
+	err      error
+}
+
+// addWizard walks through cluster → namespace → service → port selection, querying the
+// API live, and adds the resulting forward to the running manager
+type addWizard struct {
+	manager    *PortForwardManager
+	configPath string
+
+	step     wizardStep
+	options  []wizardOption
+	cursor   int
+	loading  bool
+	err      string
+	services []corev1.Service
+
+	cluster    ClusterConfig
+	namespace  string
+	service    string
+	remotePort int
+	localPort  string
+
+	// notice is shown in the forward list after the wizard closes
+	notice string
+}
+
+// newAddWizard opens the wizard on the cluster step
+func newAddWizard(manager *PortForwardManager, configPath string) *addWizard {
+	return &addWizard{
+		manager:    manager,
+		configPath: configPath,
+		options:    clusterOptions(manager.config),
+	}
+}
+
+// Update handles a message while the wizard is open. Returns true once the wizard is closed.
+func (w *addWizard) Update(msg tea.Msg) (bool, tea.Cmd) {
+	switch msg := msg.(type) {
+	case wizardOptionsMsg:
+		if msg.step != w.step {
+			return false, nil
+		}
+		w.loading = false
+		w.cursor = 0
+		w.options = msg.options
+		w.services = msg.services
+		if msg.err != nil {
+			w.err = msg.err.Error()
+		}
+
+	case tea.KeyMsg:
+		if w.step == stepLocalPort {
+			return w.updateLocalPort(msg)
+		}
+
+		switch msg.String() {
+		case "esc", "ctrl+c":
+			return true, nil
+		case "up", "k":
+			if w.cursor > 0 {
+				w.cursor--
+			}
+		case "down", "j":
+			if w.cursor < len(w.options)-1 {
+				w.cursor++
+			}
+		case "backspace", "left", "h":
+			return false, w.back()
+		case "enter", "right", "l":
+			if w.loading || len(w.options) == 0 {
+				return false, nil
+			}
+			return false, w.selectOption(w.options[w.cursor])
+		}
+	}
+
+	return false, nil
+}
+
+// updateLocalPort handles keys on the local port step
+func (w *addWizard) updateLocalPort(msg tea.KeyMsg) (bool, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		return true, nil
+	case "backspace":
+		if w.localPort == "" {
+			return false, w.back()
+		}
+		w.localPort = w.localPort[:len(w.localPort)-1]
+	case "enter":
+		return w.add(false), nil
+	case "s":
+		return w.add(true), nil
+	default:
+		if key := msg.String(); len(key) == 1 && key[0] >= '0' && key[0] <= '9' && len(w.localPort) < 5 {
+			w.localPort += key
+		}
+	}
+
+	return false, nil
+}
+
+// selectOption moves to the next step with the chosen option
+func (w *addWizard) selectOption(option wizardOption) tea.Cmd {
+	w.err = ""
+
+	switch w.step {
+	case stepCluster:
+		if cluster := findCluster(w.manager.config, option.value); cluster != nil {
+			w.cluster = *cluster
+		}
+		return w.enter(stepNamespace)
+	case stepNamespace:
+		w.namespace = option.value
+		return w.enter(stepService)
+	case stepService:
+		w.service = option.value
+		return w.enter(stepPort)
+	case stepPort:
+		w.remotePort = option.port
+		w.localPort = strconv.Itoa(option.port)
+		w.step = stepLocalPort
+	}
+
+	return nil
+}
+
+// enter switches to a list step and loads its options
+func (w *addWizard) enter(step wizardStep) tea.Cmd {
+	w.step = step
+	w.options = nil
+	w.cursor = 0
+
+	if step == stepPort {
+		w.options = w.portOptions()
+		return nil
+	}
+
+	w.loading = true
+	return w.load(step)
+}
+
+// back returns to the previous step
+func (w *addWizard) back() tea.Cmd {
+	w.err = ""
+	w.cursor = 0
+
+	switch w.step {
+	case stepNamespace:
+		w.step = stepCluster
+		w.options = clusterOptions(w.manager.config)
+	case stepService:
+		// Namespaces aren't kept around, list them again
+		return w.enter(stepNamespace)
+	case stepPort:
+		w.step = stepService
+		w.options = serviceOptions(w.services)
+	case stepLocalPort:
+		w.step = stepPort
+		w.options = w.portOptions()
+	}
+
+	return nil
+}
+
+// load queries the options of a step from the selected cluster
+func (w *addWizard) load(step wizardStep) tea.Cmd {
+	clusterClient := w.manager.ClusterClient(w.cluster.Name)
+	cluster := w.cluster
+	namespace := w.namespace
+
+	return func() tea.Msg {
+		if clusterClient == nil {
+			return wizardOptionsMsg{step: step, err: fmt.Errorf("cluster %s is not connected", cluster.Name)}
+		}
+		if err := clusterClient.Err(); err != nil {
+			return wizardOptionsMsg{step: step, err: err}
+		}
+		_, client := clusterClient.Get()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		switch step {
+		case stepNamespace:
+			list, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				// Listing namespaces is often forbidden; offer the ones from the config instead
+				return wizardOptionsMsg{step: step, options: configuredNamespaces(cluster), err: err}
+			}
+			var options []wizardOption
+			for _, ns := range list.Items {
+				options = append(options, wizardOption{label: ns.Name, value: ns.Name})
+			}
+			return wizardOptionsMsg{step: step, options: options}
+
+		case stepService:
+			list, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return wizardOptionsMsg{step: step, err: err}
+			}
+			services := list.Items
+			sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+			return wizardOptionsMsg{step: step, options: serviceOptions(services), services: services}
+		}
+
+		return wizardOptionsMsg{step: step}
+	}
+}
+
+// portOptions lists the forwardable ports of the selected service
+func (w *addWizard) portOptions() []wizardOption {
+	var options []wizardOption
+
+	for _, svc := range w.services {
+		if svc.Name != w.service {
+			continue
+		}
+		for _, port := range svc.Spec.Ports {
+			target := servicePortTarget(port)
+			if target == 0 {
+				continue
+			}
+			label := fmt.Sprintf("%d → %d", port.Port, target)
+			if port.Name != "" {
+				label = fmt.Sprintf("%s (%s)", label, port.Name)
+			}
+			options = append(options, wizardOption{label: label, port: target})
+		}
+	}
+
+	return options
+}
+
+// add creates the forward and optionally saves it to the config file. Returns true once the forward was added.
+func (w *addWizard) add(save bool) bool {
+	localPort, err := strconv.Atoi(w.localPort)
+	if err != nil || localPort < 1 || localPort > 65535 {
+		w.err = "local port must be 1-65535"
+		return false
+	}
+
+	forward := ForwardConfig{
+		Namespace:  w.namespace,
+		Service:    w.service,
+		Type:       "service",
+		LocalPort:  localPort,
+		RemotePort: w.remotePort,
+	}
+
+	_, err = w.manager.AddForward(w.cluster, forward)
+	audit("add", auditSourceTUI, err, "cluster", w.cluster.Name, "namespace", w.namespace, "service", w.service, "local_port", localPort)
+	if err != nil {
+		w.err = err.Error()
+		return false
+	}
+
+	name := fmt.Sprintf("%s/%s/%s", w.cluster.Name, w.namespace, w.service)
+	w.notice = fmt.Sprintf("Added %s on port %d", name, localPort)
+
+	if save {
+		if err := appendForwardToConfig(w.configPath, w.cluster.Name, forward); err != nil {
+			w.notice = fmt.Sprintf("Added %s on port %d, but not saved: %v", name, localPort, err)
+		} else {
+			w.notice += " and saved it to " + w.configPath
+		}
+	}
+
+	return true
+}
+
+// View renders the wizard
+func (w *addWizard) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("Add port-forward"))
+	b.WriteString("\n\n")
+
+	path := []string{w.cluster.Name, w.namespace, w.service}
+	if crumbs := path[:min(int(w.step), len(path))]; len(crumbs) > 0 {
+		b.WriteString(headerStyle.Render(strings.Join(crumbs, " / ")))
+		b.WriteString("\n\n")
+	}
+
+	prompts := map[wizardStep]string{
+		stepCluster:   "Select cluster",
+		stepNamespace: "Select namespace",
+		stepService:   "Select service",
+		stepPort:      "Select port",
+	}
+
+	if w.step == stepLocalPort {
+		b.WriteString(fmt.Sprintf("Remote port %d → local port: %s█\n", w.remotePort, w.localPort))
+	} else {
+		b.WriteString(prompts[w.step] + ":\n")
+		switch {
+		case w.loading:
+			b.WriteString("  loading...\n")
+		case len(w.options) == 0:
+			b.WriteString("  (nothing found)\n")
+		}
+
+		// Keep the cursor visible in long lists
+		start := 0
+		if w.cursor >= 15 {
+			start = w.cursor - 14
+		}
+		for i := start; i < len(w.options) && i < start+15; i++ {
+			if i == w.cursor {
+				b.WriteString(activeStyle.Render("> " + w.options[i].label))
+			} else {
+				b.WriteString("  " + w.options[i].label)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if w.err != "" {
+		b.WriteString("\n")
+		b.WriteString(failedStyle.Render(w.err))
+		b.WriteString("\n")
+	}
+
+	help := "↑/↓ select • enter next • backspace back • esc cancel"
+	if w.step == stepLocalPort {
+		help = "enter add • s add and save to config • backspace back • esc cancel"
+	}
+	b.WriteString(helpStyle.Render(help))
+
+	return b.String()
+}
+
+// clusterOptions lists the configured clusters as wizard options
+func clusterOptions(config *Config) []wizardOption {
+	var options []wizardOption
+	for _, cluster := range config.Clusters {
+		options = append(options, wizardOption{label: cluster.Name, value: cluster.Name})
+	}
+	return options
+}
+
+// serviceOptions lists services as wizard options
+func serviceOptions(services []corev1.Service) []wizardOption {
+	var options []wizardOption
+	for _, svc := range services {
+		options = append(options, wizardOption{label: svc.Name, value: svc.Name})
+	}
+	return options
+}
+
+// configuredNamespaces lists the namespaces a cluster's configured forwards use
+func configuredNamespaces(cluster ClusterConfig) []wizardOption {
+	seen := make(map[string]bool)
+	var options []wizardOption
+
+	for _, forward := range cluster.Forwards {
+		if !seen[forward.Namespace] {
+			seen[forward.Namespace] = true
+			options = append(options, wizardOption{label: forward.Namespace, value: forward.Namespace})
+		}
+	}
+
+	return options
+}